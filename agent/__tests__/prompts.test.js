@@ -102,3 +102,70 @@ Use the security-audit skill to guide your comprehensive security and performanc
     })
   })
 })
+
+describe('Structured Findings Block', () => {
+  it('should ask for a probe-findings JSON block', async () => {
+    const { fullAuditPrompt, findingsBlockInstructions } = await import('../prompts.js')
+
+    const prompt = fullAuditPrompt('/path/to/codebase')
+
+    expect(prompt).toContain(findingsBlockInstructions)
+    expect(findingsBlockInstructions).toContain('```json probe-findings')
+    expect(findingsBlockInstructions).toContain('"cwe"')
+    expect(findingsBlockInstructions).toContain('"remediation"')
+  })
+})
+
+describe('Verify Prompt', () => {
+  it('should describe the finding and ask for a probe-verdict block', async () => {
+    const { verifyPrompt, verdictBlockInstructions } = await import('../prompts.js')
+
+    const prompt = verifyPrompt('/path/to/codebase', {
+      title: 'SQL injection in login handler',
+      severity: 'critical',
+      file: 'src/auth/login.js',
+      line_start: 42,
+      line_end: 48,
+      cwe: 'CWE-89',
+      description: 'User input is concatenated into the SQL query.',
+    })
+
+    expect(prompt).toContain('/path/to/codebase')
+    expect(prompt).toContain('SQL injection in login handler')
+    expect(prompt).toContain('src/auth/login.js:42-48')
+    expect(prompt).toContain('CWE-89')
+    expect(prompt).toContain(verdictBlockInstructions)
+    expect(verdictBlockInstructions).toContain('```json probe-verdict')
+    expect(verdictBlockInstructions).toContain('still_present')
+  })
+
+  it('should handle findings without a location', async () => {
+    const { verifyPrompt } = await import('../prompts.js')
+
+    const prompt = verifyPrompt('/path', { text: 'Debug mode enabled', severity: 'high' })
+
+    expect(prompt).toContain('Debug mode enabled')
+    expect(prompt).toContain('Location: unknown')
+    expect(prompt).not.toContain('CWE:')
+  })
+})
+
+describe('Fix Prompt', () => {
+  it('should ask for a probe-patch diff without editing files', async () => {
+    const { fixPrompt, patchBlockInstructions } = await import('../prompts.js')
+
+    const prompt = fixPrompt('/path/to/codebase', {
+      title: 'SQL injection in login handler',
+      severity: 'critical',
+      file: 'src/auth/login.js',
+      line_start: 42,
+      remediation: 'Use parameterised queries.',
+    })
+
+    expect(prompt).toContain('src/auth/login.js:42')
+    expect(prompt).toContain('Suggested remediation: Use parameterised queries.')
+    expect(prompt).toContain('do not edit any files')
+    expect(prompt).toContain(patchBlockInstructions)
+    expect(patchBlockInstructions).toContain('```diff probe-patch')
+  })
+})
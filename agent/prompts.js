@@ -5,5 +5,110 @@ First, list what skills you have available.
 Then audit this codebase: ${targetPath}
 
 Use the security-audit skill to guide your comprehensive security and performance analysis.
+
+${findingsBlockInstructions}
+`.trim()
+}
+
+// Machine-readable findings block parsed by the Go CLI (internal/findings).
+export const findingsBlockInstructions = `
+After the markdown report, append every finding once more as a fenced code
+block tagged \`json probe-findings\` so tools can read them, for example:
+
+\`\`\`json probe-findings
+{
+  "findings": [
+    {
+      "title": "SQL injection in login handler",
+      "severity": "critical",
+      "file": "src/auth/login.js",
+      "line_start": 42,
+      "line_end": 48,
+      "cwe": "CWE-89",
+      "owasp": "A03:2021-Injection",
+      "description": "User input is concatenated into the SQL query.",
+      "remediation": "Use parameterised queries."
+    }
+  ]
+}
+\`\`\`
+
+Use paths relative to the audited directory. Severity must be one of
+critical, high, medium, low or info. Omit fields you cannot determine.
+`.trim()
+
+// Re-check a single finding from an earlier audit. The finding is the JSON
+// stored by the Go CLI (title, severity, description, file, line_start, ...).
+export function verifyPrompt(targetPath, finding) {
+  return `
+An earlier security audit of ${targetPath} reported the finding below. Check
+whether it is still present in the current code. Only look at the code needed
+to decide; do not audit anything else.
+
+Title: ${finding.title || finding.text || ''}
+Severity: ${finding.severity || 'unknown'}
+Location: ${formatLocation(finding)}
+${finding.cwe ? `CWE: ${finding.cwe}\n` : ''}Description: ${finding.description || 'none given'}
+
+${verdictBlockInstructions}
+`.trim()
+}
+
+function formatLocation(finding) {
+  if (!finding.file) return 'unknown'
+  let location = finding.file
+  if (finding.line_start) location += `:${finding.line_start}`
+  if (finding.line_end && finding.line_end !== finding.line_start) location += `-${finding.line_end}`
+  return location
+}
+
+// Machine-readable verdict parsed by the Go CLI (internal/findings).
+export const verdictBlockInstructions = `
+End your answer with a fenced code block tagged \`json probe-verdict\`:
+
+\`\`\`json probe-verdict
+{
+  "verdict": "fixed",
+  "reasoning": "The query in src/auth/login.js now uses bound parameters."
+}
+\`\`\`
+
+The verdict must be one of fixed, still_present or inconclusive. Use
+inconclusive when the code has moved or you cannot tell. Keep the reasoning to
+a few sentences and cite the files and lines you checked.
+`.trim()
+
+// Ask for a minimal patch that remediates a single finding. The agent only
+// reads the code; the Go CLI validates and applies the diff.
+export function fixPrompt(targetPath, finding) {
+  return `
+An earlier security audit of ${targetPath} reported the finding below. Write
+the smallest change that fixes it. Do not refactor, reformat or fix unrelated
+issues, and do not edit any files yourself.
+
+Title: ${finding.title || finding.text || ''}
+Severity: ${finding.severity || 'unknown'}
+Location: ${formatLocation(finding)}
+${finding.cwe ? `CWE: ${finding.cwe}\n` : ''}Description: ${finding.description || 'none given'}
+${finding.remediation ? `Suggested remediation: ${finding.remediation}\n` : ''}
+${patchBlockInstructions}
 `.trim()
 }
+
+// Unified diff parsed by the Go CLI (internal/findings).
+export const patchBlockInstructions = `
+Explain the fix in one or two sentences, then give it as a unified diff in a
+fenced code block tagged \`diff probe-patch\`:
+
+\`\`\`diff probe-patch
+--- a/src/auth/login.js
++++ b/src/auth/login.js
+@@ -42,1 +42,1 @@
+-  db.query("SELECT * FROM users WHERE name = '" + name + "'")
++  db.query("SELECT * FROM users WHERE name = ?", [name])
+\`\`\`
+
+Paths are relative to the audited directory with a/ and b/ prefixes. Copy the
+context lines exactly from the current file so the patch applies with git
+apply.
+`.trim()
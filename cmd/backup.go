@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ndzuma/probeTool/internal/backup"
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/process"
+	"github.com/ndzuma/probeTool/internal/version"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupStripKeys bool
+	restoreForce    bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup <file.tar.gz>",
+	Short: "Archive the database, reports and config",
+	Long: `Writes a gzipped tar archive of everything probe keeps: a consistent
+snapshot of the database (safe while the server is running), the report and
+transcript files in the probes directory, and the config.
+
+The config holds provider API keys. Use --strip-keys to leave them out, for
+example when sharing a backup; restore keeps the keys already configured.`,
+	Example: `  probe backup probe-backup.tar.gz
+  probe backup --strip-keys shared.tar.gz`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		archive := args[0]
+		if _, err := os.Stat(archive); err == nil {
+			fmt.Printf("❌ Error: %s already exists\n", archive)
+			os.Exit(1)
+		}
+
+		database := openDatabase()
+		defer db.Close(database)
+
+		manifest, err := backup.Create(database, archive, backup.DefaultPaths(), backupStripKeys)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Backed up %d file(s) to %s (schema version %d)\n", len(manifest.Files), archive, manifest.SchemaVersion)
+		if manifest.KeysStripped {
+			fmt.Println("   API keys were left out")
+		} else {
+			fmt.Println("⚠️  The backup contains your API keys; use --strip-keys to leave them out")
+		}
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <file.tar.gz>",
+	Short: "Restore a backup made with probe backup",
+	Long: `Replaces the database, reports and config with the contents of a backup.
+
+The backup must come from a probe whose database schema this version knows;
+older backups are migrated after their integrity is checked. Restoring over
+existing probes or providers needs --force, and the replaced database is kept
+next to the new one as a .bak file. Stop the server first.`,
+	Example: `  probe restore probe-backup.tar.gz
+  probe restore --force probe-backup.tar.gz`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if process.IsServerRunning() {
+			fmt.Println("❌ Error: the server is running; stop it first with: probe stop")
+			os.Exit(1)
+		}
+
+		manifest, err := backup.Inspect(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Backup from probe %s, created %s\n", manifest.ProbeVersion, manifest.CreatedAt)
+		fmt.Printf("This is probe %s\n", version.Version)
+
+		result, err := backup.Restore(args[0], backup.DefaultPaths(), restoreForce)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if result.Previous != "" {
+			fmt.Printf("   Previous database saved to %s\n", result.Previous)
+		}
+		if result.From != result.To {
+			fmt.Printf("   Migrated schema %d → %d\n", result.From, result.To)
+		}
+		if result.Relinked > 0 {
+			fmt.Printf("   Relinked %d report(s) to the probes directory\n", result.Relinked)
+		}
+		if manifest.KeysStripped {
+			fmt.Println("⚠️  The backup has no API keys; set any that are missing with: probe config set-key")
+		}
+		fmt.Printf("✅ Restored %d probe(s) and %d file(s)\n", result.Probes, result.Files)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	backupCmd.Flags().BoolVar(&backupStripKeys, "strip-keys", false, "Leave provider API keys out of the archived config")
+	restoreCmd.Flags().BoolVar(&restoreForce, "force", false, "Replace existing probes and config")
+}
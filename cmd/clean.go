@@ -5,24 +5,48 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/ndzuma/probeTool/internal/db"
 	"github.com/ndzuma/probeTool/internal/paths"
 	"github.com/spf13/cobra"
 )
 
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
-	Short: "Clean probe reports and cache",
-	Long:  `Removes all probe report markdown files and clears cache.`,
+	Short: "Clean orphaned probe reports and cache",
+	Long: `Removes probe report markdown files that no probe refers to and clears
+cache. Probes and their findings are kept; use "probe rm" or "probe prune" to
+delete them.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Clean probe markdown files
+		// Keep the files of probes that are still in the database
+		database := openDatabase()
+		probes, err := db.GetAllProbes(database)
+		db.Close(database)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		keep := make(map[string]bool)
+		for _, p := range probes {
+			for _, file := range db.ProbeFiles(p) {
+				keep[filepath.Clean(file)] = true
+			}
+		}
+
+		// Clean orphaned probe markdown files
 		probesDir := paths.GetProbesDir()
 		pattern := filepath.Join(probesDir, "*.md")
 		matches, err := filepath.Glob(pattern)
 		if err == nil {
+			removed := 0
 			for _, file := range matches {
-				os.Remove(file)
+				if keep[filepath.Clean(file)] {
+					continue
+				}
+				if os.Remove(file) == nil {
+					removed++
+				}
 			}
-			fmt.Printf("✅ Cleaned %d probe report(s)\n", len(matches))
+			fmt.Printf("✅ Cleaned %d orphaned probe report(s)\n", removed)
 		}
 
 		// Clean cache
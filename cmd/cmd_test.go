@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/ndzuma/probeTool/internal/config"
@@ -30,12 +31,17 @@ func TestConfigCommandExists(t *testing.T) {
 func TestConfigSubcommands(t *testing.T) {
 	// Check that all config subcommands exist
 	expectedCommands := map[string]bool{
-		"providers":    false,
-		"add-provider": false,
-		"set-key":      false,
-		"add-model":    false,
-		"set-default":  false,
-		"list":         false,
+		"providers":        false,
+		"add-provider":     false,
+		"set-key":          false,
+		"add-model":        false,
+		"set-default":      false,
+		"list":             false,
+		"set-similarity":   false,
+		"set-retention":    false,
+		"add-redaction":    false,
+		"set-server":       false,
+		"remove-redaction": false,
 	}
 
 	for _, cmd := range configCmd.Commands() {
@@ -203,12 +209,30 @@ func TestStopAllFlag(t *testing.T) {
 	}
 }
 
-func TestServerConstants(t *testing.T) {
-	if process.ServerPort != "37330" {
-		t.Errorf("ServerPort = %v, want 37330", process.ServerPort)
+func TestServerDefaults(t *testing.T) {
+	if config.DefaultHost != "127.0.0.1" || config.DefaultPort != 37330 {
+		t.Errorf("Default address = %s:%d, want 127.0.0.1:37330", config.DefaultHost, config.DefaultPort)
 	}
-	if process.NextJSPort != "37331" {
-		t.Errorf("NextJSPort = %v, want 37331", process.NextJSPort)
+	if process.DefaultNextJSPort != 37331 {
+		t.Errorf("DefaultNextJSPort = %v, want 37331", process.DefaultNextJSPort)
+	}
+	for _, flag := range []string{"host", "port", "allowed-host"} {
+		if serveCmd.Flags().Lookup(flag) == nil {
+			t.Errorf("serve should have --%s flag", flag)
+		}
+	}
+
+	origins := dashboardOrigins("192.168.1.5", 8080, nil)
+	if len(origins) != 3 || origins[2] != "http://192.168.1.5:8080" {
+		t.Errorf("dashboardOrigins() = %v, want the loopback names and the host", origins)
+	}
+	if origins := dashboardOrigins("0.0.0.0", 8080, nil); len(origins) != 2 {
+		t.Errorf("dashboardOrigins() for a wildcard host = %v, want the loopback names", origins)
+	}
+	origins = dashboardOrigins("0.0.0.0", 8080, []string{"probe.lan", "10.0.0.7:80", "fe80::1"})
+	want := []string{"http://localhost:8080", "http://127.0.0.1:8080", "http://probe.lan:8080", "http://10.0.0.7:80", "http://[fe80::1]:8080"}
+	if !reflect.DeepEqual(origins, want) {
+		t.Errorf("dashboardOrigins() with allowed hosts = %v, want %v", origins, want)
 	}
 }
 
@@ -276,3 +300,196 @@ func TestUpdateCommandShortFlag(t *testing.T) {
 		t.Errorf("update --yes shorthand = %v, want y", yesFlag.Shorthand)
 	}
 }
+
+func TestDiffCommand(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"diff"})
+	if err != nil {
+		t.Fatalf("diff command not found: %v", err)
+	}
+
+	if cmd.Flags().Lookup("json") == nil {
+		t.Error("diff command should have --json flag")
+	}
+
+	if err := cmd.Args(cmd, []string{"only-one"}); err == nil {
+		t.Error("diff command should require two probe IDs")
+	}
+}
+
+func TestFindingSubcommands(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"finding", "set-state"})
+	if err != nil {
+		t.Fatalf("finding set-state command not found: %v", err)
+	}
+	if cmd.Flags().Lookup("reason") == nil {
+		t.Error("finding set-state should have --reason flag")
+	}
+	if cmd.Flags().Lookup("actor") == nil {
+		t.Error("finding set-state should have --actor flag")
+	}
+
+	if _, _, err := rootCmd.Find([]string{"finding", "history"}); err != nil {
+		t.Errorf("finding history command not found: %v", err)
+	}
+}
+
+func TestFindingTriageSubcommands(t *testing.T) {
+	for _, name := range []string{"list", "comment", "comments", "assign", "tag", "untag"} {
+		if _, _, err := rootCmd.Find([]string{"finding", name}); err != nil {
+			t.Errorf("finding %s command not found: %v", name, err)
+		}
+	}
+
+	cmd, _, _ := rootCmd.Find([]string{"finding", "list"})
+	for _, flag := range []string{"tag", "assignee", "state", "severity", "probe", "json"} {
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("finding list should have --%s flag", flag)
+		}
+	}
+
+	cmd, _, _ = rootCmd.Find([]string{"finding", "comment"})
+	if cmd.Flags().Lookup("reply-to") == nil {
+		t.Error("finding comment should have --reply-to flag")
+	}
+}
+
+func TestVerifyCommand(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"verify"})
+	if err != nil || cmd.Name() != "verify" {
+		t.Fatalf("verify command not found: %v", err)
+	}
+
+	for _, flag := range []string{"provider", "model", "verbose"} {
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("verify should have --%s flag", flag)
+		}
+	}
+}
+
+func TestFixCommand(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"fix"})
+	if err != nil || cmd.Name() != "fix" {
+		t.Fatalf("fix command not found: %v", err)
+	}
+	for _, flag := range []string{"provider", "model", "verbose"} {
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("fix should have --%s flag", flag)
+		}
+	}
+
+	for _, name := range []string{"apply", "show"} {
+		if sub, _, err := rootCmd.Find([]string{"fix", name}); err != nil || sub.Name() != name {
+			t.Errorf("fix %s command not found: %v", name, err)
+		}
+	}
+
+	cmd, _, _ = rootCmd.Find([]string{"fix", "apply"})
+	if cmd.Flags().Lookup("branch") == nil {
+		t.Error("fix apply should have --branch flag")
+	}
+}
+
+func TestDBCommand(t *testing.T) {
+	for _, name := range []string{"migrate", "status", "check"} {
+		cmd, _, err := rootCmd.Find([]string{"db", name})
+		if err != nil || cmd.Name() != name {
+			t.Errorf("db %s command not found: %v", name, err)
+		}
+	}
+
+	check, _, _ := rootCmd.Find([]string{"db", "check"})
+	for _, flag := range []string{"fix", "restore", "search", "json"} {
+		if check.Flags().Lookup(flag) == nil {
+			t.Errorf("db check should have --%s flag", flag)
+		}
+	}
+
+	// The config path migration keeps its own top-level command
+	if cmd, _, err := rootCmd.Find([]string{"migrate"}); err != nil || cmd.Name() != "migrate" {
+		t.Errorf("migrate command not found: %v", err)
+	}
+}
+
+func TestRmCommand(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"rm"})
+	if err != nil || cmd.Name() != "rm" {
+		t.Fatalf("rm command not found: %v", err)
+	}
+
+	for _, flag := range []string{"target", "before", "dry-run", "yes", "json"} {
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("rm should have --%s flag", flag)
+		}
+	}
+}
+
+func TestSearchCommand(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"search"})
+	if err != nil || cmd.Name() != "search" {
+		t.Fatalf("search command not found: %v", err)
+	}
+
+	for _, flag := range []string{"severity", "target", "since", "until", "limit", "json"} {
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("search should have --%s flag", flag)
+		}
+	}
+
+	mark := func(a ...interface{}) string { return "[" + a[0].(string) + "]" }
+	got := highlightSnippet("the <mark>SSRF</mark>\nreaches <mark>internal</mark> hosts", mark)
+	if want := "the [SSRF] reaches [internal] hosts"; got != want {
+		t.Errorf("highlightSnippet() = %q, want %q", got, want)
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"list"})
+	if err != nil || cmd.Name() != "list" {
+		t.Fatalf("list command not found: %v", err)
+	}
+
+	for _, flag := range []string{"target", "status", "type", "since", "until", "sort", "asc", "limit", "cursor", "json",
+		"provider", "model", "probe-version", "agent-version", "prompt-hash", "skill-hash"} {
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("list should have --%s flag", flag)
+		}
+	}
+}
+
+func TestPruneCommand(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"prune"})
+	if err != nil || cmd.Name() != "prune" {
+		t.Fatalf("prune command not found: %v", err)
+	}
+
+	for _, flag := range []string{"dry-run", "keep-last", "max-age", "json"} {
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("prune should have --%s flag", flag)
+		}
+	}
+}
+
+func TestBackupCommands(t *testing.T) {
+	for name, flag := range map[string]string{"backup": "strip-keys", "restore": "force"} {
+		cmd, _, err := rootCmd.Find([]string{name})
+		if err != nil || cmd.Name() != name {
+			t.Fatalf("%s command not found: %v", name, err)
+		}
+		if cmd.Flags().Lookup(flag) == nil {
+			t.Errorf("%s should have --%s flag", name, flag)
+		}
+	}
+}
+
+func TestProjectsCommand(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"projects"})
+	if err != nil || cmd.Name() != "projects" {
+		t.Fatalf("projects command not found: %v", err)
+	}
+	if cmd.Flags().Lookup("json") == nil {
+		t.Error("projects should have --json flag")
+	}
+	if probeListCmd.Flags().Lookup("project") == nil {
+		t.Error("list should have --project flag")
+	}
+}
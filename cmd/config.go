@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/ndzuma/probeTool/internal/config"
 	"github.com/spf13/cobra"
@@ -18,6 +19,11 @@ func init() {
 	configCmd.AddCommand(addModelCmd)
 	configCmd.AddCommand(setDefaultCmd)
 	configCmd.AddCommand(listConfigCmd)
+	configCmd.AddCommand(setSimilarityCmd)
+	configCmd.AddCommand(setRetentionCmd)
+	configCmd.AddCommand(addRedactionCmd)
+	configCmd.AddCommand(removeRedactionCmd)
+	configCmd.AddCommand(setServerCmd)
 }
 
 var configCmd = &cobra.Command{
@@ -150,6 +156,152 @@ var setDefaultCmd = &cobra.Command{
 	},
 }
 
+var setSimilarityCmd = &cobra.Command{
+	Use:   "set-similarity <profile> <threshold>",
+	Short: "Set the duplicate-merging threshold of a probe profile",
+	Long: `Findings in the same file whose titles are at least this similar (0-1) are
+merged into one finding that lists the others as aliases. The profile is the
+probe type: full or quick. Use 0 for the default and a value above 1 to turn
+merging off.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		profile := args[0]
+
+		threshold, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			fmt.Printf("❌ Invalid threshold %q: expected a number such as 0.6\n", args[1])
+			os.Exit(1)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Printf("❌ Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := cfg.SetSimilarityThreshold(profile, threshold); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Similarity threshold for '%s' probes set to %g\n", profile, threshold)
+	},
+}
+
+var setRetentionCmd = &cobra.Command{
+	Use:   "set-retention <keep-last> <max-age-days>",
+	Short: "Set how much probe history is kept",
+	Long: `Keeps the <keep-last> most recent probes of each project and drops probes
+older than <max-age-days>. Use 0 to turn either limit off. Probes with open
+critical findings are always kept. The server applies the policy on start and
+daily; run "probe prune --dry-run" to preview it.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		keepLast, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Printf("❌ Invalid keep-last %q: expected a whole number\n", args[0])
+			os.Exit(1)
+		}
+		maxAge, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("❌ Invalid max-age-days %q: expected a whole number\n", args[1])
+			os.Exit(1)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Printf("❌ Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := cfg.SetRetention(keepLast, maxAge); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Retention set: keep last %d per project, max age %d days (0 = no limit)\n", keepLast, maxAge)
+	},
+}
+
+var setServerCmd = &cobra.Command{
+	Use:   "set-server <host> <port>",
+	Short: "Set the address the dashboard server listens on",
+	Long: `Sets the host and port "probe serve" listens on. The default, 127.0.0.1 and
+37330, only accepts connections from this machine; use 0 for the default port.
+The PROBE_HOST and PROBE_PORT environment variables and the serve --host and
+--port flags override this. Restart the server to apply it.`,
+	Example: `  probe config set-server 127.0.0.1 8080
+  probe config set-server 172.20.1.4 0`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		port, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("❌ Invalid port %q: expected a number such as 37330\n", args[1])
+			os.Exit(1)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Printf("❌ Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := cfg.SetServer(args[0], port); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		host, port, _ := cfg.ServerAddress()
+		fmt.Printf("✅ Server address set to %s:%d (restart the server to apply)\n", host, port)
+	},
+}
+
+var addRedactionCmd = &cobra.Command{
+	Use:   "add-redaction <pattern>",
+	Short: "Mask values matching a pattern in reports and findings",
+	Long: `Adds a regular expression (Go syntax) whose matches are masked as [REDACTED]
+in reports and findings before they are stored, on top of the built-in
+patterns for common API keys, tokens, private keys and passwords. A pattern
+with a capturing group masks only the first group.`,
+	Example: `  probe config add-redaction 'ACME-[0-9]{8}'
+  probe config add-redaction '(?i)session_id=([a-f0-9]{32})'`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Printf("❌ Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := cfg.AddRedactionPattern(args[0]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Redaction pattern added: %s\n", args[0])
+	},
+}
+
+var removeRedactionCmd = &cobra.Command{
+	Use:   "remove-redaction <pattern>",
+	Short: "Remove a redaction pattern",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Printf("❌ Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := cfg.RemoveRedactionPattern(args[0]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Redaction pattern removed: %s\n", args[0])
+	},
+}
+
 var listConfigCmd = &cobra.Command{
 	Use:   "list",
 	Short: "Show current configuration",
@@ -183,5 +335,20 @@ var listConfigCmd = &cobra.Command{
 			fmt.Printf("  Models: %v\n", provider.Models)
 			fmt.Printf("  Default Model: %s\n\n", provider.DefaultModel)
 		}
+
+		for name, profile := range cfg.Profiles {
+			fmt.Printf("Profile: %s\n", name)
+			fmt.Printf("  Similarity Threshold: %g\n\n", profile.SimilarityThreshold)
+		}
+
+		if len(cfg.Redaction.Patterns) > 0 || cfg.Redaction.NoDefaults {
+			fmt.Println("Redaction:")
+			if cfg.Redaction.NoDefaults {
+				fmt.Println("  Built-in patterns: off")
+			}
+			for _, p := range cfg.Redaction.Patterns {
+				fmt.Printf("  Pattern: %s\n", p)
+			}
+		}
 	},
 }
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbCheckCmd)
+	dbCheckCmd.Flags().BoolVar(&checkFix, "fix", false, "Relink moved reports and store reports that exist only as files")
+	dbCheckCmd.Flags().BoolVar(&checkRestore, "restore", false, "Rewrite missing report files from the stored copy")
+	dbCheckCmd.Flags().StringSliceVar(&checkSearch, "search", nil, "Extra directories to look in for moved reports")
+	dbCheckCmd.Flags().BoolVar(&checkJSON, "json", false, "Output the check as JSON")
+}
+
+var (
+	checkFix     bool
+	checkRestore bool
+	checkSearch  []string
+	checkJSON    bool
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the probe database",
+	Long: `Inspect and migrate the database schema. Pending migrations are also applied
+automatically whenever probe opens the database.`,
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Long: `Applies every pending schema migration, each in its own transaction. A
+database that already holds data is backed up next to the database file first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openUnmigratedDatabase()
+		defer db.Close(database)
+
+		result, err := db.Migrate(database)
+		if err != nil {
+			fmt.Printf("❌ Migration failed: %v\n", err)
+			if result != nil && result.Backup != "" {
+				fmt.Printf("Backup of the database before migrating: %s\n", result.Backup)
+			}
+			os.Exit(1)
+		}
+
+		if len(result.Applied) == 0 {
+			fmt.Printf("✅ Database is up to date (schema version %d)\n", result.To)
+			return
+		}
+
+		if result.Backup != "" {
+			fmt.Printf("💾 Backup: %s\n", result.Backup)
+		}
+		for _, m := range result.Applied {
+			fmt.Printf("  ✓ %04d %s\n", m.Version, m.Name)
+		}
+		fmt.Printf("✅ Migrated schema from version %d to %d\n", result.From, result.To)
+	},
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending schema migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openUnmigratedDatabase()
+		defer db.Close(database)
+
+		states, err := db.MigrationStatus(database)
+		if errors.Is(err, db.ErrSchemaTooNew) {
+			fmt.Printf("❌ %v\n", err)
+			fmt.Println("Upgrade probe with: probe update")
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Database: %s\n\n", db.DBPath())
+
+		pending := 0
+		for _, s := range states {
+			if s.AppliedAt != "" {
+				fmt.Printf("  ✓ %04d %-24s applied %s\n", s.Version, s.Name, s.AppliedAt)
+			} else {
+				fmt.Printf("  · %04d %-24s pending\n", s.Version, s.Name)
+				pending++
+			}
+		}
+
+		fmt.Println()
+		if pending == 0 {
+			fmt.Println("✅ Database is up to date")
+		} else {
+			fmt.Printf("%d pending migration(s). Run: probe db migrate\n", pending)
+		}
+	},
+}
+
+var dbCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check report files against their stored copies",
+	Long: `Compares every probe's report file with the copy stored in the database
+(by SHA-256) and flags reports that are modified, missing or lost. Missing
+files are looked for by content in the probes directory, the pre-migration
+~/.probe/probes directory and any --search directory; --fix relinks the probe
+to the copy it finds. Exits with status 1 while problems remain.`,
+	Example: `  probe db check
+  probe db check --fix --search ~/backups/probes
+  probe db check --restore`,
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		dirs := append([]string{paths.GetProbesDir()}, checkSearch...)
+		if old := paths.GetOldProbePath(); old != "" {
+			dirs = append(dirs, filepath.Join(old, "probes"))
+		}
+
+		checks, err := db.CheckReports(database, dirs)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		for i := range checks {
+			if err := repairReport(database, &checks[i]); err != nil {
+				fmt.Printf("❌ %s: %v\n", checks[i].ProbeID, err)
+			}
+		}
+
+		problems := 0
+		for _, c := range checks {
+			if c.Status != db.ReportOK {
+				problems++
+			}
+		}
+
+		if checkJSON {
+			if checks == nil {
+				checks = []db.ReportCheck{}
+			}
+			output, _ := json.MarshalIndent(checks, "", "  ")
+			fmt.Println(string(output))
+		} else {
+			printReportChecks(checks, problems)
+		}
+		if problems > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// repairReport applies --fix and --restore to one check and updates its
+// status to match.
+func repairReport(database *sql.DB, c *db.ReportCheck) error {
+	switch {
+	case c.Status == db.ReportMoved && checkFix:
+		if err := db.RelinkReport(database, c.ProbeID, c.MovedTo); err != nil {
+			return err
+		}
+		c.Path, c.MovedTo, c.Status = c.MovedTo, "", db.ReportOK
+
+	case c.Status == db.ReportUnstored && checkFix:
+		content, err := os.ReadFile(c.Path)
+		if err != nil {
+			return err
+		}
+		if err := db.SaveReport(database, c.ProbeID, string(content)); err != nil {
+			return err
+		}
+		c.SHA256, c.Status = db.HashReport(string(content)), db.ReportOK
+
+	case (c.Status == db.ReportMissing || c.Status == db.ReportMoved) && checkRestore:
+		report, err := db.GetReport(database, c.ProbeID)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(c.Path, []byte(report.Content), 0644); err != nil {
+			return err
+		}
+		c.MovedTo, c.FileHash, c.Status = "", report.SHA256, db.ReportOK
+	}
+	return nil
+}
+
+func printReportChecks(checks []db.ReportCheck, problems int) {
+	for _, c := range checks {
+		switch c.Status {
+		case db.ReportOK:
+			continue
+		case db.ReportModified:
+			fmt.Printf("  ✗ %s  modified  %s (stored %s, file %s)\n", c.ProbeID, c.Path, c.SHA256[:12], c.FileHash[:12])
+		case db.ReportMissing:
+			fmt.Printf("  ✗ %s  missing   %s (stored copy kept; --restore rewrites it)\n", c.ProbeID, c.Path)
+		case db.ReportMoved:
+			fmt.Printf("  ✗ %s  moved     %s → %s (--fix relinks it)\n", c.ProbeID, c.Path, c.MovedTo)
+		case db.ReportUnstored:
+			fmt.Printf("  ✗ %s  unstored  %s (--fix stores it)\n", c.ProbeID, c.Path)
+		case db.ReportLost:
+			fmt.Printf("  ✗ %s  lost      %s (no file and no stored copy)\n", c.ProbeID, c.Path)
+		}
+	}
+
+	if problems == 0 {
+		fmt.Printf("✅ All %d report(s) intact\n", len(checks))
+		return
+	}
+	fmt.Printf("\n%d of %d report(s) need attention\n", problems, len(checks))
+}
+
+// openUnmigratedDatabase opens the database without applying migrations.
+func openUnmigratedDatabase() *sql.DB {
+	database, err := db.Open(db.DBPath())
+	if err != nil {
+		fmt.Printf("Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	return database
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/ndzuma/probeTool/internal/compare"
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var diffJSON bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <base-probe> <head-probe>",
+	Short: "Compare the findings of two probes of the same project",
+	Long: `Matches findings between an older (base) and a newer (head) probe by
+fingerprint and reports which are new, resolved or persisting, including
+severity changes on persisting findings.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDiff(args[0], args[1])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "Output the comparison as JSON")
+}
+
+func runDiff(baseID, headID string) {
+	database, err := db.InitDB(db.DBPath())
+	if err != nil {
+		fmt.Printf("Error initializing database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close(database)
+
+	result, err := compare.Probes(database, baseID, headID)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	if diffJSON {
+		output, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	printComparison(result)
+}
+
+func printComparison(result *compare.Result) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+
+	fmt.Printf("Comparing %s → %s\n", result.Base.ID, result.Head.ID)
+	fmt.Printf("Target: %s\n\n", result.Head.Target)
+
+	fmt.Println(bold(fmt.Sprintf("New (%d)", len(result.New))))
+	for _, e := range result.New {
+		fmt.Printf("  %s [%s] %s\n", red("+"), e.Severity, e.Text)
+	}
+
+	fmt.Println(bold(fmt.Sprintf("\nResolved (%d)", len(result.Resolved))))
+	for _, e := range result.Resolved {
+		fmt.Printf("  %s [%s] %s\n", green("-"), e.Severity, e.Text)
+	}
+
+	fmt.Println(bold(fmt.Sprintf("\nPersisting (%d)", len(result.Persisting))))
+	for _, e := range result.Persisting {
+		text := e.Text
+		if e.Match == compare.MatchSimilar && e.Base != nil && e.Base.Text != e.Text {
+			text += fmt.Sprintf(" (was: %s)", e.Base.Text)
+		}
+		if e.SeverityChange != "" {
+			fmt.Printf("  %s [%s → %s] %s\n", yellow("~"), e.PreviousSeverity, e.Severity, text)
+			continue
+		}
+		fmt.Printf("  = [%s] %s\n", e.Severity, text)
+	}
+
+	s := result.Summary
+	fmt.Printf("\n%d new, %d resolved, %d persisting (%d severity changed)\n", s.New, s.Resolved, s.Persisting, s.SeverityChanged)
+}
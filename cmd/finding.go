@@ -0,0 +1,351 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stateReason string
+	stateActor  string
+
+	commentAuthor string
+	commentReply  int64
+
+	listTag      string
+	listAssignee string
+	listState    string
+	listSeverity string
+	listProbe    string
+	listJSON     bool
+)
+
+func init() {
+	rootCmd.AddCommand(findingCmd)
+
+	findingCmd.AddCommand(setStateCmd)
+	findingCmd.AddCommand(findingHistoryCmd)
+	findingCmd.AddCommand(findingListCmd)
+	findingCmd.AddCommand(commentCmd)
+	findingCmd.AddCommand(commentsCmd)
+	findingCmd.AddCommand(assignCmd)
+	findingCmd.AddCommand(tagCmd)
+	findingCmd.AddCommand(untagCmd)
+
+	setStateCmd.Flags().StringVar(&stateReason, "reason", "", "Why the state is changing")
+	setStateCmd.Flags().StringVar(&stateActor, "actor", "", "Who is changing the state (defaults to the current user)")
+
+	commentCmd.Flags().StringVar(&commentAuthor, "author", "", "Comment author (defaults to the current user)")
+	commentCmd.Flags().Int64Var(&commentReply, "reply-to", 0, "ID of the comment this replies to")
+
+	findingListCmd.Flags().StringVar(&listTag, "tag", "", "Only findings with this tag")
+	findingListCmd.Flags().StringVar(&listAssignee, "assignee", "", "Only findings assigned to this person")
+	findingListCmd.Flags().StringVar(&listState, "state", "", "Only findings in this state")
+	findingListCmd.Flags().StringVar(&listSeverity, "severity", "", "Only findings of this severity")
+	findingListCmd.Flags().StringVar(&listProbe, "probe", "", "Only findings from this probe")
+	findingListCmd.Flags().BoolVar(&listJSON, "json", false, "Output findings as JSON")
+}
+
+var findingCmd = &cobra.Command{
+	Use:   "finding",
+	Short: "Manage individual findings",
+	Long:  `Triage findings: change their state, assign them, tag them and discuss them.`,
+}
+
+var setStateCmd = &cobra.Command{
+	Use:   "set-state <finding-id> <state>",
+	Short: "Set the state of a finding",
+	Long: fmt.Sprintf(`Sets a finding to one of: %s.
+
+The finding ID may be abbreviated to any unique prefix.`, strings.Join(db.FindingStates, ", ")),
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+		state := args[1]
+
+		actor := stateActor
+		if actor == "" {
+			actor = currentUser()
+		}
+
+		change, err := db.SetFindingState(database, findingID, state, actor, stateReason)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if change == nil {
+			fmt.Printf("Finding %s is already %s\n", shortID(findingID), state)
+			return
+		}
+
+		fmt.Printf("✅ Finding %s: %s → %s\n", shortID(findingID), change.FromState, change.ToState)
+	},
+}
+
+var findingHistoryCmd = &cobra.Command{
+	Use:   "history <finding-id>",
+	Short: "Show the state history of a finding",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		finding, err := db.GetFinding(database, findingID)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		history, err := db.GetFindingHistory(database, findingID)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("[%s] %s\n", finding.Severity, finding.Text)
+		fmt.Printf("State: %s\n\n", finding.State)
+
+		if len(history) == 0 {
+			fmt.Println("No state changes recorded.")
+			return
+		}
+
+		for _, c := range history {
+			fmt.Printf("%s  %s → %s  by %s", c.CreatedAt, c.FromState, c.ToState, c.Actor)
+			if c.Reason != "" {
+				fmt.Printf("  (%s)", c.Reason)
+			}
+			fmt.Println()
+		}
+	},
+}
+
+var findingListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List findings across probes",
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		list, err := db.ListFindings(database, db.FindingFilter{
+			ProbeID:  listProbe,
+			Severity: listSeverity,
+			State:    listState,
+			Tag:      listTag,
+			Assignee: listAssignee,
+		})
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if listJSON {
+			if list == nil {
+				list = []db.Finding{}
+			}
+			output, _ := json.MarshalIndent(list, "", "  ")
+			fmt.Println(string(output))
+			return
+		}
+
+		if len(list) == 0 {
+			fmt.Println("No findings match.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSEVERITY\tSTATE\tASSIGNEE\tTAGS\tFINDING")
+		for _, f := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", shortID(f.ID), f.Severity, f.State, f.Assignee, strings.Join(f.Tags, ","), f.Text)
+		}
+		w.Flush()
+	},
+}
+
+var commentCmd = &cobra.Command{
+	Use:   "comment <finding-id> <text>",
+	Short: "Add a comment to a finding",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		author := commentAuthor
+		if author == "" {
+			author = currentUser()
+		}
+
+		comment, err := db.AddComment(database, findingID, commentReply, author, strings.Join(args[1:], " "))
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Comment #%d added to finding %s\n", comment.ID, shortID(findingID))
+	},
+}
+
+var commentsCmd = &cobra.Command{
+	Use:   "comments <finding-id>",
+	Short: "Show the comment thread of a finding",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		comments, err := db.GetComments(database, findingID)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(comments) == 0 {
+			fmt.Println("No comments yet.")
+			return
+		}
+
+		for _, c := range comments {
+			indent := ""
+			if c.ParentID != 0 {
+				indent = "    ↳ "
+			}
+			fmt.Printf("%s#%d %s (%s)\n", indent, c.ID, c.Author, c.CreatedAt)
+			for _, line := range strings.Split(c.Body, "\n") {
+				fmt.Printf("%s  %s\n", strings.Repeat(" ", len([]rune(indent))), line)
+			}
+		}
+	},
+}
+
+var assignCmd = &cobra.Command{
+	Use:   "assign <finding-id> [assignee]",
+	Short: "Assign a finding, or unassign it when no assignee is given",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		assignee := ""
+		if len(args) > 1 {
+			assignee = args[1]
+		}
+
+		if err := db.SetAssignee(database, findingID, assignee); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if assignee == "" {
+			fmt.Printf("✅ Finding %s unassigned\n", shortID(findingID))
+			return
+		}
+		fmt.Printf("✅ Finding %s assigned to %s\n", shortID(findingID), assignee)
+	},
+}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag <finding-id> <tag>...",
+	Short: "Add tags to a finding",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		if err := db.AddTags(database, findingID, args[1:]...); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printTags(database, findingID)
+	},
+}
+
+var untagCmd = &cobra.Command{
+	Use:   "untag <finding-id> <tag>...",
+	Short: "Remove tags from a finding",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		if err := db.RemoveTags(database, findingID, args[1:]...); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printTags(database, findingID)
+	},
+}
+
+func printTags(database *sql.DB, findingID string) {
+	tags, err := db.GetTags(database, findingID)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(tags) == 0 {
+		fmt.Printf("Finding %s has no tags\n", shortID(findingID))
+		return
+	}
+	fmt.Printf("Finding %s tags: %s\n", shortID(findingID), strings.Join(tags, ", "))
+}
+
+func openDatabase() *sql.DB {
+	database, err := db.InitDB(db.DBPath())
+	if err != nil {
+		fmt.Printf("Error initializing database: %v\n", err)
+		os.Exit(1)
+	}
+	return database
+}
+
+func resolveFinding(database *sql.DB, prefix string) string {
+	id, err := db.ResolveFindingID(database, prefix)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	return id
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "cli"
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/prober"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fixProvider string
+	fixModel    string
+	fixVerbose  bool
+	fixBranch   string
+)
+
+var fixCmd = &cobra.Command{
+	Use:   "fix <finding-id>",
+	Short: "Ask the agent for a patch that fixes a finding",
+	Long: `Asks the agent for a minimal unified diff that remediates a finding, checks
+it with git apply --check against the probe's target and stores it with the
+finding. Review it, then apply it with: probe fix apply <finding-id>
+
+The finding ID may be abbreviated to any unique prefix.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigChan
+			cancel()
+		}()
+
+		fmt.Printf("🔧 Generating a fix for finding %s...\n", shortID(findingID))
+
+		p, err := prober.GeneratePatch(ctx, database, findingID, prober.FixArgs{
+			Provider: fixProvider,
+			Model:    fixModel,
+			Verbose:  fixVerbose,
+		})
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printPatch(p)
+
+		if !p.Valid {
+			fmt.Printf("\n⚠️  The patch does not apply cleanly:\n%s\n", p.CheckOutput)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n✅ Patch applies cleanly. Apply it with: probe fix apply %s\n", shortID(findingID))
+	},
+}
+
+var fixApplyCmd = &cobra.Command{
+	Use:   "apply <finding-id>",
+	Short: "Apply a finding's patch on a new branch",
+	Long: `Creates a new branch in the probe's target, switches to it and applies the
+stored patch without committing, so the change can be reviewed with git diff.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		p, err := prober.ApplyPatch(database, findingID, fixBranch)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Applied on branch %s\n", p.Branch)
+		fmt.Println("Review with: git diff")
+	},
+}
+
+var fixShowCmd = &cobra.Command{
+	Use:   "show <finding-id>",
+	Short: "Show the stored patch of a finding",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		p, err := db.GetPatch(database, findingID)
+		if err != nil {
+			fmt.Printf("❌ No patch for finding %s. Run: probe fix %s\n", shortID(findingID), shortID(findingID))
+			os.Exit(1)
+		}
+
+		printPatch(p)
+		if p.Branch != "" {
+			fmt.Printf("\nApplied on branch %s at %s\n", p.Branch, p.AppliedAt)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+	fixCmd.AddCommand(fixApplyCmd)
+	fixCmd.AddCommand(fixShowCmd)
+
+	fixCmd.Flags().StringVar(&fixProvider, "provider", "", "Provider to use (defaults to the configured default)")
+	fixCmd.Flags().StringVar(&fixModel, "model", "", "Override the default model")
+	fixCmd.Flags().BoolVar(&fixVerbose, "verbose", false, "Enable verbose output")
+	fixApplyCmd.Flags().StringVar(&fixBranch, "branch", "", "Branch to create (default probe/fix-<finding-id>)")
+}
+
+func printPatch(p *db.Patch) {
+	if p.Summary != "" {
+		fmt.Printf("\n%s\n", p.Summary)
+	}
+	fmt.Printf("\n%s", p.Patch)
+}
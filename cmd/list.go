@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	probeListTarget  string
+	probeListProject string
+	probeListStatus  string
+	probeListType    string
+	probeListProv    string
+	probeListModel   string
+	probeListVersion string
+	probeListAgent   string
+	probeListPrompt  string
+	probeListSkill   string
+	probeListSince   string
+	probeListUntil   string
+	probeListSort    string
+	probeListAsc     bool
+	probeListLimit   int
+	probeListCursor  string
+	probeListJSON    bool
+)
+
+var probeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List probes with their finding counts",
+	Long: `Lists probes, newest first, with the number of findings of each severity.
+Filters combine. When more probes match than --limit, the command prints the
+--cursor value that fetches the next page.`,
+	Example: `  probe list
+  probe list --target . --since 30d
+  probe list --status failed --json
+  probe list --sort findings --limit 10
+  probe list --model anthropic/claude-sonnet-4 --prompt-hash 3f2a91`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := db.ProbeFilter{
+			Project:      probeListProject,
+			Status:       probeListStatus,
+			Type:         probeListType,
+			Provider:     probeListProv,
+			Model:        probeListModel,
+			ProbeVersion: probeListVersion,
+			AgentVersion: probeListAgent,
+			PromptHash:   probeListPrompt,
+			SkillHash:    probeListSkill,
+			Sort:         probeListSort,
+			Asc:          probeListAsc,
+			Limit:        probeListLimit,
+			Cursor:       probeListCursor,
+		}
+		if probeListTarget != "" {
+			abs, err := filepath.Abs(probeListTarget)
+			if err != nil {
+				fmt.Printf("❌ Error: %v\n", err)
+				os.Exit(1)
+			}
+			filter.Target = abs
+		}
+		var err error
+		if filter.Since, err = db.ParseSince(probeListSince); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		if filter.Until, err = db.ParseSince(probeListUntil); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+
+		database := openDatabase()
+		defer db.Close(database)
+
+		if filter.Project != "" {
+			if filter.Project, err = db.ResolveProjectID(database, filter.Project); err != nil {
+				fmt.Printf("❌ Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		page, err := db.ListProbes(database, filter)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if probeListJSON {
+			output, _ := json.MarshalIndent(page, "", "  ")
+			fmt.Println(string(output))
+			return
+		}
+
+		if len(page.Probes) == 0 {
+			fmt.Println("No probes match.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tCREATED\tCRIT\tHIGH\tMED\tLOW\tINFO\tTARGET")
+		for _, p := range page.Probes {
+			c := p.FindingCounts
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+				p.ID, p.Type, p.Status, p.CreatedAt, c.Critical, c.High, c.Medium, c.Low, c.Info, p.Target)
+		}
+		w.Flush()
+
+		if page.NextCursor != "" {
+			fmt.Printf("\nMore probes match. Next page: --cursor %s\n", page.NextCursor)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(probeListCmd)
+	probeListCmd.Flags().StringVar(&probeListTarget, "target", "", "Only probes of this target directory")
+	probeListCmd.Flags().StringVar(&probeListProject, "project", "", "Only probes of this project (ID or unique prefix, see probe projects)")
+	probeListCmd.Flags().StringVar(&probeListStatus, "status", "", "Only probes with this status (running, completed, failed)")
+	probeListCmd.Flags().StringVar(&probeListType, "type", "", "Only probes of this type (full, quick)")
+	probeListCmd.Flags().StringVar(&probeListProv, "provider", "", "Only probes run with this provider")
+	probeListCmd.Flags().StringVar(&probeListModel, "model", "", "Only probes run with this model")
+	probeListCmd.Flags().StringVar(&probeListVersion, "probe-version", "", "Only probes run by this probe version")
+	probeListCmd.Flags().StringVar(&probeListAgent, "agent-version", "", "Only probes run with this agent bundle version")
+	probeListCmd.Flags().StringVar(&probeListPrompt, "prompt-hash", "", "Only probes run with this prompt template (hash or prefix)")
+	probeListCmd.Flags().StringVar(&probeListSkill, "skill-hash", "", "Only probes run with this audit skill (hash or prefix)")
+	probeListCmd.Flags().StringVar(&probeListSince, "since", "", "Only probes created after a date (2006-01-02) or within an age (e.g. 30d)")
+	probeListCmd.Flags().StringVar(&probeListUntil, "until", "", "Only probes created before a date (2006-01-02) or age (e.g. 30d)")
+	probeListCmd.Flags().StringVar(&probeListSort, "sort", "created_at", "Sort by created_at, cost, duration or findings")
+	probeListCmd.Flags().BoolVar(&probeListAsc, "asc", false, "Sort ascending instead of descending")
+	probeListCmd.Flags().IntVar(&probeListLimit, "limit", 20, "Maximum number of probes per page")
+	probeListCmd.Flags().StringVar(&probeListCursor, "cursor", "", "Continue from the cursor printed by the previous page")
+	probeListCmd.Flags().BoolVar(&probeListJSON, "json", false, "Output the page as JSON, including next_cursor")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var projectsJSON bool
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "List projects with their latest posture and open findings",
+	Long: `Lists the repositories that have been probed. Clones of the same git
+remote are one project. For each project the command shows the finding
+counts of its latest completed probe and the findings still open or in
+progress across its history.`,
+	Example: `  probe projects
+  probe projects --json
+  probe list --project <id>`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		projects, err := db.ListProjects(database)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if projectsJSON {
+			output, _ := json.MarshalIndent(projects, "", "  ")
+			fmt.Println(string(output))
+			return
+		}
+
+		if len(projects) == 0 {
+			fmt.Println("No projects yet. Run a probe inside a repository first.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPROBES\tLAST PROBE\tCRIT\tHIGH\tMED\tLOW\tOPEN\tREMOTE / ROOT")
+		for _, p := range projects {
+			location := p.RemoteURL
+			if location == "" {
+				location = p.Root
+			}
+			c := p.Posture
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+				shortID(p.ID), p.Name, p.ProbeCount, p.LastProbeAt, c.Critical, c.High, c.Medium, c.Low,
+				p.OpenFindings.Total, location)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(projectsCmd)
+	projectsCmd.Flags().BoolVar(&projectsJSON, "json", false, "Output projects as JSON, including trends")
+}
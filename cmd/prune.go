@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ndzuma/probeTool/internal/config"
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneDryRun   bool
+	pruneKeepLast int
+	pruneMaxAge   int
+	pruneJSON     bool
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete probe history outside the retention policy",
+	Long: `Deletes probes that are not among the most recent --keep-last probes of
+their project (or target, for probes outside a git repository), or that are
+older than --max-age days, together with their findings and files. Probes
+with an open or in-progress critical finding and probes still running are
+always kept.
+
+The limits default to the configured policy (probe config set-retention),
+which the server also applies on start and daily.`,
+	Example: `  probe prune --dry-run
+  probe prune --keep-last 10
+  probe prune --max-age 90 --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		policy := configuredRetention()
+		if cmd.Flags().Changed("keep-last") {
+			policy.KeepLast = pruneKeepLast
+		}
+		if cmd.Flags().Changed("max-age") {
+			policy.MaxAge = time.Duration(pruneMaxAge) * 24 * time.Hour
+		}
+		if pruneKeepLast < 0 || pruneMaxAge < 0 {
+			fmt.Println("❌ Error: retention limits must not be negative")
+			os.Exit(1)
+		}
+		if !policy.Enabled() {
+			fmt.Println("No retention policy set. Use --keep-last/--max-age or: probe config set-retention <keep-last> <max-age-days>")
+			return
+		}
+
+		database := openDatabase()
+		defer db.Close(database)
+
+		result, err := db.Prune(database, policy, time.Now(), pruneDryRun)
+		if err != nil && result == nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if pruneJSON {
+			output, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Println(string(output))
+		} else {
+			printPruneResult(result)
+		}
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show what would be deleted without deleting it")
+	pruneCmd.Flags().IntVar(&pruneKeepLast, "keep-last", 0, "Keep this many most recent probes per project (0 for no limit)")
+	pruneCmd.Flags().IntVar(&pruneMaxAge, "max-age", 0, "Delete probes older than this many days (0 for no limit)")
+	pruneCmd.Flags().BoolVar(&pruneJSON, "json", false, "Output the result as JSON")
+}
+
+func printPruneResult(r *db.PruneResult) {
+	if len(r.Candidates) == 0 {
+		fmt.Println("✅ Nothing to prune")
+		return
+	}
+
+	for _, c := range r.Candidates {
+		fmt.Printf("  %s  %s  %s (%s)\n", c.ID, c.CreatedAt, c.Target, c.Reason)
+	}
+
+	if r.DryRun {
+		fmt.Printf("Would delete %d probe(s), %d finding(s) and %d file(s)\n", len(r.Probes), r.Findings, len(r.Files))
+		return
+	}
+	fmt.Printf("✅ Deleted %d probe(s), %d finding(s) and %d file(s)\n", len(r.Probes), r.Findings, len(r.Files))
+}
+
+// configuredRetention reads the retention policy from the config file.
+func configuredRetention() db.RetentionPolicy {
+	cfg, err := config.Load()
+	if err != nil {
+		return db.RetentionPolicy{}
+	}
+	return db.RetentionPolicy{
+		KeepLast: cfg.Retention.KeepLast,
+		MaxAge:   time.Duration(cfg.Retention.MaxAgeDays) * 24 * time.Hour,
+	}
+}
+
+// pruneInterval is how often a running server applies the retention policy.
+const pruneInterval = 24 * time.Hour
+
+// pruneHistory applies the configured retention policy now and then every
+// pruneInterval until ctx is done. The policy is re-read each time so config
+// changes take effect without a restart.
+func pruneHistory(ctx context.Context, database *sql.DB) {
+	run := func() {
+		policy := configuredRetention()
+		if !policy.Enabled() {
+			return
+		}
+		result, err := db.Prune(database, policy, time.Now(), false)
+		if daemonMode {
+			return
+		}
+		if err != nil {
+			fmt.Printf("Warning: pruning probe history failed: %v\n", err)
+		}
+		if result != nil && len(result.Probes) > 0 {
+			fmt.Printf("Pruned %d probe(s) outside the retention policy\n", len(result.Probes))
+		}
+	}
+
+	run()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
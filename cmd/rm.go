@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rmTarget string
+	rmBefore string
+	rmDryRun bool
+	rmYes    bool
+	rmJSON   bool
+)
+
+var rmCmd = &cobra.Command{
+	Use:   "rm [probe-id...]",
+	Short: "Delete probes with their findings and files",
+	Long: `Deletes probes together with their findings, finding history, comments,
+tags, snippets, patches and verifications, and removes the probe's report and
+transcript files.
+
+Probes are chosen by ID (any unique prefix), or by --target and --before,
+which combine. Use --dry-run to see what would be removed.`,
+	Example: `  probe rm 3f2a9c1e
+  probe rm --target . --dry-run
+  probe rm --before 30d
+  probe rm --target ~/src/app --before 2025-01-01 --yes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 && (rmTarget != "" || rmBefore != "") {
+			fmt.Println("❌ Error: pass probe IDs or --target/--before, not both")
+			os.Exit(1)
+		}
+		if len(args) == 0 && rmTarget == "" && rmBefore == "" {
+			fmt.Println("❌ Error: pass probe IDs, --target or --before")
+			os.Exit(1)
+		}
+
+		database := openDatabase()
+		defer db.Close(database)
+
+		ids, err := selectProbesToRemove(database, args)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(ids) == 0 {
+			fmt.Println("No probes match.")
+			return
+		}
+
+		plan, err := db.DeleteProbes(database, ids, true)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if rmDryRun {
+			printDeleteResult(plan)
+			return
+		}
+
+		if !rmYes && !rmJSON {
+			printDeleteResult(plan)
+			if !confirm("Delete these probes?") {
+				fmt.Println("Aborted.")
+				return
+			}
+		}
+
+		result, err := db.DeleteProbes(database, ids, false)
+		if err != nil && result == nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if rmJSON {
+			out, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Println(string(out))
+		} else {
+			fmt.Printf("✅ Deleted %d probe(s), %d finding(s) and %d file(s)\n", len(result.Probes), result.Findings, len(result.Files))
+		}
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rmCmd)
+	rmCmd.Flags().StringVar(&rmTarget, "target", "", "Delete probes of this target directory")
+	rmCmd.Flags().StringVar(&rmBefore, "before", "", "Delete probes created before a date (2006-01-02) or age (e.g. 30d, 2w)")
+	rmCmd.Flags().BoolVar(&rmDryRun, "dry-run", false, "Show what would be deleted without deleting it")
+	rmCmd.Flags().BoolVarP(&rmYes, "yes", "y", false, "Do not ask for confirmation")
+	rmCmd.Flags().BoolVar(&rmJSON, "json", false, "Output the result as JSON (implies --yes)")
+}
+
+func selectProbesToRemove(database *sql.DB, args []string) ([]string, error) {
+	if len(args) > 0 {
+		var ids []string
+		for _, arg := range args {
+			id, err := db.ResolveProbeID(database, arg)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	var sel db.ProbeSelector
+	if rmTarget != "" {
+		abs, err := filepath.Abs(rmTarget)
+		if err != nil {
+			return nil, err
+		}
+		sel.Target = abs
+	}
+	before, err := db.ParseSince(rmBefore)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --before %q (expected a date like 2006-01-02 or an age like 30d)", rmBefore)
+	}
+	sel.Before = before
+
+	probes, err := db.SelectProbes(database, sel)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(probes))
+	for _, p := range probes {
+		ids = append(ids, p.ID)
+	}
+	return ids, nil
+}
+
+func printDeleteResult(r *db.DeleteResult) {
+	if rmJSON {
+		out, _ := json.MarshalIndent(r, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	for _, p := range r.Probes {
+		fmt.Printf("  %s  %s  %s\n", shortID(p.ID), p.CreatedAt, p.Target)
+	}
+	for _, f := range r.Files {
+		fmt.Printf("  - %s\n", f)
+	}
+
+	verb := "Will delete"
+	if rmDryRun {
+		verb = "Would delete"
+	}
+	fmt.Printf("%s %d probe(s), %d finding(s) and %d file(s)\n", verb, len(r.Probes), r.Findings, len(r.Files))
+}
+
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
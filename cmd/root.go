@@ -114,7 +114,7 @@ func runProbe() {
 		fmt.Printf("Error initializing database: %v\n", err)
 		os.Exit(1)
 	}
-	defer database.Close()
+	defer db.Close(database)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -140,7 +140,7 @@ func runProbe() {
 	}
 
 	fmt.Println("Security audit complete!")
-	if process.IsServerRunning() {
-		fmt.Printf("View: http://localhost:%s/probes/%s\n", process.ServerPort, probeID)
+	if state, err := process.RunningServer(); err == nil {
+		fmt.Printf("View: %s/probes/%s\n", state.URL, probeID)
 	}
 }
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchSeverity string
+	searchTarget   string
+	searchSince    string
+	searchUntil    string
+	searchLimit    int
+	searchJSON     bool
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search findings and reports",
+	Long: `Full-text search over finding titles, descriptions and remediation and
+over the content of every report. All words must match; end a word with * to
+match it as a prefix.`,
+	Example: `  probe search ssrf
+  probe search "path traversal" --severity critical,high
+  probe search token* --target . --since 30d`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		q := db.SearchQuery{Query: strings.Join(args, " "), Limit: searchLimit}
+		if searchSeverity != "" {
+			q.Severity = strings.Split(searchSeverity, ",")
+		}
+		if searchTarget != "" {
+			abs, err := filepath.Abs(searchTarget)
+			if err != nil {
+				fmt.Printf("❌ Error: %v\n", err)
+				os.Exit(1)
+			}
+			q.Target = abs
+		}
+		var err error
+		if q.Since, err = db.ParseSince(searchSince); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		if q.Until, err = db.ParseSince(searchUntil); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+
+		database := openDatabase()
+		defer db.Close(database)
+
+		hits, err := db.Search(database, q)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if searchJSON {
+			output, _ := json.MarshalIndent(hits, "", "  ")
+			fmt.Println(string(output))
+			return
+		}
+
+		if len(hits) == 0 {
+			fmt.Println("No matches.")
+			return
+		}
+		printSearchHits(hits)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().StringVar(&searchSeverity, "severity", "", "Only findings of these severities (comma-separated)")
+	searchCmd.Flags().StringVar(&searchTarget, "target", "", "Only probes of this target directory")
+	searchCmd.Flags().StringVar(&searchSince, "since", "", "Only probes created after a date (2006-01-02) or within an age (e.g. 30d)")
+	searchCmd.Flags().StringVar(&searchUntil, "until", "", "Only probes created before a date (2006-01-02) or age (e.g. 30d)")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", db.DefaultSearchLimit, "Maximum number of results")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Output results as JSON")
+}
+
+func printSearchHits(hits []db.SearchHit) {
+	bold := color.New(color.Bold).SprintFunc()
+	highlight := color.New(color.FgYellow, color.Bold).SprintFunc()
+	dim := color.New(color.Faint).SprintFunc()
+
+	for _, h := range hits {
+		if h.Kind == db.SearchFinding {
+			fmt.Printf("%s  [%s] %s\n", shortID(h.ID), h.Severity, bold(h.Title))
+		} else {
+			fmt.Printf("%s  report %s\n", shortID(h.ID), bold(h.ProbeID))
+		}
+		fmt.Printf("    %s\n", highlightSnippet(h.Snippet, highlight))
+		fmt.Printf("    %s\n\n", dim(fmt.Sprintf("%s · %s · probe %s", h.Target, h.CreatedAt, h.ProbeID)))
+	}
+	fmt.Printf("%d result(s)\n", len(hits))
+}
+
+// highlightSnippet flattens a snippet onto one line and renders its <mark>
+// spans with mark.
+func highlightSnippet(snippet string, mark func(a ...interface{}) string) string {
+	snippet = strings.Join(strings.Fields(snippet), " ")
+	var b strings.Builder
+	for {
+		start := strings.Index(snippet, "<mark>")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(snippet[start:], "</mark>")
+		if end < 0 {
+			break
+		}
+		b.WriteString(snippet[:start])
+		b.WriteString(mark(snippet[start+len("<mark>") : start+end]))
+		snippet = snippet[start+end+len("</mark>"):]
+	}
+	b.WriteString(snippet)
+	return b.String()
+}
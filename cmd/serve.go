@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -11,9 +12,13 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/ndzuma/probeTool/internal/auth"
+	"github.com/ndzuma/probeTool/internal/config"
 	"github.com/ndzuma/probeTool/internal/db"
 	"github.com/ndzuma/probeTool/internal/process"
 	"github.com/ndzuma/probeTool/internal/runtime"
@@ -23,11 +28,24 @@ import (
 
 var quietMode bool
 var daemonMode bool
+var serveHost string
+var servePort int
+var serveAllowedHosts []string
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start the probe dashboard server",
-	Long:  `Starts both the API server and Next.js frontend.`,
+	Long: `Starts both the API server and Next.js frontend.
+
+The server listens on 127.0.0.1:37330 unless --host/--port, the PROBE_HOST and
+PROBE_PORT environment variables or "probe config set-server" say otherwise,
+in that order. When the port is taken it falls back to a free one; "probe
+status" shows the address in use.
+
+Only requests addressed to localhost, 127.0.0.1 or the listen address are
+answered. When listening on a wildcard address such as 0.0.0.0, pass
+--allowed-host for each name or address other machines reach the dashboard
+at.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		runServe()
 	},
@@ -37,6 +55,33 @@ func init() {
 	rootCmd.AddCommand(serveCmd)
 	serveCmd.Flags().BoolVar(&quietMode, "quiet", false, "Run in background (daemon mode)")
 	serveCmd.Flags().BoolVar(&daemonMode, "daemon", false, "Run as daemon (internal use)")
+	serveCmd.Flags().StringVar(&serveHost, "host", "", "Address to listen on (default 127.0.0.1)")
+	serveCmd.Flags().IntVar(&servePort, "port", 0, "Port to listen on (default 37330)")
+	serveCmd.Flags().StringArrayVar(&serveAllowedHosts, "allowed-host", nil, "Also answer requests for this host or host:port (repeatable)")
+}
+
+// serveAddress resolves where to listen: --host and --port, then the
+// environment and the config.
+func serveAddress() (string, int, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", 0, err
+	}
+	host, port, err := cfg.ServerAddress()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if serveHost != "" {
+		host = serveHost
+	}
+	if servePort < 0 || servePort > 65535 {
+		return "", 0, fmt.Errorf("port %d out of range (1-65535)", servePort)
+	}
+	if servePort != 0 {
+		port = servePort
+	}
+	return host, port, nil
 }
 
 func runServe() {
@@ -65,7 +110,18 @@ func startAsDaemon() {
 		os.Exit(1)
 	}
 
-	cmd := exec.Command(execPath, "serve", "--daemon")
+	daemonArgs := []string{"serve", "--daemon"}
+	if serveHost != "" {
+		daemonArgs = append(daemonArgs, "--host", serveHost)
+	}
+	if servePort != 0 {
+		daemonArgs = append(daemonArgs, "--port", strconv.Itoa(servePort))
+	}
+	for _, h := range serveAllowedHosts {
+		daemonArgs = append(daemonArgs, "--allowed-host", h)
+	}
+
+	cmd := exec.Command(execPath, daemonArgs...)
 	cmd.Stdin = nil
 	cmd.Stdout = nil
 	cmd.Stderr = nil
@@ -104,7 +160,7 @@ func runForeground() {
 		process.RemoveServerPID()
 		os.Exit(1)
 	}
-	defer database.Close()
+	defer db.Close(database)
 
 	nodePath, err := runtime.NodePath()
 	if err != nil {
@@ -126,10 +182,39 @@ func runForeground() {
 		os.Exit(1)
 	}
 
+	host, port, err := serveAddress()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		process.RemoveServerPID()
+		os.Exit(1)
+	}
+
+	listener, err := process.Listen(host, port)
+	if err != nil {
+		fmt.Printf("Error listening on %s: %v\n", net.JoinHostPort(host, strconv.Itoa(port)), err)
+		process.RemoveServerPID()
+		os.Exit(1)
+	}
+	if actual := listener.Addr().(*net.TCPAddr).Port; actual != port {
+		if !daemonMode {
+			fmt.Printf("Port %d is in use, using %d instead\n", port, actual)
+		}
+		port = actual
+	}
+
+	nextJSPort, err := process.FreePort("127.0.0.1", process.DefaultNextJSPort)
+	if err != nil {
+		fmt.Printf("Error finding a port for Next.js: %v\n", err)
+		process.RemoveServerPID()
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	nextJSCmd := startNextJS(ctx, nodePath, webPath)
+	go pruneHistory(ctx, database)
+
+	nextJSCmd := startNextJS(ctx, nodePath, webPath, nextJSPort)
 	if nextJSCmd == nil {
 		fmt.Println("Failed to start Next.js server")
 		process.RemoveServerPID()
@@ -141,13 +226,35 @@ func runForeground() {
 		}
 	}()
 
-	if !waitForNextJS(process.NextJSPort, 30*time.Second) {
+	if !waitForNextJS(nextJSPort, 30*time.Second) {
 		fmt.Println("Next.js server failed to start")
 		process.RemoveServerPID()
 		os.Exit(1)
 	}
 
-	mux := createUnifiedServer(database)
+	token, err := auth.LoadToken()
+	if err != nil {
+		fmt.Printf("Error loading API token: %v\n", err)
+		process.RemoveServerPID()
+		os.Exit(1)
+	}
+	if wildcardHost(host) && len(serveAllowedHosts) == 0 && !daemonMode {
+		fmt.Printf("Warning: listening on %s, but only requests for localhost are answered; add --allowed-host for the name other machines use\n", host)
+	}
+	handler := server.Protect(createUnifiedServer(database, token, nextJSPort), token, dashboardOrigins(host, port, serveAllowedHosts)...)
+
+	dashboardURL := process.URL(host, port)
+	err = process.WriteServerState(process.ServerState{
+		PID:        os.Getpid(),
+		Host:       host,
+		Port:       port,
+		URL:        dashboardURL,
+		NextJSPort: nextJSPort,
+		StartedAt:  time.Now().UTC(),
+	})
+	if err != nil && !daemonMode {
+		fmt.Printf("Warning: could not record the server address: %v\n", err)
+	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -162,17 +269,19 @@ func runForeground() {
 			nextJSCmd.Process.Kill()
 		}
 		process.RemoveServerPID()
+		process.RemoveServerState()
 		os.Exit(0)
 	}()
 
 	if !daemonMode {
-		fmt.Printf("Dashboard running at http://localhost:%s\n", process.ServerPort)
+		fmt.Printf("Dashboard running at %s\n", dashboardURL)
 		fmt.Println("Press Ctrl+C to stop")
 	}
 
-	if err := http.ListenAndServe(":"+process.ServerPort, mux); err != nil {
+	if err := http.Serve(listener, handler); err != nil {
 		fmt.Printf("Server error: %v\n", err)
 		process.RemoveServerPID()
+		process.RemoveServerState()
 		os.Exit(1)
 	}
 }
@@ -219,12 +328,14 @@ func ensureNextJSReady(nodePath, webPath string) error {
 	return nil
 }
 
-func startNextJS(ctx context.Context, nodePath, webPath string) *exec.Cmd {
+// startNextJS starts Next.js on the loopback interface; it is only reached
+// through the unified server's proxy.
+func startNextJS(ctx context.Context, nodePath, webPath string, port int) *exec.Cmd {
 	npmPath, _ := runtime.NpmPath()
 
-	cmd := exec.CommandContext(ctx, npmPath, "run", "start")
+	cmd := exec.CommandContext(ctx, npmPath, "run", "start", "--", "--hostname", "127.0.0.1", "--port", strconv.Itoa(port))
 	cmd.Dir = webPath
-	cmd.Env = append(os.Environ(), "PORT="+process.NextJSPort, "PATH="+filepath.Dir(nodePath)+":"+os.Getenv("PATH"))
+	cmd.Env = append(os.Environ(), "PORT="+strconv.Itoa(port), "PATH="+filepath.Dir(nodePath)+":"+os.Getenv("PATH"))
 
 	if daemonMode {
 		cmd.Stdout = nil
@@ -238,8 +349,8 @@ func startNextJS(ctx context.Context, nodePath, webPath string) *exec.Cmd {
 	return cmd
 }
 
-func waitForNextJS(port string, timeout time.Duration) bool {
-	checkURL := fmt.Sprintf("http://localhost:%s", port)
+func waitForNextJS(port int, timeout time.Duration) bool {
+	checkURL := fmt.Sprintf("http://127.0.0.1:%d", port)
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
@@ -256,11 +367,42 @@ func waitForNextJS(port string, timeout time.Duration) bool {
 	return false
 }
 
-func createUnifiedServer(database *sql.DB) *http.ServeMux {
+// dashboardOrigins are the origins the dashboard is served from: the
+// loopback names, the host itself when it is a specific address such as a
+// LAN or WSL IP, and the allowed hosts. An allowed host without a port is
+// reached on port.
+func dashboardOrigins(host string, port int, allowed []string) []string {
+	p := strconv.Itoa(port)
+	origins := []string{"http://localhost:" + p, "http://127.0.0.1:" + p}
+	if h := strings.Trim(host, "[]"); h != "localhost" && h != "127.0.0.1" && !wildcardHost(h) {
+		origins = append(origins, "http://"+net.JoinHostPort(h, p))
+	}
+	for _, h := range allowed {
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(strings.Trim(h, "[]"), p)
+		}
+		origins = append(origins, "http://"+h)
+	}
+	return origins
+}
+
+// wildcardHost reports whether host listens on every interface.
+func wildcardHost(host string) bool {
+	switch strings.Trim(host, "[]") {
+	case "", "0.0.0.0", "::":
+		return true
+	}
+	return false
+}
+
+// createUnifiedServer serves the API and proxies everything else to
+// Next.js, adding the API token to the pages it returns.
+func createUnifiedServer(database *sql.DB, token string, nextJSPort int) *http.ServeMux {
 	mux := http.NewServeMux()
 
-	nextURL, _ := url.Parse(fmt.Sprintf("http://localhost:%s", process.NextJSPort))
+	nextURL, _ := url.Parse(fmt.Sprintf("http://127.0.0.1:%d", nextJSPort))
 	proxy := httputil.NewSingleHostReverseProxy(nextURL)
+	proxy.ModifyResponse = server.InjectToken(token)
 
 	server.RegisterRoutes(mux, database)
 
@@ -271,6 +413,8 @@ func createUnifiedServer(database *sql.DB) *http.ServeMux {
 	})
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Pages must arrive uncompressed for the token to be added
+		r.Header.Del("Accept-Encoding")
 		proxy.ServeHTTP(w, r)
 	})
 
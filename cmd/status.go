@@ -3,6 +3,8 @@ package cmd
 import (
 	"database/sql"
 	"fmt"
+	"net"
+	"strconv"
 
 	"github.com/ndzuma/probeTool/internal/db"
 	"github.com/ndzuma/probeTool/internal/process"
@@ -30,7 +32,12 @@ func runStatus() {
 	if process.IsServerRunning() {
 		pid, _ := process.ReadServerPID()
 		fmt.Printf("Server:     Running (PID: %d)\n", pid)
-		fmt.Printf("Dashboard:  http://localhost:%s\n", process.ServerPort)
+		if state, err := process.RunningServer(); err == nil {
+			fmt.Printf("Address:    %s\n", net.JoinHostPort(state.Host, strconv.Itoa(state.Port)))
+			fmt.Printf("Dashboard:  %s\n", state.URL)
+		} else {
+			fmt.Println("Dashboard:  Starting...")
+		}
 	} else {
 		fmt.Println("Server:     Not running")
 	}
@@ -49,7 +56,7 @@ func runStatus() {
 		fmt.Printf("Reports:    Database unavailable\n")
 		return
 	}
-	defer database.Close()
+	defer db.Close(database)
 
 	probeCount := getProbeCount(database)
 	completedCount := getCompletedProbeCount(database)
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageBy    string
+	usageSince string
+	usageJSON  bool
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token usage and cost of past probes",
+	Long: `Aggregates tokens, cost, duration and turns recorded for each probe.
+
+Examples:
+  probe usage
+  probe usage --by model --since 30d
+  probe usage --by target --since 2w --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runUsage()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.Flags().StringVar(&usageBy, "by", "provider", "Group by provider, model or target")
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "Only include probes newer than this (e.g. 30d, 2w, 12h)")
+	usageCmd.Flags().BoolVar(&usageJSON, "json", false, "Output usage as JSON")
+}
+
+func runUsage() {
+	since, err := db.ParseSince(usageSince)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	database, err := db.InitDB(db.DBPath())
+	if err != nil {
+		fmt.Printf("Error initializing database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close(database)
+
+	summaries, err := db.GetUsage(database, usageBy, since)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	if usageJSON {
+		if summaries == nil {
+			summaries = []db.UsageSummary{}
+		}
+		output, _ := json.MarshalIndent(summaries, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	if len(summaries) == 0 {
+		fmt.Println("No probes recorded in this period.")
+		return
+	}
+
+	var total db.UsageSummary
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tPROBES\tINPUT\tOUTPUT\tCOST\tDURATION\n", usageHeader(usageBy))
+	for _, s := range summaries {
+		key := s.Key
+		if key == "" {
+			key = "(unknown)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t$%.4f\t%.1fs\n", key, s.Probes, s.InputTokens, s.OutputTokens, s.CostUSD, float64(s.DurationMS)/1000)
+
+		total.Probes += s.Probes
+		total.InputTokens += s.InputTokens
+		total.OutputTokens += s.OutputTokens
+		total.CostUSD += s.CostUSD
+		total.DurationMS += s.DurationMS
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%d\t%d\t$%.4f\t%.1fs\n", total.Probes, total.InputTokens, total.OutputTokens, total.CostUSD, float64(total.DurationMS)/1000)
+	w.Flush()
+}
+
+func usageHeader(by string) string {
+	switch by {
+	case "model":
+		return "MODEL"
+	case "target":
+		return "TARGET"
+	default:
+		return "PROVIDER"
+	}
+}
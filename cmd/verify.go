@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/findings"
+	"github.com/ndzuma/probeTool/internal/prober"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyProvider string
+	verifyModel    string
+	verifyVerbose  bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <finding-id>",
+	Short: "Check whether a finding has been fixed",
+	Long: `Runs a short agent session scoped to a single finding's description and
+location in its probe's target. The verdict (fixed, still present or
+inconclusive) is recorded in the finding's history, and the finding is marked
+fixed or reopened to match.
+
+The finding ID may be abbreviated to any unique prefix.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		database := openDatabase()
+		defer db.Close(database)
+
+		findingID := resolveFinding(database, args[0])
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigChan
+			cancel()
+		}()
+
+		fmt.Printf("🔍 Verifying finding %s...\n", shortID(findingID))
+
+		v, change, err := prober.VerifyFinding(ctx, database, findingID, prober.VerifyArgs{
+			Provider: verifyProvider,
+			Model:    verifyModel,
+			Verbose:  verifyVerbose,
+			Actor:    currentUser(),
+		})
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printVerification(v, change)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().StringVar(&verifyProvider, "provider", "", "Provider to use (defaults to the configured default)")
+	verifyCmd.Flags().StringVar(&verifyModel, "model", "", "Override the default model")
+	verifyCmd.Flags().BoolVar(&verifyVerbose, "verbose", false, "Enable verbose output")
+}
+
+func printVerification(v *db.Verification, change *db.StateChange) {
+	switch v.Verdict {
+	case findings.VerdictFixed:
+		fmt.Println("✅ Fixed")
+	case findings.VerdictStillPresent:
+		fmt.Println("🔴 Still present")
+	default:
+		fmt.Println("❔ Inconclusive")
+	}
+
+	if v.Reasoning != "" {
+		fmt.Printf("\n%s\n", v.Reasoning)
+	}
+
+	fmt.Println()
+	if change.FromState != change.ToState {
+		fmt.Printf("State: %s → %s\n", change.FromState, change.ToState)
+	} else {
+		fmt.Printf("State: %s (unchanged)\n", change.ToState)
+	}
+	if v.CostUSD > 0 {
+		fmt.Printf("Cost: $%.4f\n", v.CostUSD)
+	}
+}
@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Paths of the files that shape a report, relative to the agent directory.
+// probe-runner.js loads the skill from the same place.
+const (
+	PromptFile = "prompts.js"
+	SkillFile  = ".claude/skills/security-audit/SKILL.md"
+)
+
+// Missing is recorded in place of the hash of a file that is not
+// installed, so it is told apart from a probe that recorded no hashes.
+const Missing = "missing"
+
+// Bundle identifies the installed agent a probe runs with. Hashes are the
+// hex SHA-256 of the file, or Missing, so a report can be tied to the exact
+// prompt and skill rules that produced it.
+type Bundle struct {
+	Version    string `json:"agent_version"`
+	PromptHash string `json:"prompt_hash"`
+	SkillHash  string `json:"skill_hash"`
+}
+
+// Describe reads the version and file hashes of the agent installed in dir.
+// A missing package.json leaves the version empty rather than failing.
+func Describe(dir string) Bundle {
+	var b Bundle
+
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			Version string `json:"version"`
+		}
+		if json.Unmarshal(data, &pkg) == nil {
+			b.Version = pkg.Version
+		}
+	}
+	b.PromptHash = hashFile(filepath.Join(dir, PromptFile))
+	b.SkillHash = hashFile(filepath.Join(dir, filepath.FromSlash(SkillFile)))
+
+	return b
+}
+
+func hashFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Missing
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
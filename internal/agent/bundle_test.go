@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	dir := t.TempDir()
+
+	if b := Describe(dir); b != (Bundle{PromptHash: Missing, SkillHash: Missing}) {
+		t.Errorf("Describe() of an empty directory = %+v, want the files missing", b)
+	}
+
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "probe-agent", "version": "1.2.0"}`), 0644)
+	os.WriteFile(filepath.Join(dir, PromptFile), []byte("export const prompt = 'a'"), 0644)
+	os.MkdirAll(filepath.Join(dir, ".claude", "skills", "security-audit"), 0755)
+	os.WriteFile(filepath.Join(dir, ".claude", "skills", "security-audit", "SKILL.md"), []byte("# Rules"), 0644)
+
+	b := Describe(dir)
+	if b.Version != "1.2.0" || len(b.PromptHash) != 64 || len(b.SkillHash) != 64 {
+		t.Errorf("Describe() = %+v", b)
+	}
+
+	os.WriteFile(filepath.Join(dir, ".claude", "skills", "security-audit", "SKILL.md"), []byte("# Rules v2"), 0644)
+	if changed := Describe(dir); changed.SkillHash == b.SkillHash || changed.PromptHash != b.PromptHash {
+		t.Error("Editing SKILL.md should change only the skill hash")
+	}
+}
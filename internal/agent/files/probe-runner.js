@@ -16,6 +16,10 @@ const target = getArg('target') || process.cwd()
 const outPath = getArg('out')
 const model = getArg('model') || 'anthropic/claude-3.5-haiku'
 const verbose = getArg('verbose') === 'true'
+// verify re-checks and fix patches one finding (read from --finding)
+// instead of running a full audit
+const mode = getArg('mode') || 'audit'
+const findingPath = getArg('finding')
 
 // Helper function for verbose logging
 function verboseLog(msg) {
@@ -41,7 +45,7 @@ if (process.env.ANTHROPIC_API_KEY !== '') {
 }
 
 // Import prompt
-import { fullAuditPrompt } from './prompts.js'
+import { fullAuditPrompt, verifyPrompt, fixPrompt } from './prompts.js'
 
 console.log(`PROGRESS:init:openrouter:${model}`)
 
@@ -64,12 +68,25 @@ try {
   process.exit(1)
 }
 
+let prompt = fullAuditPrompt(target)
+let finding = null
+if (mode === 'verify' || mode === 'fix') {
+  try {
+    finding = JSON.parse(readFileSync(findingPath, 'utf-8'))
+  } catch (err) {
+    console.error(`ERROR: Could not read finding: ${err.message}`)
+    process.exit(1)
+  }
+  prompt = mode === 'fix' ? fixPrompt(target, finding) : verifyPrompt(target, finding)
+}
+
 // FIX: Inject skill directly into systemPrompt instead of relying on Skill tool
 const options = {
   model: model,
   
   // Don't use Skill tool - we're injecting directly
-  allowedTools: ['Read', 'Glob', 'Grep', 'Bash'],
+  // Single-finding modes only read the code around the finding
+  allowedTools: mode !== 'audit' ? ['Read', 'Glob', 'Grep'] : ['Read', 'Glob', 'Grep', 'Bash'],
   
   permissionMode: 'acceptEdits',
   
@@ -96,6 +113,7 @@ Follow ALL instructions from the security-audit skill above.
 verboseLog(`Agent directory: ${__dirname}`)
 verboseLog(`Target directory: ${target}`)
 verboseLog(`Model: ${model}`)
+verboseLog(`Mode: ${mode}`)
 verboseLog(`Allowed tools: ${options.allowedTools.join(', ')}`)
 verboseLog(`✓ Skill content injected into systemPrompt (${skillContent.length} bytes)`)
 
@@ -106,7 +124,7 @@ let currentSection = ''
 console.log('PROGRESS:reading_files')
 
 try {
-  for await (const message of query({ prompt: fullAuditPrompt(target), options })) {
+  for await (const message of query({ prompt, options })) {
     
     // ADD: Log message types in verbose mode
     if (verbose && message.type) {
@@ -167,19 +185,35 @@ try {
       if (message.subtype === 'success') {
         console.log('PROGRESS:finalizing')
         
-        // Clean up markdown
-        const reportStart = markdownOutput.indexOf('# Security')
-        if (reportStart !== -1) {
-          markdownOutput = markdownOutput.substring(reportStart)
+        // Single-finding output is parsed as-is for its verdict or patch
+        if (mode === 'audit') {
+          // Clean up markdown
+          const reportStart = markdownOutput.indexOf('# Security')
+          if (reportStart !== -1) {
+            markdownOutput = markdownOutput.substring(reportStart)
+          }
+
+          // Add metadata footer
+          markdownOutput += `\n\n---\n\n## Audit Metadata\n\n`
+          markdownOutput += `- **Cost**: $${message.total_cost_usd.toFixed(4)}\n`
+          markdownOutput += `- **Duration**: ${(message.duration_ms / 1000).toFixed(2)}s\n`
+          markdownOutput += `- **Turns**: ${message.num_turns}\n`
+          markdownOutput += `- **Model**: ${model}\n`
+          markdownOutput += `- **Auditor**: Claude Security Engineer\n`
         }
-        
-        // Add metadata footer
-        markdownOutput += `\n\n---\n\n## Audit Metadata\n\n`
-        markdownOutput += `- **Cost**: $${message.total_cost_usd.toFixed(4)}\n`
-        markdownOutput += `- **Duration**: ${(message.duration_ms / 1000).toFixed(2)}s\n`
-        markdownOutput += `- **Turns**: ${message.num_turns}\n`
-        markdownOutput += `- **Model**: ${model}\n`
-        markdownOutput += `- **Auditor**: Claude Security Engineer\n`
+
+        // Machine-readable usage for the CLI to persist. Cache reads and
+        // writes are billed as input, so they count towards input tokens.
+        const usage = message.usage || {}
+        console.log(`USAGE:${JSON.stringify({
+          input_tokens: (usage.input_tokens || 0) +
+            (usage.cache_creation_input_tokens || 0) +
+            (usage.cache_read_input_tokens || 0),
+          output_tokens: usage.output_tokens || 0,
+          cost_usd: message.total_cost_usd || 0,
+          duration_ms: message.duration_ms || 0,
+          num_turns: message.num_turns || 0,
+        })}`)
       } else {
         console.error(`ERROR:Audit failed: ${message.subtype}`)
         if (message.errors) {
@@ -0,0 +1,60 @@
+// Package auth manages the token that guards the local API. The token is
+// generated on first use and kept in the app directory, readable only by
+// the user; the server injects it into the dashboard it serves.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ndzuma/probeTool/internal/paths"
+)
+
+// Scheme prefixes the token in the Authorization header.
+const Scheme = "Bearer "
+
+// LoadToken returns the API token, creating it on first use.
+func LoadToken() (string, error) {
+	return loadToken(paths.GetAPITokenPath())
+}
+
+func loadToken(path string) (string, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read API token: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save API token: %w", err)
+	}
+	return token, nil
+}
+
+// Authorize adds the token to a request.
+func Authorize(r *http.Request, token string) {
+	r.Header.Set("Authorization", Scheme+token)
+}
+
+// Valid reports whether a request carries the token.
+func Valid(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), Scheme)
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
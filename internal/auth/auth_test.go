@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app", "api-token")
+
+	token, err := loadToken(path)
+	if err != nil {
+		t.Fatalf("loadToken() failed: %v", err)
+	}
+	if len(token) != 64 {
+		t.Errorf("Token %q should be 64 hex characters", token)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatalf("Token file not written: %v", err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Token file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if again, _ := loadToken(path); again != token {
+		t.Errorf("loadToken() = %q on second use, want the stored %q", again, token)
+	}
+}
+
+func TestValid(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/probes", nil)
+	if Valid(req, "secret") {
+		t.Error("Valid() without a header should be false")
+	}
+
+	Authorize(req, "secret")
+	if !Valid(req, "secret") {
+		t.Error("Valid() with the token should be true")
+	}
+	if Valid(req, "other") {
+		t.Error("Valid() with another token should be false")
+	}
+	if Valid(req, "") {
+		t.Error("Valid() with no configured token should be false")
+	}
+
+	req.Header.Set("Authorization", "secret")
+	if Valid(req, "secret") {
+		t.Error("Valid() without the Bearer scheme should be false")
+	}
+}
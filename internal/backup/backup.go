@@ -0,0 +1,584 @@
+// Package backup archives and restores all probe state: a snapshot of the
+// database, the report and transcript files, and the config.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ndzuma/probeTool/internal/config"
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/paths"
+	"github.com/ndzuma/probeTool/internal/version"
+)
+
+// FormatVersion is the archive layout this binary writes and reads.
+const FormatVersion = 1
+
+// Archive entry names.
+const (
+	manifestEntry = "manifest.json"
+	databaseEntry = "probes.db"
+	configEntry   = "config.json"
+	probesPrefix  = "probes/"
+)
+
+// ErrExistingState is returned when restoring over a database or config
+// that already holds data without Force.
+var ErrExistingState = errors.New("probe already has data here; restore with --force to replace it")
+
+// Manifest describes an archive. It is the first entry so a restore can
+// refuse an incompatible archive before extracting anything else.
+type Manifest struct {
+	Format        int      `json:"format"`
+	ProbeVersion  string   `json:"probe_version"`
+	SchemaVersion int      `json:"schema_version"`
+	CreatedAt     string   `json:"created_at"`
+	KeysStripped  bool     `json:"keys_stripped"`
+	Files         []string `json:"files"`
+}
+
+// Paths locates the state to back up or restore into.
+type Paths struct {
+	DBPath     string
+	ProbesDir  string
+	ConfigPath string
+}
+
+// DefaultPaths returns the locations probe uses.
+func DefaultPaths() Paths {
+	return Paths{
+		DBPath:     paths.GetDBPath(),
+		ProbesDir:  paths.GetProbesDir(),
+		ConfigPath: paths.GetConfigPath(),
+	}
+}
+
+// Create writes a gzipped tar archive of the database, the files in the
+// probes directory and the config. With stripKeys the archived config has
+// no API keys.
+func Create(database *sql.DB, archive string, p Paths, stripKeys bool) (*Manifest, error) {
+	schema, err := db.SchemaVersion(database)
+	if err != nil {
+		return nil, err
+	}
+
+	work, err := os.MkdirTemp("", "probe-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(work)
+
+	snapshot := filepath.Join(work, databaseEntry)
+	if err := db.Snapshot(database, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	manifest := &Manifest{
+		Format:        FormatVersion,
+		ProbeVersion:  version.Version,
+		SchemaVersion: schema,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		KeysStripped:  stripKeys,
+	}
+
+	// entry name -> source file
+	entries := [][2]string{{databaseEntry, snapshot}}
+
+	if _, err := os.Stat(p.ConfigPath); err == nil {
+		cfgFile := p.ConfigPath
+		if stripKeys {
+			cfgFile = filepath.Join(work, configEntry)
+			if err := writeStrippedConfig(p.ConfigPath, cfgFile); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, [2]string{configEntry, cfgFile})
+	}
+
+	files, err := stateFiles(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		entries = append(entries, [2]string{probesPrefix + name, filepath.Join(p.ProbesDir, name)})
+	}
+
+	for _, e := range entries {
+		manifest.Files = append(manifest.Files, e[0])
+	}
+
+	tmp := archive + ".tmp"
+	if err := writeArchive(tmp, manifest, entries); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, archive); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// stateFiles lists the report and transcript files in the probes
+// directory. The database and its journals and migration backups are left
+// out; the database is archived from its snapshot.
+func stateFiles(p Paths) ([]string, error) {
+	entries, err := os.ReadDir(p.ProbesDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dbName := filepath.Base(p.DBPath)
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || strings.HasPrefix(name, dbName) {
+			continue
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+func writeStrippedConfig(src, dest string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	var cfg config.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	for name, provider := range cfg.Providers {
+		provider.APIKey = ""
+		cfg.Providers[name] = provider
+	}
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, out, 0600)
+}
+
+func writeArchive(archive string, manifest *Manifest, entries [][2]string) error {
+	f, err := os.OpenFile(archive, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: manifestEntry, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := addFile(tw, e[0], e[1]); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", e[0], err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func addFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Inspect reads the manifest of an archive and checks that this binary can
+// restore it.
+func Inspect(archive string) (*Manifest, error) {
+	var manifest *Manifest
+	err := readArchive(archive, func(name string, r io.Reader) (bool, error) {
+		if name != manifestEntry {
+			return false, fmt.Errorf("not a probe backup: first entry is %s, not %s", name, manifestEntry)
+		}
+		m, err := decodeManifest(r)
+		manifest = m
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("not a probe backup: archive is empty")
+	}
+	return manifest, nil
+}
+
+func decodeManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	if m.Format < 1 || m.Format > FormatVersion {
+		return nil, fmt.Errorf("backup format %d is not supported by this version of probe (supports %d); upgrade probe with: probe update", m.Format, FormatVersion)
+	}
+
+	migrations, err := db.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if m.SchemaVersion > len(migrations) {
+		return nil, fmt.Errorf("%w: backup has schema version %d, this probe supports up to %d", db.ErrSchemaTooNew, m.SchemaVersion, len(migrations))
+	}
+	return &m, nil
+}
+
+// readArchive calls fn for each entry in order until fn returns false.
+func readArchive(archive string, fn func(name string, r io.Reader) (bool, error)) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("not a probe backup: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("corrupt backup: %w", err)
+		}
+		more, err := fn(hdr.Name, tr)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// RestoreResult describes a completed restore.
+type RestoreResult struct {
+	Manifest *Manifest `json:"manifest"`
+	// From and To are the schema versions before and after migrating the
+	// restored database.
+	From     int `json:"from"`
+	To       int `json:"to"`
+	Probes   int `json:"probes"`
+	Files    int `json:"files"`
+	Relinked int `json:"relinked"`
+	// Previous is where the replaced database was moved, if there was one.
+	Previous string `json:"previous,omitempty"`
+}
+
+// Restore replaces the state at p with the contents of archive. The
+// archive's database is checked for integrity and migrated to the current
+// schema before anything is replaced. Without force, Restore refuses to
+// overwrite a database that holds probes or a config with providers.
+func Restore(archive string, p Paths, force bool) (*RestoreResult, error) {
+	work, err := os.MkdirTemp("", "probe-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(work)
+
+	manifest, err := extract(archive, work)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Manifest: manifest}
+	if err := prepareDatabase(filepath.Join(work, databaseEntry), manifest, result); err != nil {
+		return nil, err
+	}
+
+	if !force {
+		if has, err := hasState(p); err != nil {
+			return nil, err
+		} else if has {
+			return nil, ErrExistingState
+		}
+	}
+
+	if err := os.MkdirAll(p.ProbesDir, 0755); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(p.DBPath); err == nil {
+		result.Previous = fmt.Sprintf("%s.pre-restore-%s.bak", p.DBPath, time.Now().Format("20060102-150405"))
+		if err := os.Rename(p.DBPath, result.Previous); err != nil {
+			return nil, err
+		}
+	}
+	// The write-ahead log holds changes not yet in the database file, so it
+	// moves with the previous database rather than being dropped.
+	for _, journal := range []string{"-wal", "-shm", "-journal"} {
+		if result.Previous != "" {
+			os.Rename(p.DBPath+journal, result.Previous+journal)
+		}
+		os.Remove(p.DBPath + journal)
+	}
+	if err := copyFile(filepath.Join(work, databaseEntry), p.DBPath); err != nil {
+		return nil, err
+	}
+
+	files, _ := os.ReadDir(filepath.Join(work, "probes"))
+	for _, f := range files {
+		if err := copyFile(filepath.Join(work, "probes", f.Name()), filepath.Join(p.ProbesDir, f.Name())); err != nil {
+			return nil, err
+		}
+		result.Files++
+	}
+
+	if _, err := os.Stat(filepath.Join(work, configEntry)); err == nil {
+		if err := restoreConfig(filepath.Join(work, configEntry), p.ConfigPath, manifest.KeysStripped); err != nil {
+			return nil, err
+		}
+	}
+
+	restored, err := db.Open(p.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close(restored)
+
+	if err := restored.QueryRow(`SELECT COUNT(*) FROM probes`).Scan(&result.Probes); err != nil {
+		return nil, err
+	}
+	if result.Relinked, err = relinkReports(restored, p.ProbesDir); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// extract unpacks archive into dir, rejecting entries outside the layout
+// Create writes.
+func extract(archive, dir string) (*Manifest, error) {
+	var manifest *Manifest
+	err := readArchive(archive, func(name string, r io.Reader) (bool, error) {
+		if manifest == nil {
+			if name != manifestEntry {
+				return false, fmt.Errorf("not a probe backup: first entry is %s, not %s", name, manifestEntry)
+			}
+			m, err := decodeManifest(r)
+			manifest = m
+			return err == nil, err
+		}
+
+		var dest string
+		switch {
+		case name == databaseEntry || name == configEntry:
+			dest = filepath.Join(dir, name)
+		case strings.HasPrefix(name, probesPrefix):
+			base := strings.TrimPrefix(name, probesPrefix)
+			if base == "" || base != path.Base(base) || base == ".." || base == "." {
+				return false, fmt.Errorf("backup entry %q is not allowed", name)
+			}
+			dest = filepath.Join(dir, "probes", base)
+		default:
+			return false, fmt.Errorf("backup entry %q is not allowed", name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return false, err
+		}
+		out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return false, err
+		}
+		_, err = io.Copy(out, r)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("not a probe backup: archive is empty")
+	}
+	if _, err := os.Stat(filepath.Join(dir, databaseEntry)); err != nil {
+		return nil, fmt.Errorf("backup has no database")
+	}
+	return manifest, nil
+}
+
+// prepareDatabase checks an extracted database against its manifest and
+// migrates it to this binary's schema.
+func prepareDatabase(file string, manifest *Manifest, result *RestoreResult) error {
+	database, err := db.Open(file)
+	if err != nil {
+		return err
+	}
+	defer db.Close(database)
+
+	if err := db.IntegrityCheck(database); err != nil {
+		return err
+	}
+
+	schema, err := db.SchemaVersion(database)
+	if err != nil {
+		return err
+	}
+	if schema != manifest.SchemaVersion {
+		return fmt.Errorf("backup database has schema version %d but its manifest says %d", schema, manifest.SchemaVersion)
+	}
+
+	migrated, err := db.Migrate(database)
+	if err != nil {
+		return fmt.Errorf("failed to migrate backup database: %w", err)
+	}
+	if migrated.Backup != "" {
+		os.Remove(migrated.Backup)
+	}
+	result.From, result.To = migrated.From, migrated.To
+	return nil
+}
+
+// hasState reports whether p already holds probes or configured providers.
+func hasState(p Paths) (bool, error) {
+	if _, err := os.Stat(p.DBPath); err == nil {
+		existing, err := db.Open(p.DBPath)
+		if err != nil {
+			return false, err
+		}
+		var probes int
+		err = existing.QueryRow(`SELECT COUNT(*) FROM probes`).Scan(&probes)
+		db.Close(existing)
+		if err == nil && probes > 0 {
+			return true, nil
+		}
+	}
+
+	if data, err := os.ReadFile(p.ConfigPath); err == nil {
+		var cfg config.Config
+		if json.Unmarshal(data, &cfg) == nil && len(cfg.Providers) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// restoreConfig installs the archived config. Keys stripped from the
+// archive are taken from the current config for providers of the same name.
+func restoreConfig(src, dest string, keysStripped bool) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if keysStripped {
+		var restored, current config.Config
+		if err := json.Unmarshal(data, &restored); err != nil {
+			return fmt.Errorf("invalid config in backup: %w", err)
+		}
+		if existing, err := os.ReadFile(dest); err == nil && json.Unmarshal(existing, &current) == nil {
+			for name, provider := range restored.Providers {
+				if provider.APIKey == "" {
+					provider.APIKey = current.Providers[name].APIKey
+					restored.Providers[name] = provider
+				}
+			}
+		}
+		if data, err = json.MarshalIndent(restored, "", "  "); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0600)
+}
+
+// relinkReports points probes whose report path does not exist on this
+// machine at the restored file of the same name.
+func relinkReports(database *sql.DB, probesDir string) (int, error) {
+	probes, err := db.GetAllProbes(database)
+	if err != nil {
+		return 0, err
+	}
+
+	relinked := 0
+	for _, p := range probes {
+		if p.FilePath == "" {
+			continue
+		}
+		if _, err := os.Stat(p.FilePath); err == nil {
+			continue
+		}
+		// Reports may come from another OS, so split on either separator.
+		base := p.FilePath[strings.LastIndexAny(p.FilePath, `/\`)+1:]
+		candidate := filepath.Join(probesDir, base)
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		if err := db.RelinkReport(database, p.ID, candidate); err != nil {
+			return relinked, err
+		}
+		relinked++
+	}
+	return relinked, nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,255 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ndzuma/probeTool/internal/config"
+	"github.com/ndzuma/probeTool/internal/db"
+)
+
+// setupState creates a database with one probe, its report file and a
+// config with an API key under dir.
+func setupState(t *testing.T, dir string) Paths {
+	t.Helper()
+	p := Paths{
+		DBPath:     filepath.Join(dir, "probes", "probes.db"),
+		ProbesDir:  filepath.Join(dir, "probes"),
+		ConfigPath: filepath.Join(dir, "config.json"),
+	}
+	os.MkdirAll(p.ProbesDir, 0755)
+
+	database, err := db.InitDB(p.DBPath)
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer db.Close(database)
+
+	report := filepath.Join(p.ProbesDir, "p1.md")
+	os.WriteFile(report, []byte("# Report"), 0644)
+	os.WriteFile(filepath.Join(p.ProbesDir, "p1.jsonl"), []byte("{}\n"), 0644)
+	db.InsertProbe(database, "p1", "full", "/tmp/app", report)
+	db.SaveReport(database, "p1", "# Report")
+
+	writeConfig(t, p.ConfigPath, "sk-secret")
+	return p
+}
+
+func writeConfig(t *testing.T, path, key string) {
+	t.Helper()
+	cfg := config.Config{Providers: map[string]config.Provider{
+		"openrouter": {Name: "openrouter", APIKey: key},
+	}}
+	data, _ := json.Marshal(cfg)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readConfig(t *testing.T, path string) config.Config {
+	t.Helper()
+	var cfg config.Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	json.Unmarshal(data, &cfg)
+	return cfg
+}
+
+func createBackup(t *testing.T, p Paths, stripKeys bool) string {
+	t.Helper()
+	database, err := db.Open(p.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close(database)
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if _, err := Create(database, archive, p, stripKeys); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	return archive
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	src := setupState(t, t.TempDir())
+	archive := createBackup(t, src, false)
+
+	m, err := Inspect(archive)
+	if err != nil {
+		t.Fatalf("Inspect() failed: %v", err)
+	}
+	if m.Format != FormatVersion || m.KeysStripped {
+		t.Errorf("Unexpected manifest %+v", m)
+	}
+	for _, want := range []string{"probes.db", "config.json", "probes/p1.md", "probes/p1.jsonl"} {
+		found := false
+		for _, f := range m.Files {
+			found = found || f == want
+		}
+		if !found {
+			t.Errorf("Manifest files %v missing %s", m.Files, want)
+		}
+	}
+
+	dest := Paths{
+		DBPath:     filepath.Join(t.TempDir(), "probes", "probes.db"),
+		ConfigPath: filepath.Join(t.TempDir(), "config.json"),
+	}
+	dest.ProbesDir = filepath.Dir(dest.DBPath)
+
+	result, err := Restore(archive, dest, false)
+	if err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if result.Probes != 1 || result.Files != 2 {
+		t.Errorf("Restore() = %+v, want 1 probe and 2 files", result)
+	}
+
+	// The source report path still exists, so only a removed source is relinked.
+	database, err := db.Open(dest.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close(database)
+	if r, err := db.GetReport(database, "p1"); err != nil || r.Content != "# Report" {
+		t.Errorf("GetReport() after restore = %+v, %v", r, err)
+	}
+	if cfg := readConfig(t, dest.ConfigPath); cfg.Providers["openrouter"].APIKey != "sk-secret" {
+		t.Errorf("Restored config lost the API key: %+v", cfg.Providers)
+	}
+
+	// Restoring again over existing probes needs force.
+	if _, err := Restore(archive, dest, false); !errors.Is(err, ErrExistingState) {
+		t.Errorf("Restore() over existing state error = %v, want ErrExistingState", err)
+	}
+	result, err = Restore(archive, dest, true)
+	if err != nil {
+		t.Fatalf("Restore() with force failed: %v", err)
+	}
+	if _, err := os.Stat(result.Previous); err != nil {
+		t.Errorf("Previous database not kept at %q: %v", result.Previous, err)
+	}
+}
+
+func TestBackupRelinksReports(t *testing.T) {
+	srcDir := t.TempDir()
+	src := setupState(t, srcDir)
+	archive := createBackup(t, src, false)
+	os.RemoveAll(srcDir)
+
+	dir := t.TempDir()
+	dest := Paths{
+		DBPath:     filepath.Join(dir, "probes", "probes.db"),
+		ProbesDir:  filepath.Join(dir, "probes"),
+		ConfigPath: filepath.Join(dir, "config.json"),
+	}
+	result, err := Restore(archive, dest, false)
+	if err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if result.Relinked != 1 {
+		t.Errorf("Relinked = %d, want 1", result.Relinked)
+	}
+
+	database, _ := db.Open(dest.DBPath)
+	defer db.Close(database)
+	p, _ := db.GetProbe(database, "p1")
+	if p.FilePath != filepath.Join(dest.ProbesDir, "p1.md") {
+		t.Errorf("FilePath = %q, want it in the restored probes dir", p.FilePath)
+	}
+}
+
+func TestBackupStripKeys(t *testing.T) {
+	src := setupState(t, t.TempDir())
+	archive := createBackup(t, src, true)
+
+	if m, _ := Inspect(archive); !m.KeysStripped {
+		t.Error("Manifest should record stripped keys")
+	}
+
+	dir := t.TempDir()
+	dest := Paths{
+		DBPath:     filepath.Join(dir, "probes", "probes.db"),
+		ProbesDir:  filepath.Join(dir, "probes"),
+		ConfigPath: filepath.Join(dir, "config.json"),
+	}
+	if _, err := Restore(archive, dest, false); err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if cfg := readConfig(t, dest.ConfigPath); cfg.Providers["openrouter"].APIKey != "" {
+		t.Error("Stripped backup restored an API key")
+	}
+
+	// Keys already configured here survive a stripped restore.
+	writeConfig(t, dest.ConfigPath, "sk-local")
+	if _, err := Restore(archive, dest, true); err != nil {
+		t.Fatalf("Restore() with force failed: %v", err)
+	}
+	if cfg := readConfig(t, dest.ConfigPath); cfg.Providers["openrouter"].APIKey != "sk-local" {
+		t.Errorf("API key = %q, want the existing key kept", cfg.Providers["openrouter"].APIKey)
+	}
+}
+
+// writeTar builds an archive from a manifest and raw entries.
+func writeTar(t *testing.T, manifest Manifest, entries map[string]string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "crafted.tar.gz")
+	f, _ := os.Create(archive)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	data, _ := json.Marshal(manifest)
+	tw.WriteHeader(&tar.Header{Name: manifestEntry, Mode: 0600, Size: int64(len(data))})
+	tw.Write(data)
+	for name, body := range entries {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(body))})
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	gz.Close()
+	return archive
+}
+
+func TestRestoreRejectsIncompatibleArchives(t *testing.T) {
+	dir := t.TempDir()
+	dest := Paths{
+		DBPath:     filepath.Join(dir, "probes", "probes.db"),
+		ProbesDir:  filepath.Join(dir, "probes"),
+		ConfigPath: filepath.Join(dir, "config.json"),
+	}
+
+	tooNew := writeTar(t, Manifest{Format: FormatVersion, SchemaVersion: 1000}, nil)
+	if _, err := Restore(tooNew, dest, false); !errors.Is(err, db.ErrSchemaTooNew) {
+		t.Errorf("Restore() of newer schema error = %v, want ErrSchemaTooNew", err)
+	}
+
+	newFormat := writeTar(t, Manifest{Format: FormatVersion + 1}, nil)
+	if _, err := Inspect(newFormat); err == nil || !strings.Contains(err.Error(), "format") {
+		t.Errorf("Inspect() of newer format error = %v", err)
+	}
+
+	for _, name := range []string{"../escape", "probes/../../escape", "/etc/passwd", "probes/sub/file"} {
+		archive := writeTar(t, Manifest{Format: FormatVersion, SchemaVersion: 1}, map[string]string{name: "x"})
+		if _, err := Restore(archive, dest, false); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("Restore() with entry %q error = %v, want it rejected", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape")); err == nil {
+		t.Error("Path traversal entry was written")
+	}
+
+	notGzip := filepath.Join(dir, "plain.txt")
+	os.WriteFile(notGzip, []byte("hello"), 0644)
+	if _, err := Inspect(notGzip); err == nil {
+		t.Error("Inspect() should reject a file that is not a backup")
+	}
+}
@@ -0,0 +1,277 @@
+package compare
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/findings"
+)
+
+// Finding statuses between a base and a head probe.
+const (
+	StatusNew        = "new"
+	StatusResolved   = "resolved"
+	StatusPersisting = "persisting"
+)
+
+// How a persisting finding was matched: by fingerprint, or by a similar
+// title in the same file and category.
+const (
+	MatchFingerprint = "fingerprint"
+	MatchSimilar     = "similar"
+)
+
+// Severity changes for persisting findings.
+const (
+	SeverityEscalated  = "escalated"
+	SeverityDowngraded = "downgraded"
+)
+
+var severityRank = map[string]int{
+	"info":     0,
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// Entry is one finding matched across the two probes.
+type Entry struct {
+	Fingerprint      string      `json:"fingerprint"`
+	Status           string      `json:"status"`
+	Match            string      `json:"match,omitempty"`
+	Text             string      `json:"text"`
+	Severity         string      `json:"severity"`
+	PreviousSeverity string      `json:"previous_severity,omitempty"`
+	SeverityChange   string      `json:"severity_change,omitempty"`
+	Base             *db.Finding `json:"base,omitempty"`
+	Head             *db.Finding `json:"head,omitempty"`
+}
+
+// Summary counts entries per status.
+type Summary struct {
+	New             int `json:"new"`
+	Resolved        int `json:"resolved"`
+	Persisting      int `json:"persisting"`
+	SeverityChanged int `json:"severity_changed"`
+}
+
+// Result is the comparison of a base (older) probe against a head (newer) one.
+type Result struct {
+	Base       *db.Probe `json:"base"`
+	Head       *db.Probe `json:"head"`
+	New        []Entry   `json:"new"`
+	Resolved   []Entry   `json:"resolved"`
+	Persisting []Entry   `json:"persisting"`
+	Summary    Summary   `json:"summary"`
+}
+
+// ErrTargetMismatch is returned when the probes audited different projects,
+// or different targets when either is not linked to a project.
+type ErrTargetMismatch struct {
+	Base, Head string
+}
+
+func (e *ErrTargetMismatch) Error() string {
+	return fmt.Sprintf("probes audit different projects (%s vs %s)", e.Base, e.Head)
+}
+
+// SameProject reports whether two probes audited the same code: the same
+// project when both are linked to one, so clones and checkouts of a
+// repository compare, or else the same target.
+func SameProject(a, b *db.Probe) bool {
+	if a.ProjectID != "" && b.ProjectID != "" {
+		return a.ProjectID == b.ProjectID
+	}
+	return a.Target == b.Target
+}
+
+// Probes loads two probes and their findings and compares them.
+func Probes(database *sql.DB, baseID, headID string) (*Result, error) {
+	base, err := db.GetProbe(database, baseID)
+	if err != nil {
+		return nil, fmt.Errorf("probe %s not found: %w", baseID, err)
+	}
+	head, err := db.GetProbe(database, headID)
+	if err != nil {
+		return nil, fmt.Errorf("probe %s not found: %w", headID, err)
+	}
+
+	if !SameProject(base, head) {
+		return nil, &ErrTargetMismatch{Base: base.Target, Head: head.Target}
+	}
+
+	baseFindings, err := db.GetFindingsByProbe(database, baseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load findings for %s: %w", baseID, err)
+	}
+	headFindings, err := db.GetFindingsByProbe(database, headID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load findings for %s: %w", headID, err)
+	}
+
+	result := Findings(baseFindings, headFindings)
+	result.Base = base
+	result.Head = head
+	return result, nil
+}
+
+// Findings matches two finding sets. Findings are matched by fingerprint
+// first; a finding left over in one set then matches the most similar left
+// over finding of the other in the same file and category, so a reworded
+// title does not read as one issue fixed and another found. Findings only in
+// head are new, findings only in base are resolved and the rest are
+// persisting.
+func Findings(base, head []db.Finding) *Result {
+	baseByFingerprint := indexByFingerprint(base)
+	headByFingerprint := indexByFingerprint(head)
+
+	result := &Result{
+		New:        []Entry{},
+		Resolved:   []Entry{},
+		Persisting: []Entry{},
+	}
+
+	var unmatchedBase, unmatchedHead []db.Finding
+	for _, f := range uniqueFindings(base) {
+		if _, ok := headByFingerprint[f.Fingerprint]; !ok {
+			unmatchedBase = append(unmatchedBase, f)
+		}
+	}
+	matched := make(map[int]bool, len(unmatchedBase))
+
+	for _, f := range uniqueFindings(head) {
+		h := f
+		if b, ok := baseByFingerprint[f.Fingerprint]; ok {
+			result.addPersisting(b, &h, MatchFingerprint)
+			continue
+		}
+		unmatchedHead = append(unmatchedHead, h)
+	}
+
+	for _, f := range unmatchedHead {
+		h := f
+		if i := mostSimilar(h, unmatchedBase, matched); i >= 0 {
+			matched[i] = true
+			result.addPersisting(&unmatchedBase[i], &h, MatchSimilar)
+			continue
+		}
+
+		result.New = append(result.New, Entry{
+			Fingerprint: f.Fingerprint,
+			Status:      StatusNew,
+			Text:        h.Text,
+			Severity:    h.Severity,
+			Head:        &h,
+		})
+	}
+
+	for i, f := range unmatchedBase {
+		if matched[i] {
+			continue
+		}
+		b := f
+		result.Resolved = append(result.Resolved, Entry{
+			Fingerprint: f.Fingerprint,
+			Status:      StatusResolved,
+			Text:        b.Text,
+			Severity:    b.Severity,
+			Base:        &b,
+		})
+	}
+
+	sortEntries(result.New)
+	sortEntries(result.Resolved)
+	sortEntries(result.Persisting)
+
+	result.Summary.New = len(result.New)
+	result.Summary.Resolved = len(result.Resolved)
+	result.Summary.Persisting = len(result.Persisting)
+
+	return result
+}
+
+func (r *Result) addPersisting(b, h *db.Finding, match string) {
+	entry := Entry{
+		Fingerprint: h.Fingerprint,
+		Status:      StatusPersisting,
+		Match:       match,
+		Text:        h.Text,
+		Severity:    h.Severity,
+		Base:        b,
+		Head:        h,
+	}
+	if b.Severity != h.Severity {
+		entry.PreviousSeverity = b.Severity
+		entry.SeverityChange = severityChange(b.Severity, h.Severity)
+		r.Summary.SeverityChanged++
+	}
+	r.Persisting = append(r.Persisting, entry)
+}
+
+// mostSimilar returns the index of the candidate not yet matched that is
+// most similar to f, at or above findings.DefaultSimilarity, or -1.
+func mostSimilar(f db.Finding, candidates []db.Finding, matched map[int]bool) int {
+	best, bestScore := -1, 0.0
+	for i, c := range candidates {
+		if matched[i] {
+			continue
+		}
+		score := findings.Similarity(parsed(c), parsed(f))
+		if score >= findings.DefaultSimilarity && score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func parsed(f db.Finding) findings.Finding {
+	return findings.Finding{Text: f.Text, File: f.File, LineStart: f.LineStart, LineEnd: f.LineEnd, CWE: f.CWE, OWASP: f.OWASP}
+}
+
+func indexByFingerprint(findings []db.Finding) map[string]*db.Finding {
+	index := make(map[string]*db.Finding, len(findings))
+	for i := range findings {
+		if _, ok := index[findings[i].Fingerprint]; !ok {
+			index[findings[i].Fingerprint] = &findings[i]
+		}
+	}
+	return index
+}
+
+// uniqueFindings drops repeated fingerprints within one probe.
+func uniqueFindings(findings []db.Finding) []db.Finding {
+	seen := make(map[string]bool, len(findings))
+	var unique []db.Finding
+	for _, f := range findings {
+		if seen[f.Fingerprint] {
+			continue
+		}
+		seen[f.Fingerprint] = true
+		unique = append(unique, f)
+	}
+	return unique
+}
+
+func severityChange(from, to string) string {
+	if severityRank[to] > severityRank[from] {
+		return SeverityEscalated
+	}
+	if severityRank[to] < severityRank[from] {
+		return SeverityDowngraded
+	}
+	return ""
+}
+
+// sortEntries orders entries by descending severity, then text.
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, rj := severityRank[entries[i].Severity], severityRank[entries[j].Severity]
+		if ri != rj {
+			return ri > rj
+		}
+		return entries[i].Text < entries[j].Text
+	})
+}
@@ -0,0 +1,153 @@
+package compare
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/gitinfo"
+)
+
+func finding(fingerprint, text, severity string) db.Finding {
+	return db.Finding{ID: fingerprint + "-" + severity, Fingerprint: fingerprint, Text: text, Severity: severity}
+}
+
+func TestFindings(t *testing.T) {
+	base := []db.Finding{
+		finding("a", "SQL injection in login", "critical"),
+		finding("b", "Missing CSRF token", "medium"),
+		finding("c", "Verbose error messages", "low"),
+	}
+	head := []db.Finding{
+		finding("a", "SQL injection in login", "critical"),
+		finding("b", "Missing CSRF token", "high"),
+		finding("d", "Hardcoded API key", "high"),
+		finding("d", "Hardcoded API key", "high"),
+	}
+
+	result := Findings(base, head)
+
+	if result.Summary.New != 1 || result.New[0].Fingerprint != "d" {
+		t.Errorf("Expected finding d to be new, got %+v", result.New)
+	}
+	if result.Summary.Resolved != 1 || result.Resolved[0].Fingerprint != "c" {
+		t.Errorf("Expected finding c to be resolved, got %+v", result.Resolved)
+	}
+	if result.Summary.Persisting != 2 {
+		t.Fatalf("Expected 2 persisting findings, got %d", result.Summary.Persisting)
+	}
+	if result.Summary.SeverityChanged != 1 {
+		t.Errorf("Expected 1 severity change, got %d", result.Summary.SeverityChanged)
+	}
+
+	for _, e := range result.Persisting {
+		if e.Fingerprint != "b" {
+			continue
+		}
+		if e.SeverityChange != SeverityEscalated || e.PreviousSeverity != "medium" || e.Severity != "high" {
+			t.Errorf("Unexpected severity change: %+v", e)
+		}
+	}
+
+	// Persisting entries are ordered by severity
+	if result.Persisting[0].Severity != "critical" {
+		t.Errorf("Expected critical finding first, got %s", result.Persisting[0].Severity)
+	}
+}
+
+func TestFindingsDowngraded(t *testing.T) {
+	result := Findings(
+		[]db.Finding{finding("a", "Weak hashing", "high")},
+		[]db.Finding{finding("a", "Weak hashing", "low")},
+	)
+
+	if len(result.Persisting) != 1 || result.Persisting[0].SeverityChange != SeverityDowngraded {
+		t.Errorf("Expected a downgraded persisting finding, got %+v", result.Persisting)
+	}
+}
+
+func TestFindingsEmpty(t *testing.T) {
+	result := Findings(nil, nil)
+	if result.New == nil || result.Resolved == nil || result.Persisting == nil {
+		t.Error("Entry lists should be empty slices, not nil")
+	}
+}
+
+func TestProbes(t *testing.T) {
+	database, err := db.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer db.Close(database)
+
+	db.InsertProbe(database, "old", "full", "/repo", "")
+	db.InsertProbe(database, "new", "full", "/repo", "")
+	db.InsertProbe(database, "other", "full", "/elsewhere", "")
+
+	db.InsertFinding(database, "f1", "old", "SQL injection in login handler", "critical")
+	db.InsertFinding(database, "f2", "old", "Missing rate limiting on API", "medium")
+	db.InsertFinding(database, "f3", "new", "SQL injection in login handler", "critical")
+
+	result, err := Probes(database, "old", "new")
+	if err != nil {
+		t.Fatalf("Probes() failed: %v", err)
+	}
+	if result.Summary.Resolved != 1 || result.Summary.Persisting != 1 || result.Summary.New != 0 {
+		t.Errorf("Unexpected summary: %+v", result.Summary)
+	}
+	if result.Base.ID != "old" || result.Head.ID != "new" {
+		t.Errorf("Unexpected probes: %s → %s", result.Base.ID, result.Head.ID)
+	}
+
+	if _, err := Probes(database, "old", "other"); err == nil {
+		t.Error("Probes() should reject probes of different targets")
+	} else if _, ok := err.(*ErrTargetMismatch); !ok {
+		t.Errorf("Expected ErrTargetMismatch, got %T", err)
+	}
+
+	// Clones of one repository compare through their project
+	project, err := db.EnsureProject(database, gitinfo.Repo{Root: "/repo", RemoteURL: "github.com/org/app"})
+	if err != nil {
+		t.Fatalf("EnsureProject() failed: %v", err)
+	}
+	db.InsertProbe(database, "clone", "full", "/home/ci/app", "")
+	for _, id := range []string{"old", "clone"} {
+		db.SetProbeProject(database, id, project.ID)
+	}
+	if _, err := Probes(database, "old", "clone"); err != nil {
+		t.Errorf("Probes() should compare probes of one project: %v", err)
+	}
+
+	if _, err := Probes(database, "old", "missing"); err == nil {
+		t.Error("Probes() should fail for unknown probes")
+	}
+}
+
+func TestFindingsSimilar(t *testing.T) {
+	located := func(fingerprint, text, file string, line int, cwe string) db.Finding {
+		f := finding(fingerprint, text, "high")
+		f.File, f.LineStart, f.CWE = file, line, cwe
+		return f
+	}
+	base := []db.Finding{
+		located("a", "SQL injection in login handler", "src/login.js", 42, "CWE-89"),
+		located("b", "Hardcoded API key in config", "src/config.js", 3, "CWE-798"),
+	}
+	head := []db.Finding{
+		// Reworded and moved: still the same issue
+		located("a2", "SQL injection vulnerability in the login handler", "src/login.js", 60, "CWE-89"),
+		// Similar title, but another category
+		located("b2", "Hardcoded API key in config", "src/config.js", 3, "CWE-200"),
+	}
+
+	result := Findings(base, head)
+	if result.Summary.Persisting != 1 || result.Persisting[0].Match != MatchSimilar || result.Persisting[0].Base.Fingerprint != "a" {
+		t.Errorf("Expected the reworded finding to persist, got %+v", result.Persisting)
+	}
+	if result.Summary.New != 1 || result.New[0].Fingerprint != "b2" {
+		t.Errorf("Expected b2 to be new, got %+v", result.New)
+	}
+	if result.Summary.Resolved != 1 || result.Resolved[0].Fingerprint != "b" {
+		t.Errorf("Expected b to be resolved, got %+v", result.Resolved)
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/ndzuma/probeTool/internal/paths"
+	"github.com/ndzuma/probeTool/internal/redact"
 )
 
 type Config struct {
 	Providers map[string]Provider `json:"providers"`
 	Default   string              `json:"default"`
+	Profiles  map[string]Profile  `json:"profiles,omitempty"`
+	Retention Retention           `json:"retention,omitempty"`
+	Redaction Redaction           `json:"redaction,omitempty"`
+	Server    Server              `json:"server,omitempty"`
 }
 
 type Provider struct {
@@ -22,6 +28,53 @@ type Provider struct {
 	DefaultModel string   `json:"default_model"`
 }
 
+// Profile holds settings for one probe type ("full" or "quick").
+type Profile struct {
+	// SimilarityThreshold is the title similarity (0-1) at which findings in
+	// the same file are merged as duplicates. Zero uses the default; a value
+	// above 1 turns merging off.
+	SimilarityThreshold float64 `json:"similarity_threshold,omitempty"`
+}
+
+// Retention limits how much probe history is kept. Zero values keep
+// everything; probes with open critical findings are always kept.
+type Retention struct {
+	// KeepLast is the number of most recent probes kept per project, or
+	// per target for probes outside a git repository.
+	KeepLast int `json:"keep_last,omitempty"`
+	// MaxAgeDays drops probes older than this many days.
+	MaxAgeDays int `json:"max_age_days,omitempty"`
+}
+
+// Redaction configures the masking of secret values in reports and
+// findings before they are stored.
+type Redaction struct {
+	// Patterns are extra regular expressions to mask. A pattern with a
+	// capturing group masks only the first group.
+	Patterns []string `json:"patterns,omitempty"`
+	// NoDefaults drops the built-in credential patterns.
+	NoDefaults bool `json:"no_defaults,omitempty"`
+}
+
+// Server sets where the dashboard server listens. Empty values use the
+// defaults.
+type Server struct {
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+}
+
+// Default server address: the loopback interface only.
+const (
+	DefaultHost = "127.0.0.1"
+	DefaultPort = 37330
+)
+
+// Environment variables that override the server settings.
+const (
+	HostEnv = "PROBE_HOST"
+	PortEnv = "PROBE_PORT"
+)
+
 // GetConfigDir returns the application directory path
 // Deprecated: Use paths.GetAppDir() instead
 func GetConfigDir() string {
@@ -173,6 +226,151 @@ func (c *Config) SetDefault(providerName string) error {
 	return c.Save()
 }
 
+// SetSimilarityThreshold sets the duplicate-merging threshold of a profile
+func (c *Config) SetSimilarityThreshold(profile string, threshold float64) error {
+	if threshold < 0 {
+		return fmt.Errorf("threshold must not be negative")
+	}
+
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]Profile)
+	}
+
+	p := c.Profiles[profile]
+	p.SimilarityThreshold = threshold
+	c.Profiles[profile] = p
+	return c.Save()
+}
+
+// SimilarityThreshold returns the duplicate-merging threshold of a profile,
+// or zero when it is not set
+func (c *Config) SimilarityThreshold(profile string) float64 {
+	return c.Profiles[profile].SimilarityThreshold
+}
+
+// SetRetention sets the retention policy. Zero turns a limit off.
+func (c *Config) SetRetention(keepLast, maxAgeDays int) error {
+	if keepLast < 0 || maxAgeDays < 0 {
+		return fmt.Errorf("retention limits must not be negative")
+	}
+
+	c.Retention = Retention{KeepLast: keepLast, MaxAgeDays: maxAgeDays}
+	return c.Save()
+}
+
+// AddRedactionPattern adds a pattern to mask, checking that it compiles
+func (c *Config) AddRedactionPattern(pattern string) error {
+	if _, err := redact.Compile(pattern); err != nil {
+		return err
+	}
+	for _, p := range c.Redaction.Patterns {
+		if p == pattern {
+			return fmt.Errorf("pattern %q already configured", pattern)
+		}
+	}
+
+	c.Redaction.Patterns = append(c.Redaction.Patterns, pattern)
+	return c.Save()
+}
+
+// RemoveRedactionPattern removes a configured pattern
+func (c *Config) RemoveRedactionPattern(pattern string) error {
+	for i, p := range c.Redaction.Patterns {
+		if p == pattern {
+			c.Redaction.Patterns = append(c.Redaction.Patterns[:i], c.Redaction.Patterns[i+1:]...)
+			return c.Save()
+		}
+	}
+	return fmt.Errorf("pattern %q not configured", pattern)
+}
+
+// Redactor builds the redactor for the configured patterns
+func (c *Config) Redactor() (*redact.Redactor, error) {
+	return redact.New(c.Redaction.Patterns, c.Redaction.NoDefaults)
+}
+
+// SetServer sets the host and port the server listens on. An empty host
+// or a zero port restores the default.
+func (c *Config) SetServer(host string, port int) error {
+	if err := checkPort(port); err != nil {
+		return err
+	}
+
+	c.Server = Server{Host: host, Port: port}
+	return c.Save()
+}
+
+// ServerAddress returns the host and port the server listens on:
+// PROBE_HOST and PROBE_PORT if set, then the config, then 127.0.0.1:37330.
+func (c *Config) ServerAddress() (string, int, error) {
+	host, port := c.Server.Host, c.Server.Port
+
+	if env := os.Getenv(HostEnv); env != "" {
+		host = env
+	}
+	if env := os.Getenv(PortEnv); env != "" {
+		p, err := strconv.Atoi(env)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid %s %q: expected a port number", PortEnv, env)
+		}
+		port = p
+	}
+	if err := checkPort(port); err != nil {
+		return "", 0, err
+	}
+
+	if host == "" {
+		host = DefaultHost
+	}
+	if port == 0 {
+		port = DefaultPort
+	}
+	return host, port, nil
+}
+
+func checkPort(port int) error {
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("port %d out of range (1-65535)", port)
+	}
+	return nil
+}
+
+// MaskKey hides an API key, keeping its last four characters so keys can
+// be told apart. An empty key stays empty.
+func MaskKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 8 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
+
+// Masked returns a copy of the config with every API key masked
+func (c *Config) Masked() *Config {
+	masked := *c
+	masked.Providers = make(map[string]Provider, len(c.Providers))
+	for name, p := range c.Providers {
+		p.APIKey = MaskKey(p.APIKey)
+		masked.Providers[name] = p
+	}
+	return &masked
+}
+
+// KeepKeys makes API keys write-only: a provider whose key is empty or the
+// masked form of its key in current keeps the current key. Any other value
+// replaces it.
+func (c *Config) KeepKeys(current *Config) {
+	for name, p := range c.Providers {
+		old, ok := current.Providers[name]
+		if ok && (p.APIKey == "" || p.APIKey == MaskKey(old.APIKey)) {
+			p.APIKey = old.APIKey
+			c.Providers[name] = p
+		}
+	}
+}
+
 // ListProviders returns a list of provider names
 func (c *Config) ListProviders() []string {
 	names := make([]string, 0, len(c.Providers))
@@ -362,3 +362,158 @@ func TestListProviders(t *testing.T) {
 		}
 	}
 }
+
+func TestSimilarityThreshold(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("USERPROFILE", tmpDir)
+	t.Setenv("APPDATA", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	cfg := &Config{Providers: make(map[string]Provider)}
+
+	if got := cfg.SimilarityThreshold("full"); got != 0 {
+		t.Errorf("Unset threshold should be 0, got %v", got)
+	}
+
+	if err := cfg.SetSimilarityThreshold("quick", 0.7); err != nil {
+		t.Fatalf("SetSimilarityThreshold() failed: %v", err)
+	}
+	if err := cfg.SetSimilarityThreshold("quick", -1); err == nil {
+		t.Error("SetSimilarityThreshold() should reject negative thresholds")
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.SimilarityThreshold("quick"); got != 0.7 {
+		t.Errorf("Threshold for quick = %v, want 0.7", got)
+	}
+	if got := loaded.SimilarityThreshold("full"); got != 0 {
+		t.Errorf("Threshold for full = %v, want 0", got)
+	}
+}
+
+func TestSetRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("USERPROFILE", tmpDir)
+	t.Setenv("APPDATA", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	cfg := &Config{Providers: make(map[string]Provider)}
+
+	if err := cfg.SetRetention(5, 90); err != nil {
+		t.Fatalf("SetRetention() failed: %v", err)
+	}
+	if err := cfg.SetRetention(-1, 0); err == nil {
+		t.Error("SetRetention() should reject negative limits")
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if want := (Retention{KeepLast: 5, MaxAgeDays: 90}); loaded.Retention != want {
+		t.Errorf("Retention = %+v, want %+v", loaded.Retention, want)
+	}
+}
+
+func TestRedactionPatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("USERPROFILE", tmpDir)
+	t.Setenv("APPDATA", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	cfg := &Config{Providers: make(map[string]Provider)}
+
+	if err := cfg.AddRedactionPattern(`ACME-[0-9]{8}`); err != nil {
+		t.Fatalf("AddRedactionPattern() failed: %v", err)
+	}
+	if err := cfg.AddRedactionPattern(`ACME-[0-9]{8}`); err == nil {
+		t.Error("AddRedactionPattern() should reject a duplicate")
+	}
+	if err := cfg.AddRedactionPattern(`(`); err == nil {
+		t.Error("AddRedactionPattern() should reject an invalid pattern")
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	r, err := loaded.Redactor()
+	if err != nil {
+		t.Fatalf("Redactor() failed: %v", err)
+	}
+	if got, _ := r.Redact("id ACME-12345678"); got != "id [REDACTED]" {
+		t.Errorf("Redact() with the configured pattern = %q", got)
+	}
+
+	if err := loaded.RemoveRedactionPattern(`ACME-[0-9]{8}`); err != nil {
+		t.Fatalf("RemoveRedactionPattern() failed: %v", err)
+	}
+	if err := loaded.RemoveRedactionPattern(`ACME-[0-9]{8}`); err == nil {
+		t.Error("RemoveRedactionPattern() of a missing pattern should fail")
+	}
+}
+
+func TestMaskKey(t *testing.T) {
+	tests := map[string]string{
+		"":                    "",
+		"short":               "****",
+		"sk-or-v1-abcdef1234": "****1234",
+	}
+	for in, want := range tests {
+		if got := MaskKey(in); got != want {
+			t.Errorf("MaskKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	current := &Config{Providers: map[string]Provider{"a": {APIKey: "sk-or-v1-abcdef1234"}}}
+	if masked := current.Masked(); masked.Providers["a"].APIKey != "****1234" || current.Providers["a"].APIKey != "sk-or-v1-abcdef1234" {
+		t.Errorf("Masked() = %+v and changed the original to %+v", masked.Providers, current.Providers)
+	}
+
+	update := &Config{Providers: map[string]Provider{"a": {APIKey: "****1234"}, "b": {APIKey: "new"}}}
+	update.KeepKeys(current)
+	if update.Providers["a"].APIKey != "sk-or-v1-abcdef1234" || update.Providers["b"].APIKey != "new" {
+		t.Errorf("KeepKeys() = %+v", update.Providers)
+	}
+}
+
+func TestServerAddress(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("USERPROFILE", tmpDir)
+	t.Setenv("APPDATA", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv(HostEnv, "")
+	t.Setenv(PortEnv, "")
+
+	cfg := &Config{Providers: make(map[string]Provider)}
+	if host, port, err := cfg.ServerAddress(); err != nil || host != "127.0.0.1" || port != 37330 {
+		t.Errorf("ServerAddress() = %s:%d, %v; want 127.0.0.1:37330", host, port, err)
+	}
+
+	if err := cfg.SetServer("0.0.0.0", 8080); err != nil {
+		t.Fatalf("SetServer() failed: %v", err)
+	}
+	if err := cfg.SetServer("", 70000); err == nil {
+		t.Error("SetServer() should reject an out of range port")
+	}
+	loaded, _ := Load()
+	if host, port, _ := loaded.ServerAddress(); host != "0.0.0.0" || port != 8080 {
+		t.Errorf("ServerAddress() = %s:%d, want the configured 0.0.0.0:8080", host, port)
+	}
+
+	t.Setenv(PortEnv, "9090")
+	if host, port, _ := loaded.ServerAddress(); host != "0.0.0.0" || port != 9090 {
+		t.Errorf("ServerAddress() = %s:%d, want %s to override the port", host, port, PortEnv)
+	}
+	t.Setenv(PortEnv, "http")
+	if _, _, err := loaded.ServerAddress(); err == nil {
+		t.Errorf("ServerAddress() should reject an invalid %s", PortEnv)
+	}
+}
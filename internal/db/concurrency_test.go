@@ -0,0 +1,289 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ndzuma/probeTool/internal/gitinfo"
+)
+
+// openHandles opens n independent handles on one database file, as
+// separate prober and server processes would.
+func openHandles(t *testing.T, n int) []*sql.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "probes.db")
+
+	first, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	handles := []*sql.DB{first}
+	for i := 1; i < n; i++ {
+		h, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open() failed: %v", err)
+		}
+		handles = append(handles, h)
+	}
+	t.Cleanup(func() {
+		for _, h := range handles {
+			Close(h)
+		}
+	})
+	return handles
+}
+
+func TestConnectionSettings(t *testing.T) {
+	db := openHandles(t, 1)[0]
+
+	var mode string
+	if err := db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %q, %v; want wal", mode, err)
+	}
+	var timeout int64
+	if err := db.QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil || timeout != BusyTimeout.Milliseconds() {
+		t.Errorf("busy_timeout = %d, %v; want %d", timeout, err, BusyTimeout.Milliseconds())
+	}
+	var fk int
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&fk); err != nil || fk != 1 {
+		t.Errorf("foreign_keys = %d, %v; want 1", fk, err)
+	}
+}
+
+func TestCreateFindingsIsAtomic(t *testing.T) {
+	db := openHandles(t, 1)[0]
+	InsertProbe(db, "p", "full", "/tmp/app", "")
+
+	list := []Finding{
+		{ID: "f1", ProbeID: "p", Text: "XSS", Severity: "high"},
+		{ID: "f1", ProbeID: "p", Text: "Duplicate ID", Severity: "low"},
+	}
+	if err := CreateFindings(db, list); err == nil {
+		t.Fatal("CreateFindings() with a duplicate ID should fail")
+	}
+	if got, _ := GetFindingsByProbe(db, "p"); len(got) != 0 {
+		t.Errorf("A failed CreateFindings() left %d findings behind", len(got))
+	}
+
+	list[1].ID = "f2"
+	if err := CreateFindings(db, list); err != nil {
+		t.Fatalf("CreateFindings() failed: %v", err)
+	}
+	got, _ := GetFindingsByProbe(db, "p")
+	if len(got) != 2 || got[0].State != StateOpen || got[0].Fingerprint == "" {
+		t.Errorf("CreateFindings() stored %+v", got)
+	}
+}
+
+// TestConcurrentProbesAndTriage runs several probes writing their findings
+// through separate handles while another handle triages findings, as the
+// server does, and checks that every write lands.
+func TestConcurrentProbesAndTriage(t *testing.T) {
+	const probes, findingsPerProbe = 8, 25
+	handles := openHandles(t, probes+1)
+	server := handles[probes]
+
+	InsertProbe(server, "seed", "full", "/tmp/app", "")
+	CreateFinding(server, &Finding{ID: "seed-finding", ProbeID: "seed", Text: "Seed", Severity: "low"})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, probes*4+100)
+
+	for i := 0; i < probes; i++ {
+		wg.Add(1)
+		go func(db *sql.DB, i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("probe-%d", i)
+			if err := InsertProbe(db, id, "full", fmt.Sprintf("/tmp/app-%d", i), ""); err != nil {
+				errs <- fmt.Errorf("InsertProbe(%s): %w", id, err)
+				return
+			}
+			list := make([]Finding, findingsPerProbe)
+			for j := range list {
+				list[j] = Finding{ID: fmt.Sprintf("%s-f%d", id, j), ProbeID: id, Text: fmt.Sprintf("Issue %d", j), Severity: "medium"}
+			}
+			if err := CreateFindings(db, list); err != nil {
+				errs <- fmt.Errorf("CreateFindings(%s): %w", id, err)
+			}
+			if err := SaveReport(db, id, "# Report "+id); err != nil {
+				errs <- fmt.Errorf("SaveReport(%s): %w", id, err)
+			}
+			if err := UpdateProbeStatus(db, id, "completed"); err != nil {
+				errs <- fmt.Errorf("UpdateProbeStatus(%s): %w", id, err)
+			}
+		}(handles[i], i)
+	}
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			state := StateInProgress
+			if i%2 == 0 {
+				state = StateOpen
+			}
+			if _, err := SetFindingState(server, "seed-finding", state, "api", ""); err != nil {
+				errs <- fmt.Errorf("SetFindingState: %w", err)
+			}
+			if _, err := AddComment(server, "seed-finding", 0, "api", fmt.Sprintf("comment %d", i)); err != nil {
+				errs <- fmt.Errorf("AddComment: %w", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	var count int
+	server.QueryRow(`SELECT COUNT(*) FROM findings WHERE probe_id != 'seed'`).Scan(&count)
+	if count != probes*findingsPerProbe {
+		t.Errorf("Stored %d findings, want %d", count, probes*findingsPerProbe)
+	}
+	if comments, _ := GetComments(server, "seed-finding"); len(comments) != 50 {
+		t.Errorf("Stored %d comments, want 50", len(comments))
+	}
+	if hits, err := Search(server, SearchQuery{Query: "issue", Limit: 1000}); err != nil || len(hits) != probes*findingsPerProbe {
+		t.Errorf("Search() found %d findings, %v; want every finding indexed", len(hits), err)
+	}
+	if err := IntegrityCheck(server); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentInitDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes.db")
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const processes = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, processes)
+	for i := 0; i < processes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := InitDB(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			Close(db)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("InitDB() failed: %v", err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	var applied int
+	db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+	if applied != len(migrations) {
+		t.Errorf("Recorded %d migrations, want %d", applied, len(migrations))
+	}
+}
+
+func TestConcurrentEnsureProject(t *testing.T) {
+	handles := openHandles(t, 8)
+	repos := []gitinfo.Repo{
+		{Root: "/src/app", RemoteURL: "github.com/acme/app"},
+		{Root: "/src/scratch"},
+	}
+
+	var wg sync.WaitGroup
+	ids := make([][]string, len(repos))
+	errs := make(chan error, len(repos)*len(handles)*5)
+	var mu sync.Mutex
+	for r, repo := range repos {
+		for _, h := range handles {
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func(r int, repo gitinfo.Repo, h *sql.DB) {
+					defer wg.Done()
+					p, err := EnsureProject(h, repo)
+					if err != nil {
+						errs <- err
+						return
+					}
+					mu.Lock()
+					ids[r] = append(ids[r], p.ID)
+					mu.Unlock()
+				}(r, repo, h)
+			}
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("EnsureProject() failed: %v", err)
+	}
+
+	for r, list := range ids {
+		for _, id := range list {
+			if id != list[0] {
+				t.Errorf("%s got projects %s and %s", repos[r].Root, list[0], id)
+				break
+			}
+		}
+	}
+	var count int
+	handles[0].QueryRow(`SELECT COUNT(*) FROM projects`).Scan(&count)
+	if count != len(repos) {
+		t.Errorf("Created %d projects, want %d", count, len(repos))
+	}
+}
+
+func TestPreparedStatements(t *testing.T) {
+	handles := openHandles(t, 2)
+
+	first, err := prepared(handles[0], insertFinding)
+	if err != nil {
+		t.Fatalf("prepared() failed: %v", err)
+	}
+	if again, _ := prepared(handles[0], insertFinding); again != first {
+		t.Error("prepared() should reuse the statement for a query")
+	}
+	if other, _ := prepared(handles[1], insertFinding); other == first {
+		t.Error("prepared() should not share statements between databases")
+	}
+}
+
+func TestCloseDropsPreparedStatements(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "probes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InsertProbe(db, "p", "full", "/tmp/app", ""); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := prepared(db, insertFinding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Close(db); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	stmtMu.Lock()
+	_, cached := stmtCache[db]
+	stmtMu.Unlock()
+	if cached {
+		t.Error("Close() should drop the database's statements from the cache")
+	}
+	if _, err := stmt.Exec(); err == nil {
+		t.Error("Close() should close the cached statements")
+	}
+}
@@ -1,12 +1,18 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
+	"github.com/ndzuma/probeTool/internal/findings"
 	"github.com/ndzuma/probeTool/internal/paths"
 )
 
@@ -22,81 +28,230 @@ func DBPath() string {
 	return paths.GetDBPath()
 }
 
-// InitDB initializes the SQLite database and creates the necessary tables.
+// BusyTimeout is how long a write waits for another connection's write to
+// finish before failing.
+const BusyTimeout = 10 * time.Second
+
+// InitDB opens the SQLite database, creating it if needed, and applies any
+// pending migrations.
 func InitDB(dbPath string) (*sql.DB, error) {
+	db, err := Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := Migrate(db); err != nil {
+		Close(db)
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Open opens the SQLite database without migrating it, for commands that
+// inspect or migrate the schema themselves.
+func Open(dbPath string) (*sql.DB, error) {
 	// Create the directory if it doesn't exist
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	// These are per-connection settings in SQLite, so they go in the DSN
+	// for every connection the pool opens:
+	//   - foreign keys, so deleting a probe cascades to its findings and
+	//     everything attached to them;
+	//   - WAL, so the server keeps reading while a prober writes;
+	//   - a busy timeout, so a writer waits for another process's write
+	//     instead of failing with "database is locked";
+	//   - immediate transactions, which take the write lock at BEGIN. A
+	//     transaction that reads first and upgrades later can fail without
+	//     waiting when another writer got there in between.
+	dsn := fmt.Sprintf("%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=%d&_txlock=immediate",
+		dbPath, BusyTimeout.Milliseconds())
+	return sql.OpenDB(&connector{dsn: dsn}), nil
+}
+
+// connector opens connections with the sqlite3 driver, retrying while the
+// database is locked. The driver sets the journal mode as it connects,
+// before the busy timeout applies, so a connection opened while another
+// process creates or checkpoints the database can fail at once.
+type connector struct {
+	dsn string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	deadline := time.Now().Add(BusyTimeout)
+	for {
+		conn, err := c.Driver().Open(c.dsn)
+		var sqliteErr sqlite3.Error
+		if err == nil || !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrBusy || time.Now().After(deadline) {
+			return conn, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+type column struct {
+	name string
+	def  string
+}
+
+// querier is the part of *sql.DB and *sql.Tx that migrations use.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// ensureColumns adds any of the given columns that are missing from table.
+func ensureColumns(db querier, table string, columns []column) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	InitDB := func() error {
-		createTableSQL := `CREATE TABLE IF NOT EXISTS probes (
-			id TEXT PRIMARY KEY,
-			type TEXT NOT NULL,
-			target TEXT NOT NULL,
-			file_path TEXT,
-			status TEXT DEFAULT 'running',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);`
-
-		_, err = db.Exec(createTableSQL)
-		if err != nil {
-			return fmt.Errorf("failed to create probes table: %w", err)
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	for _, col := range columns {
+		if existing[col.name] {
+			continue
 		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.def)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, col.name, err)
+		}
+	}
+
+	return nil
+}
 
-		findingsTableSQL := `CREATE TABLE IF NOT EXISTS findings (
-			id TEXT PRIMARY KEY,
-			probe_id TEXT NOT NULL,
-			text TEXT NOT NULL,
-			severity TEXT DEFAULT 'info',
-			completed INTEGER DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (probe_id) REFERENCES probes(id) ON DELETE CASCADE
-		);`
-
-		_, err = db.Exec(findingsTableSQL)
-		if err != nil {
-			return fmt.Errorf("failed to create findings table: %w", err)
+// backfillFingerprints computes fingerprints for findings stored before
+// fingerprints were introduced.
+func backfillFingerprints(db querier) error {
+	rows, err := db.Query(`SELECT id, text, file, cwe, owasp FROM findings WHERE fingerprint = '' OR fingerprint IS NULL`)
+	if err != nil {
+		return err
+	}
+
+	updates := make(map[string]string)
+	for rows.Next() {
+		var id, text, file, cwe, owasp string
+		if err := rows.Scan(&id, &text, &file, &cwe, &owasp); err != nil {
+			rows.Close()
+			return err
 		}
+		updates[id] = findings.Fingerprint(text, file, findings.Category(cwe, owasp))
+	}
+	rows.Close()
 
-		return nil
+	stmt, err := db.Prepare(`UPDATE findings SET fingerprint = ? WHERE id = ?`)
+	if err != nil {
+		return err
 	}
+	defer stmt.Close()
 
-	if err := InitDB(); err != nil {
-		return nil, err
+	for id, fingerprint := range updates {
+		if _, err := stmt.Exec(fingerprint, id); err != nil {
+			return err
+		}
 	}
 
-	return db, nil
+	return nil
 }
 
 // InsertProbe inserts a new probe record into the database.
 func InsertProbe(db *sql.DB, id, probeType, target, filePath string) error {
 	query := `INSERT INTO probes (id, type, target, file_path, status) VALUES (?, ?, ?, ?, 'running')`
-	_, err := db.Exec(query, id, probeType, target, filePath)
+	_, err := execPrepared(db, query, id, probeType, target, filePath)
 	return err
 }
 
 // UpdateProbeStatus updates the status of a probe.
 func UpdateProbeStatus(db *sql.DB, id, status string) error {
 	query := `UPDATE probes SET status = ? WHERE id = ?`
-	_, err := db.Exec(query, status, id)
+	_, err := execPrepared(db, query, status, id)
+	return err
+}
+
+// UpdateProbeUsage records the provider, model and resource usage of a probe.
+func UpdateProbeUsage(db *sql.DB, id string, usage Usage) error {
+	query := `UPDATE probes SET provider = ?, model = ?, input_tokens = ?, output_tokens = ?,
+		cost_usd = ?, duration_ms = ?, num_turns = ? WHERE id = ?`
+	_, err := execPrepared(db, query, usage.Provider, usage.Model, usage.InputTokens, usage.OutputTokens,
+		usage.CostUSD, usage.DurationMS, usage.NumTurns, id)
 	return err
 }
 
+// UpdateProbeGit records the repository state a probe ran against.
+func UpdateProbeGit(db *sql.DB, id string, git GitProvenance) error {
+	query := `UPDATE probes SET git_commit = ?, git_branch = ?, git_dirty = ?, git_remote = ?,
+		git_changed_files = ? WHERE id = ?`
+	_, err := execPrepared(db, query, git.GitCommit, git.GitBranch, git.GitDirty, git.GitRemote, git.GitChangedFiles, id)
+	return err
+}
+
+// UpdateProbeVersions records the binary and agent a probe ran with.
+func UpdateProbeVersions(db *sql.DB, id string, v Versions) error {
+	query := `UPDATE probes SET probe_version = ?, agent_version = ?, prompt_hash = ?, skill_hash = ? WHERE id = ?`
+	_, err := execPrepared(db, query, v.ProbeVersion, v.AgentVersion, v.PromptHash, v.SkillHash, id)
+	return err
+}
+
+const probeColumns = `id, type, target, file_path, status, created_at,
+	provider, model, input_tokens, output_tokens, cost_usd, duration_ms, num_turns,
+	COALESCE(project_id, ''), git_commit, git_branch, git_dirty, git_remote, git_changed_files,
+	probe_version, agent_version, prompt_hash, skill_hash`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// probeFields are the scan destinations for probeColumns, so queries that
+// select more than the probe can append their own.
+func probeFields(probe *Probe) []interface{} {
+	return []interface{}{&probe.ID, &probe.Type, &probe.Target, &probe.FilePath, &probe.Status, &probe.CreatedAt,
+		&probe.Provider, &probe.Model, &probe.InputTokens, &probe.OutputTokens, &probe.CostUSD,
+		&probe.DurationMS, &probe.NumTurns, &probe.ProjectID,
+		&probe.GitCommit, &probe.GitBranch, &probe.GitDirty, &probe.GitRemote, &probe.GitChangedFiles,
+		&probe.ProbeVersion, &probe.AgentVersion, &probe.PromptHash, &probe.SkillHash}
+}
+
+func scanProbe(row rowScanner, probe *Probe) error {
+	return row.Scan(probeFields(probe)...)
+}
+
 // GetProbe retrieves a single probe by ID.
 func GetProbe(db *sql.DB, id string) (*Probe, error) {
-	query := `SELECT id, type, target, file_path, status, created_at FROM probes WHERE id = ?`
+	query := `SELECT ` + probeColumns + ` FROM probes WHERE id = ?`
 	row := db.QueryRow(query, id)
 
 	var probe Probe
-	err := row.Scan(&probe.ID, &probe.Type, &probe.Target, &probe.FilePath, &probe.Status, &probe.CreatedAt)
-	if err != nil {
+	if err := scanProbe(row, &probe); err != nil {
 		return nil, err
 	}
 
@@ -105,7 +260,7 @@ func GetProbe(db *sql.DB, id string) (*Probe, error) {
 
 // GetAllProbes retrieves all probes from the database.
 func GetAllProbes(db *sql.DB) ([]Probe, error) {
-	query := `SELECT id, type, target, file_path, status, created_at FROM probes ORDER BY created_at DESC`
+	query := `SELECT ` + probeColumns + ` FROM probes ORDER BY created_at DESC`
 	rows, err := db.Query(query)
 	if err != nil {
 		return nil, err
@@ -115,8 +270,7 @@ func GetAllProbes(db *sql.DB) ([]Probe, error) {
 	var probes []Probe
 	for rows.Next() {
 		var probe Probe
-		err := rows.Scan(&probe.ID, &probe.Type, &probe.Target, &probe.FilePath, &probe.Status, &probe.CreatedAt)
-		if err != nil {
+		if err := scanProbe(rows, &probe); err != nil {
 			return nil, err
 		}
 		probes = append(probes, probe)
@@ -132,60 +286,196 @@ type Probe struct {
 	FilePath  string `json:"file_path"`
 	Status    string `json:"status"`
 	CreatedAt string `json:"created_at"`
+	ProjectID string `json:"project_id"`
+	Usage
+	GitProvenance
+	Versions
+}
+
+// Versions records what produced a probe's report, so reports made with an
+// older prompt or skill can be told apart. The profile is the probe's Type.
+type Versions struct {
+	ProbeVersion string `json:"probe_version"`
+	AgentVersion string `json:"agent_version"`
+	PromptHash   string `json:"prompt_hash"`
+	SkillHash    string `json:"skill_hash"`
+}
+
+// GitProvenance is the state of the target's repository when a probe
+// started. It is empty for targets outside git.
+type GitProvenance struct {
+	GitCommit       string `json:"git_commit"`
+	GitBranch       string `json:"git_branch"`
+	GitDirty        bool   `json:"git_dirty"`
+	GitRemote       string `json:"git_remote"`
+	GitChangedFiles int    `json:"git_changed_files"`
+}
+
+// Usage holds the model and resource consumption reported by the agent.
+type Usage struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	DurationMS   int64   `json:"duration_ms"`
+	NumTurns     int     `json:"num_turns"`
 }
 
 type Finding struct {
-	ID        string `json:"id"`
-	ProbeID   string `json:"probe_id"`
-	Text      string `json:"text"`
-	Severity  string `json:"severity"`
-	Completed bool   `json:"completed"`
-	CreatedAt string `json:"created_at"`
+	ID          string   `json:"id"`
+	ProbeID     string   `json:"probe_id"`
+	Fingerprint string   `json:"fingerprint"`
+	Text        string   `json:"text"`
+	Severity    string   `json:"severity"`
+	State       string   `json:"state"`
+	Assignee    string   `json:"assignee"`
+	Tags        []string `json:"tags"`
+	Completed   bool     `json:"completed"`
+	CreatedAt   string   `json:"created_at"`
+	File        string   `json:"file"`
+	LineStart   int      `json:"line_start"`
+	LineEnd     int      `json:"line_end"`
+	CWE         string   `json:"cwe"`
+	OWASP       string   `json:"owasp"`
+	Description string   `json:"description"`
+	Remediation string   `json:"remediation"`
+	// Aliases are the titles of near-duplicates merged into this finding
+	// when the report was parsed.
+	Aliases []string `json:"aliases"`
+}
+
+const findingColumns = `id, probe_id, fingerprint, text, severity, state, assignee, completed, created_at,
+	file, line_start, line_end, cwe, owasp, description, remediation, aliases`
+
+func scanFinding(row rowScanner, f *Finding) error {
+	var completed int
+	var aliases string
+	err := row.Scan(&f.ID, &f.ProbeID, &f.Fingerprint, &f.Text, &f.Severity, &f.State, &f.Assignee, &completed, &f.CreatedAt,
+		&f.File, &f.LineStart, &f.LineEnd, &f.CWE, &f.OWASP, &f.Description, &f.Remediation, &aliases)
+	if err != nil {
+		return err
+	}
+	f.Completed = completed == 1
+	f.Aliases = []string{}
+	if aliases != "" {
+		if err := json.Unmarshal([]byte(aliases), &f.Aliases); err != nil {
+			return fmt.Errorf("finding %s has invalid aliases: %w", f.ID, err)
+		}
+	}
+	return nil
+}
+
+func encodeAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(aliases)
+	return string(data)
 }
 
 func InsertFinding(db *sql.DB, id, probeID, text, severity string) error {
-	query := `INSERT INTO findings (id, probe_id, text, severity, completed) VALUES (?, ?, ?, ?, 0)`
-	_, err := db.Exec(query, id, probeID, text, severity)
+	return CreateFinding(db, &Finding{ID: id, ProbeID: probeID, Text: text, Severity: severity})
+}
+
+// CreateFinding inserts a finding including its location and classification.
+// The fingerprint is derived from the finding when the caller leaves it empty
+// and new findings start out open.
+func CreateFinding(db *sql.DB, f *Finding) error {
+	prepareFinding(f)
+	_, err := execPrepared(db, insertFinding, findingValues(f)...)
 	return err
 }
 
-func GetFindingsByProbe(db *sql.DB, probeID string) ([]Finding, error) {
-	query := `SELECT id, probe_id, text, severity, completed, created_at FROM findings WHERE probe_id = ? ORDER BY created_at ASC`
-	rows, err := db.Query(query, probeID)
+// CreateFindings inserts the findings of a probe in one transaction, so a
+// failure leaves none of them behind. Each finding is completed as in
+// CreateFinding.
+func CreateFindings(db *sql.DB, list []Finding) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := txPrepared(db, tx, insertFinding)
+	if err != nil {
+		return err
+	}
+
+	for i := range list {
+		prepareFinding(&list[i])
+		if _, err := stmt.Exec(findingValues(&list[i])...); err != nil {
+			return fmt.Errorf("failed to insert finding %s: %w", list[i].ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+const insertFinding = `INSERT INTO findings (id, probe_id, fingerprint, text, severity, state, assignee, completed,
+	file, line_start, line_end, cwe, owasp, description, remediation, aliases)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func prepareFinding(f *Finding) {
+	if f.Fingerprint == "" {
+		f.Fingerprint = findings.Fingerprint(f.Text, f.File, findings.Category(f.CWE, f.OWASP))
+	}
+	if f.State == "" {
+		f.State = StateOpen
+	}
+	f.Completed = isClosedState(f.State)
+}
+
+func findingValues(f *Finding) []interface{} {
+	return []interface{}{f.ID, f.ProbeID, f.Fingerprint, f.Text, f.Severity, f.State, f.Assignee, f.Completed,
+		f.File, f.LineStart, f.LineEnd, f.CWE, f.OWASP, f.Description, f.Remediation, encodeAliases(f.Aliases)}
+}
+
+// GetFindingsByFingerprint returns every recorded occurrence of a finding,
+// oldest first.
+func GetFindingsByFingerprint(db *sql.DB, fingerprint string) ([]Finding, error) {
+	query := `SELECT ` + findingColumns + ` FROM findings WHERE fingerprint = ? ORDER BY created_at ASC`
+	rows, err := db.Query(query, fingerprint)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var findings []Finding
+	var results []Finding
 	for rows.Next() {
 		var f Finding
-		var completed int
-		err := rows.Scan(&f.ID, &f.ProbeID, &f.Text, &f.Severity, &completed, &f.CreatedAt)
-		if err != nil {
+		if err := scanFinding(rows, &f); err != nil {
 			return nil, err
 		}
-		f.Completed = completed == 1
-		findings = append(findings, f)
+		results = append(results, f)
 	}
 
-	return findings, nil
+	if err := attachTags(db, results); err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
-func ToggleFinding(db *sql.DB, id string) (bool, error) {
-	var completed int
-	err := db.QueryRow(`SELECT completed FROM findings WHERE id = ?`, id).Scan(&completed)
+func GetFindingsByProbe(db *sql.DB, probeID string) ([]Finding, error) {
+	query := `SELECT ` + findingColumns + ` FROM findings WHERE probe_id = ? ORDER BY created_at ASC`
+	rows, err := db.Query(query, probeID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	newCompleted := 1
-	if completed == 1 {
-		newCompleted = 0
+	var findings []Finding
+	for rows.Next() {
+		var f Finding
+		if err := scanFinding(rows, &f); err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
 	}
 
-	_, err = db.Exec(`UPDATE findings SET completed = ? WHERE id = ?`, newCompleted, id)
-	return newCompleted == 1, err
+	if err := attachTags(db, findings); err != nil {
+		return nil, err
+	}
+	return findings, nil
 }
 
 func DeleteFinding(db *sql.DB, id string) error {
@@ -194,16 +484,19 @@ func DeleteFinding(db *sql.DB, id string) error {
 }
 
 func GetFinding(db *sql.DB, id string) (*Finding, error) {
-	query := `SELECT id, probe_id, text, severity, completed, created_at FROM findings WHERE id = ?`
+	query := `SELECT ` + findingColumns + ` FROM findings WHERE id = ?`
 	row := db.QueryRow(query, id)
 
 	var f Finding
-	var completed int
-	err := row.Scan(&f.ID, &f.ProbeID, &f.Text, &f.Severity, &completed, &f.CreatedAt)
+	if err := scanFinding(row, &f); err != nil {
+		return nil, err
+	}
+
+	tags, err := GetTags(db, id)
 	if err != nil {
 		return nil, err
 	}
-	f.Completed = completed == 1
+	f.Tags = tags
 
 	return &f, nil
 }
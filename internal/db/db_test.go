@@ -1,12 +1,20 @@
 package db
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/ndzuma/probeTool/internal/findings"
+	"github.com/ndzuma/probeTool/internal/gitinfo"
 )
 
 func TestGetAppDir(t *testing.T) {
@@ -48,7 +56,7 @@ func TestInitDB(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	// Verify the database file was created
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
@@ -71,7 +79,7 @@ func TestInsertProbe(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	testID := "test-insert-" + time.Now().Format("20060102150405")
 	err = InsertProbe(db, testID, "security", "/tmp/test", "/tmp/test.md")
@@ -104,7 +112,7 @@ func TestGetProbe(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	// Test getting non-existent probe
 	_, err = GetProbe(db, "non-existent-id")
@@ -139,7 +147,7 @@ func TestGetAllProbes(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	// Get all probes from empty database
 	probes, err := GetAllProbes(db)
@@ -177,7 +185,7 @@ func TestUpdateProbeStatus(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	testID := "test-update-" + time.Now().Format("20060102150405")
 	err = InsertProbe(db, testID, "security", "/tmp/test", "/tmp/test.md")
@@ -209,7 +217,7 @@ func TestInsertFinding(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	// First insert a probe
 	probeID := "test-finding-probe-" + time.Now().Format("20060102150405")
@@ -243,7 +251,7 @@ func TestGetFindingsByProbe(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	probeID := "test-finding-" + time.Now().Format("20060102150405")
 	err = InsertProbe(db, probeID, "security", "/tmp/test", "/tmp/test.md")
@@ -271,7 +279,7 @@ func TestGetFindingsByProbe(t *testing.T) {
 	}
 }
 
-func TestToggleFinding(t *testing.T) {
+func TestSetFindingState(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
 
@@ -279,36 +287,100 @@ func TestToggleFinding(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
-	probeID := "test-toggle-" + time.Now().Format("20060102150405")
+	probeID := "test-state-" + time.Now().Format("20060102150405")
 	err = InsertProbe(db, probeID, "security", "/tmp/test", "/tmp/test.md")
 	if err != nil {
 		t.Fatalf("InsertProbe() failed: %v", err)
 	}
 
-	findingID := "toggle-finding-" + time.Now().Format("20060102150405")
+	findingID := "state-finding-" + time.Now().Format("20060102150405")
 	err = InsertFinding(db, findingID, probeID, "Test finding", "high")
 	if err != nil {
 		t.Fatalf("InsertFinding() failed: %v", err)
 	}
 
-	// Toggle to completed
-	completed, err := ToggleFinding(db, findingID)
+	f, _ := GetFinding(db, findingID)
+	if f.State != StateOpen || f.Completed {
+		t.Errorf("New finding should be open, got state=%s completed=%v", f.State, f.Completed)
+	}
+
+	change, err := SetFindingState(db, findingID, StateFixed, "alice", "patched in #42")
+	if err != nil {
+		t.Fatalf("SetFindingState() failed: %v", err)
+	}
+	if change == nil || change.FromState != StateOpen || change.ToState != StateFixed {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+
+	// Setting the same state again must not flip it back
+	change, err = SetFindingState(db, findingID, StateFixed, "bob", "")
+	if err != nil {
+		t.Fatalf("SetFindingState() failed on repeat: %v", err)
+	}
+	if change != nil {
+		t.Error("Repeating the current state should not record a transition")
+	}
+
+	f, _ = GetFinding(db, findingID)
+	if f.State != StateFixed || !f.Completed {
+		t.Errorf("Finding should be fixed and completed, got state=%s completed=%v", f.State, f.Completed)
+	}
+
+	if _, err := SetFindingState(db, findingID, StateInProgress, "alice", ""); err != nil {
+		t.Fatalf("SetFindingState() failed: %v", err)
+	}
+	f, _ = GetFinding(db, findingID)
+	if f.Completed {
+		t.Error("In-progress finding should not be completed")
+	}
+
+	history, err := GetFindingHistory(db, findingID)
 	if err != nil {
-		t.Errorf("ToggleFinding() failed: %v", err)
+		t.Fatalf("GetFindingHistory() failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", len(history))
 	}
-	if !completed {
-		t.Error("ToggleFinding() should return true after first toggle")
+	if history[0].Actor != "alice" || history[0].Reason != "patched in #42" || history[0].CreatedAt == "" {
+		t.Errorf("Unexpected first history entry: %+v", history[0])
 	}
+	if history[1].FromState != StateFixed || history[1].ToState != StateInProgress {
+		t.Errorf("Unexpected second history entry: %+v", history[1])
+	}
+
+	if _, err := SetFindingState(db, findingID, "done", "alice", ""); err == nil {
+		t.Error("SetFindingState() should reject unknown states")
+	}
+	if _, err := SetFindingState(db, "missing", StateFixed, "alice", ""); err == nil {
+		t.Error("SetFindingState() should fail for unknown findings")
+	}
+}
 
-	// Toggle back to not completed
-	completed, err = ToggleFinding(db, findingID)
+func TestResolveFindingID(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
 	if err != nil {
-		t.Errorf("ToggleFinding() failed on second toggle: %v", err)
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p", "full", "/tmp/test", "")
+	InsertFinding(db, "abc12345-0000", "p", "First finding text", "high")
+	InsertFinding(db, "abc99999-0000", "p", "Second finding text", "low")
+
+	if id, err := ResolveFindingID(db, "abc1"); err != nil || id != "abc12345-0000" {
+		t.Errorf("ResolveFindingID(abc1) = %q, %v", id, err)
+	}
+	if _, err := ResolveFindingID(db, "abc"); err == nil {
+		t.Error("ResolveFindingID() should reject ambiguous prefixes")
+	}
+	if _, err := ResolveFindingID(db, "zzz"); err == nil {
+		t.Error("ResolveFindingID() should fail when nothing matches")
 	}
-	if completed {
-		t.Error("ToggleFinding() should return false after second toggle")
+	if _, err := ResolveFindingID(db, "abc_"); err == nil {
+		t.Error("ResolveFindingID() should treat LIKE wildcards literally")
 	}
 }
 
@@ -320,7 +392,7 @@ func TestDeleteFinding(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	probeID := "test-delete-" + time.Now().Format("20060102150405")
 	err = InsertProbe(db, probeID, "security", "/tmp/test", "/tmp/test.md")
@@ -358,7 +430,7 @@ func TestConcurrentAccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB() failed: %v", err)
 	}
-	defer db.Close()
+	defer Close(db)
 
 	// Insert a probe
 	probeID := "concurrent-test-" + time.Now().Format("20060102150405")
@@ -394,3 +466,1312 @@ func TestConcurrentAccess(t *testing.T) {
 		t.Errorf("Expected 10 findings, got %d", len(findings))
 	}
 }
+
+func TestUpdateProbeUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	testID := "test-usage-" + time.Now().Format("20060102150405")
+	if err := InsertProbe(db, testID, "full", "/tmp/test", "/tmp/test.md"); err != nil {
+		t.Fatalf("InsertProbe() failed: %v", err)
+	}
+
+	usage := Usage{
+		Provider:     "openrouter",
+		Model:        "anthropic/claude-3.5-haiku",
+		InputTokens:  1200,
+		OutputTokens: 340,
+		CostUSD:      0.0123,
+		DurationMS:   45000,
+		NumTurns:     7,
+	}
+	if err := UpdateProbeUsage(db, testID, usage); err != nil {
+		t.Fatalf("UpdateProbeUsage() failed: %v", err)
+	}
+
+	probe, err := GetProbe(db, testID)
+	if err != nil {
+		t.Fatalf("GetProbe() failed: %v", err)
+	}
+	if probe.Usage != usage {
+		t.Errorf("Usage mismatch: got %+v, want %+v", probe.Usage, usage)
+	}
+}
+
+func TestInitDBAddsMissingColumns(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "legacy.db")
+
+	// Simulate a database created by an older release
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open() failed: %v", err)
+	}
+	_, err = legacy.Exec(`CREATE TABLE probes (
+		id TEXT PRIMARY KEY,
+		type TEXT NOT NULL,
+		target TEXT NOT NULL,
+		file_path TEXT,
+		status TEXT DEFAULT 'running',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+	if _, err := legacy.Exec(`INSERT INTO probes (id, type, target, file_path) VALUES ('old', 'full', '/tmp', '/tmp/old.md')`); err != nil {
+		t.Fatalf("Failed to insert legacy row: %v", err)
+	}
+	legacy.Close()
+
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB() failed on legacy database: %v", err)
+	}
+	defer Close(db)
+
+	probe, err := GetProbe(db, "old")
+	if err != nil {
+		t.Fatalf("GetProbe() failed on legacy row: %v", err)
+	}
+	if probe.CostUSD != 0 || probe.Provider != "" {
+		t.Errorf("Legacy row should have zero usage, got %+v", probe.Usage)
+	}
+}
+
+func TestGetUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	probes := []struct {
+		id    string
+		usage Usage
+	}{
+		{"u1", Usage{Provider: "openrouter", Model: "a", InputTokens: 100, OutputTokens: 10, CostUSD: 0.5}},
+		{"u2", Usage{Provider: "openrouter", Model: "b", InputTokens: 200, OutputTokens: 20, CostUSD: 0.25}},
+		{"u3", Usage{Provider: "other", Model: "a", InputTokens: 50, OutputTokens: 5, CostUSD: 0.1}},
+	}
+	for _, p := range probes {
+		if err := InsertProbe(db, p.id, "full", "/tmp/test", ""); err != nil {
+			t.Fatalf("InsertProbe() failed: %v", err)
+		}
+		if err := UpdateProbeUsage(db, p.id, p.usage); err != nil {
+			t.Fatalf("UpdateProbeUsage() failed: %v", err)
+		}
+	}
+	// An old probe outside the window
+	if err := InsertProbe(db, "old", "full", "/tmp/test", ""); err != nil {
+		t.Fatalf("InsertProbe() failed: %v", err)
+	}
+	db.Exec(`UPDATE probes SET created_at = '2000-01-01 00:00:00', cost_usd = 9 WHERE id = 'old'`)
+
+	since, _ := ParseSince("30d")
+	summaries, err := GetUsage(db, "provider", since)
+	if err != nil {
+		t.Fatalf("GetUsage() failed: %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 provider groups, got %d", len(summaries))
+	}
+	if summaries[0].Key != "openrouter" || summaries[0].Probes != 2 || summaries[0].InputTokens != 300 {
+		t.Errorf("Unexpected openrouter summary: %+v", summaries[0])
+	}
+	if summaries[0].CostUSD != 0.75 {
+		t.Errorf("Expected cost 0.75, got %v", summaries[0].CostUSD)
+	}
+
+	summaries, err = GetUsage(db, "model", time.Time{})
+	if err != nil {
+		t.Fatalf("GetUsage() failed: %v", err)
+	}
+	if len(summaries) != 3 {
+		t.Errorf("Expected 3 model groups including the old probe, got %d", len(summaries))
+	}
+
+	if _, err := GetUsage(db, "status", time.Time{}); err == nil {
+		t.Error("GetUsage() should reject unknown groups")
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"abc", 0, true},
+		{"-3d", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSince(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSince(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		diff := now.Sub(got) - tt.want
+		if diff < -time.Hour || diff > time.Hour {
+			t.Errorf("ParseSince(%q) = %v, want about %v ago", tt.in, got, tt.want)
+		}
+	}
+
+	if got, err := ParseSince(""); err != nil || !got.IsZero() {
+		t.Errorf("ParseSince(\"\") = %v, %v; want zero time", got, err)
+	}
+	if got, err := ParseSince("2025-01-02"); err != nil || !got.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("ParseSince(date) = %v, %v; want local midnight", got, err)
+	}
+}
+
+func TestCreateFinding(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	probeID := "test-structured-" + time.Now().Format("20060102150405")
+	if err := InsertProbe(db, probeID, "full", "/tmp/test", "/tmp/test.md"); err != nil {
+		t.Fatalf("InsertProbe() failed: %v", err)
+	}
+
+	want := Finding{
+		ID:          "structured-1",
+		ProbeID:     probeID,
+		Text:        "SQL injection in login handler",
+		Severity:    "critical",
+		File:        "src/auth/login.js",
+		LineStart:   42,
+		LineEnd:     48,
+		CWE:         "CWE-89",
+		OWASP:       "A03:2021-Injection",
+		Description: "User input is concatenated into the SQL query.",
+		Remediation: "Use parameterised queries.",
+		Aliases:     []string{"SQL injection vulnerability in the login handler"},
+	}
+	if err := CreateFinding(db, &want); err != nil {
+		t.Fatalf("CreateFinding() failed: %v", err)
+	}
+
+	got, err := GetFinding(db, want.ID)
+	if err != nil {
+		t.Fatalf("GetFinding() failed: %v", err)
+	}
+	want.CreatedAt = got.CreatedAt
+	want.Tags = []string{}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("GetFinding() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestFindingFingerprints(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	for _, probeID := range []string{"run-1", "run-2"} {
+		if err := InsertProbe(db, probeID, "full", "/tmp/test", ""); err != nil {
+			t.Fatalf("InsertProbe() failed: %v", err)
+		}
+		if err := InsertFinding(db, probeID+"-f", probeID, "Hardcoded API key in config", "high"); err != nil {
+			t.Fatalf("InsertFinding() failed: %v", err)
+		}
+	}
+
+	occurrences, err := GetFindingsByProbe(db, "run-1")
+	if err != nil || len(occurrences) != 1 {
+		t.Fatalf("GetFindingsByProbe() = %v, %v", occurrences, err)
+	}
+	fingerprint := occurrences[0].Fingerprint
+	if fingerprint == "" {
+		t.Fatal("InsertFinding() should store a fingerprint")
+	}
+
+	occurrences, err = GetFindingsByFingerprint(db, fingerprint)
+	if err != nil {
+		t.Fatalf("GetFindingsByFingerprint() failed: %v", err)
+	}
+	if len(occurrences) != 2 {
+		t.Errorf("Expected the finding in both probes, got %d", len(occurrences))
+	}
+}
+
+func TestFindingComments(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p", "full", "/tmp/test", "")
+	InsertFinding(db, "f1", "p", "First finding text", "high")
+	InsertFinding(db, "f2", "p", "Second finding text", "low")
+
+	first, err := AddComment(db, "f1", 0, "alice", "Looks real, reproducing")
+	if err != nil {
+		t.Fatalf("AddComment() failed: %v", err)
+	}
+	if first.ID == 0 || first.Author != "alice" || first.CreatedAt == "" {
+		t.Errorf("Unexpected comment: %+v", first)
+	}
+
+	reply, err := AddComment(db, "f1", first.ID, "bob", "Confirmed")
+	if err != nil {
+		t.Fatalf("AddComment() reply failed: %v", err)
+	}
+	if reply.ParentID != first.ID {
+		t.Errorf("Reply ParentID = %d, want %d", reply.ParentID, first.ID)
+	}
+
+	if _, err := AddComment(db, "f1", 0, "alice", "   "); err == nil {
+		t.Error("AddComment() should reject empty bodies")
+	}
+	if _, err := AddComment(db, "f2", first.ID, "alice", "wrong thread"); err == nil {
+		t.Error("AddComment() should reject replies to comments on another finding")
+	}
+	if _, err := AddComment(db, "missing", 0, "alice", "hello"); err == nil {
+		t.Error("AddComment() should fail for unknown findings")
+	}
+
+	comments, err := GetComments(db, "f1")
+	if err != nil {
+		t.Fatalf("GetComments() failed: %v", err)
+	}
+	if len(comments) != 2 || comments[0].ID != first.ID || comments[1].ID != reply.ID {
+		t.Errorf("Unexpected comments: %+v", comments)
+	}
+
+	// Deleting a comment takes its replies, and theirs, with it
+	if _, err := AddComment(db, "f1", reply.ID, "alice", "Fixed in the next release"); err != nil {
+		t.Fatalf("AddComment() nested reply failed: %v", err)
+	}
+	other, _ := AddComment(db, "f1", 0, "carol", "Separate thread")
+	if err := DeleteComment(db, "f1", first.ID); err != nil {
+		t.Fatalf("DeleteComment() failed: %v", err)
+	}
+	comments, _ = GetComments(db, "f1")
+	if len(comments) != 1 || comments[0].ID != other.ID {
+		t.Errorf("Expected only the other thread after delete, got %+v", comments)
+	}
+	if err := DeleteComment(db, "f1", first.ID); err != sql.ErrNoRows {
+		t.Errorf("DeleteComment() on missing comment = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestFindingAssigneeAndTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p", "full", "/tmp/test", "")
+	InsertFinding(db, "f1", "p", "First finding text", "high")
+	InsertFinding(db, "f2", "p", "Second finding text", "low")
+
+	if err := SetAssignee(db, "f1", "alice"); err != nil {
+		t.Fatalf("SetAssignee() failed: %v", err)
+	}
+	if err := SetAssignee(db, "missing", "alice"); err != sql.ErrNoRows {
+		t.Errorf("SetAssignee() on missing finding = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := AddTags(db, "f1", "Auth", "sqli", " auth "); err != nil {
+		t.Fatalf("AddTags() failed: %v", err)
+	}
+	if err := AddTags(db, "f2", "sqli"); err != nil {
+		t.Fatalf("AddTags() failed: %v", err)
+	}
+
+	f, _ := GetFinding(db, "f1")
+	if f.Assignee != "alice" || !reflect.DeepEqual(f.Tags, []string{"auth", "sqli"}) {
+		t.Errorf("Unexpected finding: assignee=%q tags=%v", f.Assignee, f.Tags)
+	}
+
+	tests := []struct {
+		name   string
+		filter FindingFilter
+		want   []string
+	}{
+		{"tag", FindingFilter{Tag: "SQLI"}, []string{"f1", "f2"}},
+		{"assignee", FindingFilter{Assignee: "alice"}, []string{"f1"}},
+		{"tag and severity", FindingFilter{Tag: "sqli", Severity: "low"}, []string{"f2"}},
+		{"no match", FindingFilter{Tag: "xss"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := ListFindings(db, tt.filter)
+			if err != nil {
+				t.Fatalf("ListFindings() failed: %v", err)
+			}
+			var ids []string
+			for _, f := range list {
+				ids = append(ids, f.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("ListFindings(%+v) = %v, want %v", tt.filter, ids, tt.want)
+			}
+		})
+	}
+
+	if err := RemoveTags(db, "f1", "AUTH"); err != nil {
+		t.Fatalf("RemoveTags() failed: %v", err)
+	}
+	if err := SetAssignee(db, "f1", ""); err != nil {
+		t.Fatalf("SetAssignee() unassign failed: %v", err)
+	}
+	f, _ = GetFinding(db, "f1")
+	if f.Assignee != "" || !reflect.DeepEqual(f.Tags, []string{"sqli"}) {
+		t.Errorf("Unexpected finding after untag/unassign: assignee=%q tags=%v", f.Assignee, f.Tags)
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p", "full", "/tmp/test", "")
+	f := Finding{ID: "f1", ProbeID: "p", Text: "SQL injection in login", Severity: "critical", File: "src/login.js", LineStart: 42, LineEnd: 44}
+	if err := CreateFinding(db, &f); err != nil {
+		t.Fatalf("CreateFinding() failed: %v", err)
+	}
+
+	if _, err := GetSnippet(db, "f1"); err != sql.ErrNoRows {
+		t.Errorf("GetSnippet() before capture = %v, want sql.ErrNoRows", err)
+	}
+
+	want := &Snippet{FindingID: "f1", File: "src/login.js", StartLine: 37, EndLine: 49, Content: "const q = ...", Commit: "abc123"}
+	if err := SaveSnippet(db, want); err != nil {
+		t.Fatalf("SaveSnippet() failed: %v", err)
+	}
+
+	got, err := GetSnippet(db, "f1")
+	if err != nil {
+		t.Fatalf("GetSnippet() failed: %v", err)
+	}
+	if got.Content != want.Content || got.Commit != "abc123" || got.StartLine != 37 || got.EndLine != 49 {
+		t.Errorf("Unexpected snippet: %+v", got)
+	}
+	if got.LineStart != 42 || got.LineEnd != 44 || got.CapturedAt == "" {
+		t.Errorf("Snippet should carry the finding's range and capture time: %+v", got)
+	}
+}
+
+func TestVerifiedState(t *testing.T) {
+	tests := []struct {
+		current string
+		verdict string
+		want    string
+	}{
+		{StateOpen, findings.VerdictFixed, StateFixed},
+		{StateOpen, findings.VerdictStillPresent, StateOpen},
+		{StateOpen, findings.VerdictInconclusive, StateOpen},
+		{StateInProgress, findings.VerdictFixed, StateInProgress},
+		{StateInProgress, findings.VerdictStillPresent, StateInProgress},
+		{StateInProgress, findings.VerdictInconclusive, StateInProgress},
+		{StateFixed, findings.VerdictFixed, StateFixed},
+		{StateFixed, findings.VerdictStillPresent, StateOpen},
+		{StateFixed, findings.VerdictInconclusive, StateFixed},
+		{StateAcceptedRisk, findings.VerdictFixed, StateAcceptedRisk},
+		{StateAcceptedRisk, findings.VerdictStillPresent, StateAcceptedRisk},
+		{StateAcceptedRisk, findings.VerdictInconclusive, StateAcceptedRisk},
+		{StateFalsePositive, findings.VerdictFixed, StateFalsePositive},
+		{StateFalsePositive, findings.VerdictStillPresent, StateFalsePositive},
+		{StateFalsePositive, findings.VerdictInconclusive, StateFalsePositive},
+	}
+
+	for _, tt := range tests {
+		if got := verifiedState(tt.current, tt.verdict); got != tt.want {
+			t.Errorf("verifiedState(%q, %q) = %q, want %q", tt.current, tt.verdict, got, tt.want)
+		}
+	}
+}
+
+func TestRecordVerification(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p", "full", "/tmp/test", "")
+	InsertFinding(db, "f1", "p", "SQL injection in login", "critical")
+	InsertFinding(db, "f2", "p", "Debug mode enabled", "high")
+
+	steps := []struct {
+		finding   string
+		verdict   string
+		wantState string
+	}{
+		{"f1", "still_present", StateOpen},
+		{"f1", "fixed", StateFixed},
+		{"f1", "still_present", StateOpen},
+		{"f1", "inconclusive", StateOpen},
+	}
+
+	for i, step := range steps {
+		v := &Verification{FindingID: step.finding, Verdict: step.verdict, Reasoning: "checked", Model: "m"}
+		change, err := RecordVerification(db, v, "verify")
+		if err != nil {
+			t.Fatalf("step %d: RecordVerification() failed: %v", i, err)
+		}
+		if v.ID == 0 || change.VerificationID != v.ID {
+			t.Errorf("step %d: history entry should link to verification %d, got %+v", i, v.ID, change)
+		}
+		if change.ToState != step.wantState {
+			t.Errorf("step %d: state = %s, want %s", i, change.ToState, step.wantState)
+		}
+		f, _ := GetFinding(db, step.finding)
+		if f.State != step.wantState {
+			t.Errorf("step %d: stored state = %s, want %s", i, f.State, step.wantState)
+		}
+	}
+
+	history, _ := GetFindingHistory(db, "f1")
+	if len(history) != len(steps) {
+		t.Errorf("Expected %d history entries, got %d", len(steps), len(history))
+	}
+	if !strings.Contains(history[1].Reason, "fixed") {
+		t.Errorf("History reason should mention the verdict, got %q", history[1].Reason)
+	}
+
+	// Accepted risks stay accepted even when the issue is still there
+	SetFindingState(db, "f2", StateAcceptedRisk, "alice", "")
+	if _, err := RecordVerification(db, &Verification{FindingID: "f2", Verdict: "still_present"}, "verify"); err != nil {
+		t.Fatalf("RecordVerification() failed: %v", err)
+	}
+	if f, _ := GetFinding(db, "f2"); f.State != StateAcceptedRisk {
+		t.Errorf("Accepted risk should not be reopened, got %s", f.State)
+	}
+
+	list, err := GetVerifications(db, "f1")
+	if err != nil || len(list) != len(steps) || list[0].Verdict != "inconclusive" {
+		t.Errorf("GetVerifications() = %+v, %v", list, err)
+	}
+
+	if _, err := RecordVerification(db, &Verification{FindingID: "missing", Verdict: "fixed"}, "verify"); err == nil {
+		t.Error("RecordVerification() should fail for unknown findings")
+	}
+}
+
+func TestPatches(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p", "full", "/tmp/test", "")
+	InsertFinding(db, "f1", "p", "SQL injection in login", "critical")
+
+	if _, err := GetPatch(db, "f1"); err != sql.ErrNoRows {
+		t.Errorf("GetPatch() without a patch = %v, want sql.ErrNoRows", err)
+	}
+	if err := MarkPatchApplied(db, "f1", "probe/fix"); err != sql.ErrNoRows {
+		t.Errorf("MarkPatchApplied() without a patch = %v, want sql.ErrNoRows", err)
+	}
+
+	SavePatch(db, &Patch{FindingID: "f1", Patch: "old", CheckOutput: "error: corrupt patch"})
+	if err := SavePatch(db, &Patch{FindingID: "f1", Patch: "--- a/x\n+++ b/x\n", Summary: "Bind parameters", Valid: true, BaseCommit: "abc"}); err != nil {
+		t.Fatalf("SavePatch() failed: %v", err)
+	}
+
+	p, err := GetPatch(db, "f1")
+	if err != nil {
+		t.Fatalf("GetPatch() failed: %v", err)
+	}
+	if !p.Valid || p.Patch != "--- a/x\n+++ b/x\n" || p.CheckOutput != "" || p.BaseCommit != "abc" || p.AppliedAt != "" {
+		t.Errorf("Unexpected patch %+v", p)
+	}
+
+	if err := MarkPatchApplied(db, "f1", "probe/fix-f1"); err != nil {
+		t.Fatalf("MarkPatchApplied() failed: %v", err)
+	}
+	p, _ = GetPatch(db, "f1")
+	if p.Branch != "probe/fix-f1" || p.AppliedAt == "" {
+		t.Errorf("Patch should record the branch it was applied on, got %+v", p)
+	}
+}
+
+func TestDeleteProbesCascades(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	report := filepath.Join(tmpDir, "old.md")
+	transcript := filepath.Join(tmpDir, "old.transcript.jsonl")
+	unrelated := filepath.Join(tmpDir, "older.md")
+	for _, f := range []string{report, transcript, unrelated} {
+		os.WriteFile(f, []byte("x"), 0644)
+	}
+
+	InsertProbe(db, "old", "full", "/tmp/app", report)
+	InsertProbe(db, "new", "full", "/tmp/app", "")
+	InsertFinding(db, "f1", "old", "SQL injection in login", "critical")
+	InsertFinding(db, "f2", "new", "Debug mode enabled", "low")
+	SetFindingState(db, "f1", StateInProgress, "alice", "")
+	AddComment(db, "f1", 0, "alice", "on it")
+	AddTags(db, "f1", "auth")
+	SaveSnippet(db, &Snippet{FindingID: "f1", File: "a.js", StartLine: 1, EndLine: 2, Content: "x"})
+	SavePatch(db, &Patch{FindingID: "f1", Patch: "diff"})
+	RecordVerification(db, &Verification{FindingID: "f1", Verdict: "inconclusive"}, "verify")
+
+	plan, err := DeleteProbes(db, []string{"old"}, true)
+	if err != nil {
+		t.Fatalf("DeleteProbes() dry run failed: %v", err)
+	}
+	if len(plan.Probes) != 1 || plan.Findings != 1 || len(plan.Files) != 2 {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+	if _, err := GetProbe(db, "old"); err != nil {
+		t.Error("A dry run should not delete the probe")
+	}
+	if _, err := os.Stat(report); err != nil {
+		t.Error("A dry run should not delete files")
+	}
+
+	if _, err := DeleteProbes(db, []string{"old"}, false); err != nil {
+		t.Fatalf("DeleteProbes() failed: %v", err)
+	}
+
+	for _, table := range []string{"findings", "finding_history", "finding_comments", "finding_tags", "finding_snippets", "finding_patches", "finding_verifications"} {
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM ` + table + ` WHERE ` + map[bool]string{true: "id", false: "finding_id"}[table == "findings"] + ` = 'f1'`).Scan(&n)
+		if n != 0 {
+			t.Errorf("%s still has %d rows for the deleted finding", table, n)
+		}
+	}
+	if _, err := GetFinding(db, "f2"); err != nil {
+		t.Error("Findings of other probes should be kept")
+	}
+	for _, f := range []string{report, transcript} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", f)
+		}
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Error("Files of other probes should be kept")
+	}
+
+	if _, err := DeleteProbes(db, []string{"missing"}, false); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteProbes() of an unknown probe = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestForeignKeysEnforced(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	if err := InsertFinding(db, "orphan", "no-such-probe", "Debug mode enabled", "low"); err == nil {
+		t.Error("Inserting a finding for a missing probe should fail")
+	}
+}
+
+func TestSelectProbes(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "a-old", "full", "/tmp/a", "")
+	InsertProbe(db, "a-new", "full", "/tmp/a", "")
+	InsertProbe(db, "b-old", "full", "/tmp/b", "")
+	db.Exec(`UPDATE probes SET created_at = '2020-01-01 00:00:00' WHERE id LIKE '%-old'`)
+
+	ids := func(probes []Probe) []string {
+		var out []string
+		for _, p := range probes {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+
+	if got, _ := SelectProbes(db, ProbeSelector{}); len(got) != 0 {
+		t.Errorf("An empty selector should match nothing, got %v", ids(got))
+	}
+	if got, _ := SelectProbes(db, ProbeSelector{Target: "/tmp/a"}); len(got) != 2 {
+		t.Errorf("Target /tmp/a matched %v", ids(got))
+	}
+	before := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got, _ := SelectProbes(db, ProbeSelector{Before: before}); len(got) != 2 {
+		t.Errorf("Before 2021 matched %v", ids(got))
+	}
+	if got, _ := SelectProbes(db, ProbeSelector{Target: "/tmp/a", Before: before}); !reflect.DeepEqual(ids(got), []string{"a-old"}) {
+		t.Errorf("Target and before matched %v", ids(got))
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p-old", "full", "/tmp/api", "")
+	InsertProbe(db, "p-new", "full", "/tmp/web", "")
+	db.Exec(`UPDATE probes SET created_at = '2020-01-01 00:00:00' WHERE id = 'p-old'`)
+	CreateFinding(db, &Finding{ID: "ssrf", ProbeID: "p-old", Text: "Server-side request forgery in webhook", Severity: "high",
+		Description: "The webhook URL is fetched without an allow-list.", File: "src/hooks.js"})
+	CreateFinding(db, &Finding{ID: "xss", ProbeID: "p-new", Text: "Reflected XSS in search", Severity: "medium",
+		Description: "Query is echoed unescaped."})
+	if err := SaveReport(db, "p-new", "# Security Assessment\n\nThe image proxy allows SSRF to internal hosts."); err != nil {
+		t.Fatalf("SaveReport() failed: %v", err)
+	}
+
+	ids := func(hits []SearchHit) []string {
+		out := []string{}
+		for _, h := range hits {
+			out = append(out, h.Kind+":"+h.ID)
+		}
+		sort.Strings(out)
+		return out
+	}
+	search := func(q SearchQuery) []SearchHit {
+		t.Helper()
+		hits, err := Search(db, q)
+		if err != nil {
+			t.Fatalf("Search(%+v) failed: %v", q, err)
+		}
+		return hits
+	}
+
+	if got := ids(search(SearchQuery{Query: "webhook"})); !reflect.DeepEqual(got, []string{"finding:ssrf"}) {
+		t.Errorf("Search(webhook) = %v", got)
+	}
+	hits := search(SearchQuery{Query: "ssrf"})
+	if got := ids(hits); !reflect.DeepEqual(got, []string{"report:p-new"}) {
+		t.Errorf("Search(ssrf) = %v", got)
+	}
+	if len(hits) == 1 && !strings.Contains(hits[0].Snippet, "<mark>SSRF</mark>") {
+		t.Errorf("Snippet should highlight the match, got %q", hits[0].Snippet)
+	}
+	if got := ids(search(SearchQuery{Query: "request forg*"})); !reflect.DeepEqual(got, []string{"finding:ssrf"}) {
+		t.Errorf("Prefix search = %v", got)
+	}
+	if got := ids(search(SearchQuery{Query: "server-side"})); !reflect.DeepEqual(got, []string{"finding:ssrf"}) {
+		t.Errorf("Punctuation should not break the query, got %v", got)
+	}
+
+	// Filters
+	if got := ids(search(SearchQuery{Query: "the", Severity: []string{"high"}})); !reflect.DeepEqual(got, []string{"finding:ssrf"}) {
+		t.Errorf("Severity filter = %v", got)
+	}
+	if got := ids(search(SearchQuery{Query: "the", Target: "/tmp/web"})); !reflect.DeepEqual(got, []string{"report:p-new"}) {
+		t.Errorf("Target filter = %v", got)
+	}
+	if got := ids(search(SearchQuery{Query: "the", Since: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)})); !reflect.DeepEqual(got, []string{"report:p-new"}) {
+		t.Errorf("Since filter = %v", got)
+	}
+	if got := ids(search(SearchQuery{Query: "the", Until: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)})); !reflect.DeepEqual(got, []string{"finding:ssrf"}) {
+		t.Errorf("Until filter = %v", got)
+	}
+
+	// The index follows updates and deletes
+	db.Exec(`UPDATE findings SET text = 'Open redirect in login' WHERE id = 'xss'`)
+	if got := ids(search(SearchQuery{Query: "redirect"})); !reflect.DeepEqual(got, []string{"finding:xss"}) {
+		t.Errorf("Updated finding should be found by its new text, got %v", got)
+	}
+	if got := ids(search(SearchQuery{Query: "reflected"})); len(got) != 0 {
+		t.Errorf("Updated finding should not be found by its old text, got %v", got)
+	}
+	if _, err := DeleteProbes(db, []string{"p-new"}, false); err != nil {
+		t.Fatalf("DeleteProbes() failed: %v", err)
+	}
+	for _, q := range []string{"redirect", "proxy"} {
+		if got := ids(search(SearchQuery{Query: q})); len(got) != 0 {
+			t.Errorf("Deleted probes should leave the index, %s found %v", q, got)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := map[string]string{
+		"ssrf":                `"ssrf"`,
+		"path-traversal  api": `"path-traversal" "api"`,
+		`say "hi"`:            `"say" """hi"""`,
+		"tok*":                `"tok"*`,
+		"  *  ":               "",
+	}
+	for in, want := range tests {
+		if got := MatchExpression(in); got != want {
+			t.Errorf("MatchExpression(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestListProbes(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	// Five probes a day apart; p0 is the oldest
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("p%d", i)
+		target := "/tmp/a"
+		if i%2 == 1 {
+			target = "/tmp/b"
+		}
+		InsertProbe(db, id, "full", target, "")
+		db.Exec(`UPDATE probes SET created_at = ?, cost_usd = ? WHERE id = ?`,
+			FormatTimestamp(time.Date(2025, 1, 1+i, 0, 0, 0, 0, time.UTC)), float64(5-i), id)
+	}
+	UpdateProbeStatus(db, "p4", "failed")
+	InsertFinding(db, "f1", "p2", "SQL injection in login", "critical")
+	InsertFinding(db, "f2", "p2", "Debug mode enabled", "low")
+	InsertFinding(db, "f3", "p2", "Missing header", "info")
+	InsertFinding(db, "f4", "p3", "Weak hash", "high")
+
+	ids := func(page *ProbePage) []string {
+		out := []string{}
+		for _, p := range page.Probes {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+	list := func(f ProbeFilter) *ProbePage {
+		t.Helper()
+		page, err := ListProbes(db, f)
+		if err != nil {
+			t.Fatalf("ListProbes(%+v) failed: %v", f, err)
+		}
+		return page
+	}
+
+	page := list(ProbeFilter{})
+	if got := ids(page); !reflect.DeepEqual(got, []string{"p4", "p3", "p2", "p1", "p0"}) {
+		t.Errorf("Default order = %v, want newest first", got)
+	}
+	if page.NextCursor != "" {
+		t.Error("A single page should have no next cursor")
+	}
+	if want := (SeverityCounts{Critical: 1, Low: 1, Info: 1, Total: 3}); page.Probes[2].FindingCounts != want {
+		t.Errorf("Counts of p2 = %+v, want %+v", page.Probes[2].FindingCounts, want)
+	}
+
+	// Filters
+	if got := ids(list(ProbeFilter{Target: "/tmp/b"})); !reflect.DeepEqual(got, []string{"p3", "p1"}) {
+		t.Errorf("Target filter = %v", got)
+	}
+	if got := ids(list(ProbeFilter{Status: "failed"})); !reflect.DeepEqual(got, []string{"p4"}) {
+		t.Errorf("Status filter = %v", got)
+	}
+	if got := ids(list(ProbeFilter{Type: "quick"})); len(got) != 0 {
+		t.Errorf("Type filter = %v", got)
+	}
+	since := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)
+	if got := ids(list(ProbeFilter{Since: since, Until: until})); !reflect.DeepEqual(got, []string{"p2", "p1"}) {
+		t.Errorf("Date range = %v", got)
+	}
+
+	// Sorting
+	if got := ids(list(ProbeFilter{Sort: "cost", Asc: true})); !reflect.DeepEqual(got, []string{"p4", "p3", "p2", "p1", "p0"}) {
+		t.Errorf("Sort by cost = %v", got)
+	}
+	if got := ids(list(ProbeFilter{Sort: "findings"}))[:2]; !reflect.DeepEqual(got, []string{"p2", "p3"}) {
+		t.Errorf("Sort by findings = %v", got)
+	}
+	if _, err := ListProbes(db, ProbeFilter{Sort: "name"}); err == nil {
+		t.Error("An unknown sort key should be rejected")
+	}
+
+	// Cursor pagination walks every probe once, for each sort key
+	for key := range ProbeSortKeys {
+		for _, asc := range []bool{false, true} {
+			var all []string
+			f := ProbeFilter{Sort: key, Asc: asc, Limit: 2}
+			for pages := 0; ; pages++ {
+				if pages > 5 {
+					t.Fatalf("Pagination by %s did not terminate", key)
+				}
+				page := list(f)
+				all = append(all, ids(page)...)
+				if page.NextCursor == "" {
+					break
+				}
+				f.Cursor = page.NextCursor
+			}
+			sort.Strings(all)
+			if !reflect.DeepEqual(all, []string{"p0", "p1", "p2", "p3", "p4"}) {
+				t.Errorf("Paging by %s (asc=%v) returned %v", key, asc, all)
+			}
+		}
+	}
+
+	if _, err := ListProbes(db, ProbeFilter{Cursor: "not-a-cursor"}); err == nil {
+		t.Error("An invalid cursor should be rejected")
+	}
+}
+
+func TestReports(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	path := filepath.Join(tmpDir, "p.md")
+	os.WriteFile(path, []byte("# From file"), 0644)
+	InsertProbe(db, "p", "full", "/tmp/app", path)
+
+	p, _ := GetProbe(db, "p")
+	if content, err := ReportContent(db, p); err != nil || content != "# From file" {
+		t.Errorf("ReportContent() before storing = %q, %v; want the file", content, err)
+	}
+
+	if err := SaveReport(db, "p", "# Stored"); err != nil {
+		t.Fatalf("SaveReport() failed: %v", err)
+	}
+	r, err := GetReport(db, "p")
+	if err != nil {
+		t.Fatalf("GetReport() failed: %v", err)
+	}
+	if r.SHA256 != HashReport("# Stored") || r.Size != int64(len("# Stored")) {
+		t.Errorf("Unexpected report %+v", r)
+	}
+
+	os.Remove(path)
+	if content, err := ReportContent(db, p); err != nil || content != "# Stored" {
+		t.Errorf("ReportContent() without the file = %q, %v; want the stored copy", content, err)
+	}
+
+	if _, err := GetReport(db, "missing"); err != sql.ErrNoRows {
+		t.Errorf("GetReport() of an unknown probe = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestCheckReports(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	probesDir := filepath.Join(tmpDir, "probes")
+	movedDir := filepath.Join(tmpDir, "elsewhere", "nested")
+	os.MkdirAll(probesDir, 0755)
+	os.MkdirAll(movedDir, 0755)
+
+	write := func(dir, name, content string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		return path
+	}
+	probe := func(id, path string) {
+		InsertProbe(db, id, "full", "/tmp/app", path)
+	}
+
+	// ok: file and stored copy match
+	probe("ok", write(probesDir, "ok.md", "# OK"))
+	// modified: the file was edited after storing
+	probe("modified", write(probesDir, "modified.md", "# Original"))
+	// missing: file deleted, stored copy kept
+	probe("missing", filepath.Join(probesDir, "missing.md"))
+	// moved: file deleted here, identical copy elsewhere
+	probe("moved", filepath.Join(probesDir, "moved.md"))
+	// unstored: file only, never stored
+	probe("unstored", "")
+	// lost: neither
+	probe("lost", filepath.Join(probesDir, "lost.md"))
+	// no report at all
+	probe("none", "")
+
+	tx, _ := db.Begin()
+	if err := storeReports(tx); err != nil {
+		t.Fatalf("storeReports() failed: %v", err)
+	}
+	tx.Commit()
+	SaveReport(db, "missing", "# Missing")
+	SaveReport(db, "moved", "# Moved")
+	write(probesDir, "modified.md", "# Edited")
+	write(movedDir, "renamed.md", "# Moved")
+	RelinkReport(db, "unstored", write(probesDir, "unstored.md", "# Unstored"))
+
+	checks, err := CheckReports(db, []string{filepath.Join(tmpDir, "elsewhere")})
+	if err != nil {
+		t.Fatalf("CheckReports() failed: %v", err)
+	}
+
+	got := make(map[string]ReportCheck)
+	for _, c := range checks {
+		got[c.ProbeID] = c
+	}
+	want := map[string]string{
+		"ok":       ReportOK,
+		"modified": ReportModified,
+		"missing":  ReportMissing,
+		"moved":    ReportMoved,
+		"unstored": ReportUnstored,
+		"lost":     ReportLost,
+		"none":     ReportOK,
+	}
+	for id, status := range want {
+		if got[id].Status != status {
+			t.Errorf("%s: status %q, want %q", id, got[id].Status, status)
+		}
+	}
+	if m := got["moved"].MovedTo; m != filepath.Join(movedDir, "renamed.md") {
+		t.Errorf("Moved report found at %q", m)
+	}
+	if c := got["modified"]; c.FileHash != HashReport("# Edited") || c.SHA256 != HashReport("# Original") {
+		t.Errorf("Modified report hashes %+v", c)
+	}
+
+	if err := RelinkReport(db, "moved", got["moved"].MovedTo); err != nil {
+		t.Fatalf("RelinkReport() failed: %v", err)
+	}
+	checks, _ = CheckReports(db, nil)
+	for _, c := range checks {
+		if c.ProbeID == "moved" && c.Status != ReportOK {
+			t.Errorf("Relinked report status %q, want ok", c.Status)
+		}
+	}
+	if err := RelinkReport(db, "no-such-probe", "/tmp/x.md"); err != sql.ErrNoRows {
+		t.Errorf("RelinkReport() of an unknown probe = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	// Target a: a0..a3 created 40, 30, 20 and 10 days ago; target b: one old probe
+	for i := 0; i < 4; i++ {
+		id := fmt.Sprintf("a%d", i)
+		InsertProbe(db, id, "full", "/tmp/a", "")
+		UpdateProbeStatus(db, id, "completed")
+		db.Exec(`UPDATE probes SET created_at = ? WHERE id = ?`, FormatTimestamp(now.AddDate(0, 0, -40+10*i)), id)
+	}
+	InsertProbe(db, "b0", "full", "/tmp/b", "")
+	UpdateProbeStatus(db, "b0", "completed")
+	db.Exec(`UPDATE probes SET created_at = ? WHERE id = 'b0'`, FormatTimestamp(now.AddDate(0, 0, -100)))
+
+	// a0 has an open critical; a1 a fixed one
+	InsertFinding(db, "crit-open", "a0", "SQL injection in login", "critical")
+	InsertFinding(db, "crit-fixed", "a1", "Command injection", "critical")
+	SetFindingState(db, "crit-fixed", StateFixed, "alice", "")
+
+	ids := func(candidates []PruneCandidate) []string {
+		out := []string{}
+		for _, c := range candidates {
+			out = append(out, c.ID)
+		}
+		return out
+	}
+	candidates := func(policy RetentionPolicy) []string {
+		t.Helper()
+		c, err := PruneCandidates(db, policy, now)
+		if err != nil {
+			t.Fatalf("PruneCandidates(%+v) failed: %v", policy, err)
+		}
+		return ids(c)
+	}
+
+	if got := candidates(RetentionPolicy{}); len(got) != 0 {
+		t.Errorf("An empty policy should prune nothing, got %v", got)
+	}
+	if got := candidates(RetentionPolicy{KeepLast: 2}); !reflect.DeepEqual(got, []string{"a1"}) {
+		t.Errorf("KeepLast 2 = %v, want a1 (a0 has an open critical)", got)
+	}
+	if got := candidates(RetentionPolicy{MaxAge: 25 * 24 * time.Hour}); !reflect.DeepEqual(got, []string{"b0", "a1"}) {
+		t.Errorf("MaxAge 25d = %v", got)
+	}
+	if got := candidates(RetentionPolicy{KeepLast: 3, MaxAge: 35 * 24 * time.Hour}); !reflect.DeepEqual(got, []string{"b0"}) {
+		t.Errorf("KeepLast 3 and MaxAge 35d = %v", got)
+	}
+
+	// Running probes are kept
+	UpdateProbeStatus(db, "b0", "running")
+	if got := candidates(RetentionPolicy{MaxAge: 25 * 24 * time.Hour}); !reflect.DeepEqual(got, []string{"a1"}) {
+		t.Errorf("A running probe should be kept, got %v", got)
+	}
+
+	policy := RetentionPolicy{KeepLast: 1}
+	plan, err := Prune(db, policy, now, true)
+	if err != nil {
+		t.Fatalf("Prune() dry run failed: %v", err)
+	}
+	if got := ids(plan.Candidates); !reflect.DeepEqual(got, []string{"a1", "a2"}) || !plan.DryRun {
+		t.Errorf("Dry run = %v", got)
+	}
+	if _, err := GetProbe(db, "a1"); err != nil {
+		t.Error("A dry run should not delete")
+	}
+
+	result, err := Prune(db, policy, now, false)
+	if err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	if len(result.Probes) != 2 || result.Findings != 1 {
+		t.Errorf("Prune() removed %d probes and %d findings, want 2 and 1", len(result.Probes), result.Findings)
+	}
+	for _, id := range []string{"a0", "a3", "b0"} {
+		if _, err := GetProbe(db, id); err != nil {
+			t.Errorf("%s should be kept", id)
+		}
+	}
+}
+
+func TestPruneByProject(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	// Two clones of one repository share a project, so KeepLast counts
+	// their probes together; an unlinked target is counted on its own
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	project, err := EnsureProject(db, gitinfo.Repo{Root: "/src/app", RemoteURL: "github.com/org/app"})
+	if err != nil {
+		t.Fatalf("EnsureProject() failed: %v", err)
+	}
+	for i, probe := range []struct{ id, target string }{{"clone-a", "/src/app"}, {"clone-b", "/home/ci/app"}, {"loose", "/tmp/loose"}} {
+		InsertProbe(db, probe.id, "full", probe.target, "")
+		UpdateProbeStatus(db, probe.id, "completed")
+		db.Exec(`UPDATE probes SET created_at = ? WHERE id = ?`, FormatTimestamp(now.AddDate(0, 0, -10+i)), probe.id)
+	}
+	SetProbeProject(db, "clone-a", project.ID)
+	SetProbeProject(db, "clone-b", project.ID)
+
+	candidates, err := PruneCandidates(db, RetentionPolicy{KeepLast: 1}, now)
+	if err != nil {
+		t.Fatalf("PruneCandidates() failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].ID != "clone-a" {
+		t.Errorf("KeepLast 1 = %+v, want only clone-a", candidates)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p", "full", "/tmp/app", "")
+	SaveReport(db, "p", "# Report")
+
+	dest := filepath.Join(tmpDir, "snapshot.db")
+	if err := Snapshot(db, dest); err != nil {
+		t.Fatalf("Snapshot() failed: %v", err)
+	}
+	if err := Snapshot(db, dest); err == nil {
+		t.Error("Snapshot() should refuse to overwrite an existing file")
+	}
+
+	copied, err := Open(dest)
+	if err != nil {
+		t.Fatalf("Open() snapshot failed: %v", err)
+	}
+	defer copied.Close()
+
+	if err := IntegrityCheck(copied); err != nil {
+		t.Errorf("IntegrityCheck() failed: %v", err)
+	}
+	if r, err := GetReport(copied, "p"); err != nil || r.Content != "# Report" {
+		t.Errorf("GetReport() on snapshot = %+v, %v", r, err)
+	}
+	want, _ := SchemaVersion(db)
+	if got, _ := SchemaVersion(copied); got != want {
+		t.Errorf("SchemaVersion() of snapshot = %d, want %d", got, want)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	// Two clones of the same remote are one project; its root follows the
+	// latest clone.
+	a, err := EnsureProject(db, gitinfo.Repo{Root: "/src/app", RemoteURL: "github.com/org/app"})
+	if err != nil {
+		t.Fatalf("EnsureProject() failed: %v", err)
+	}
+	b, _ := EnsureProject(db, gitinfo.Repo{Root: "/home/me/app", RemoteURL: "github.com/org/app"})
+	if a.ID != b.ID || b.Root != "/home/me/app" || a.Name != "app" {
+		t.Errorf("Clones of one remote should share a project: %+v, %+v", a, b)
+	}
+	local, _ := EnsureProject(db, gitinfo.Repo{Root: "/src/scratch"})
+	if again, _ := EnsureProject(db, gitinfo.Repo{Root: "/src/scratch"}); local.ID == a.ID || again.ID != local.ID {
+		t.Errorf("Repositories without a remote should be keyed by root: %+v, %+v", local, again)
+	}
+
+	for i, id := range []string{"p1", "p2", "p3"} {
+		InsertProbe(db, id, "full", "/src/app", "")
+		SetProbeProject(db, id, a.ID)
+		db.Exec(`UPDATE probes SET created_at = ? WHERE id = ?`, fmt.Sprintf("2026-01-0%d 00:00:00", i+1), id)
+	}
+	UpdateProbeStatus(db, "p1", "completed")
+	UpdateProbeStatus(db, "p2", "completed")
+	// p3 is still running, so it is not part of the posture or trend.
+
+	CreateFinding(db, &Finding{ID: "f1", ProbeID: "p1", Text: "SQL injection", File: "db.go", Severity: "critical"})
+	CreateFinding(db, &Finding{ID: "f2", ProbeID: "p1", Text: "Weak hash", File: "auth.go", Severity: "medium"})
+	CreateFinding(db, &Finding{ID: "f3", ProbeID: "p2", Text: "SQL injection", File: "db.go", Severity: "critical"})
+	CreateFinding(db, &Finding{ID: "f4", ProbeID: "p2", Text: "Open redirect", File: "web.go", Severity: "low"})
+	SetFindingState(db, "f4", StateFixed, "me", "")
+
+	if err := SetProbeProject(db, "missing", a.ID); err != sql.ErrNoRows {
+		t.Errorf("SetProbeProject() on a missing probe = %v, want sql.ErrNoRows", err)
+	}
+
+	s, err := GetProject(db, a.ID)
+	if err != nil {
+		t.Fatalf("GetProject() failed: %v", err)
+	}
+	if s.ProbeCount != 3 || s.LatestProbeID != "p2" {
+		t.Errorf("ProbeCount = %d, LatestProbeID = %q; want 3 and p2", s.ProbeCount, s.LatestProbeID)
+	}
+	if s.Posture.Critical != 1 || s.Posture.Low != 1 || s.Posture.Total != 2 {
+		t.Errorf("Posture = %+v, want the counts of p2", s.Posture)
+	}
+	if len(s.Trend) != 2 || s.Trend[0].ProbeID != "p1" || s.Trend[1].ProbeID != "p2" {
+		t.Errorf("Trend = %+v, want p1 then p2", s.Trend)
+	}
+	// The injection counts once, the weak hash is still open from p1 and
+	// the redirect is fixed.
+	if s.OpenFindings.Critical != 1 || s.OpenFindings.Medium != 1 || s.OpenFindings.Total != 2 {
+		t.Errorf("OpenFindings = %+v, want one critical and one medium", s.OpenFindings)
+	}
+
+	projects, err := ListProjects(db)
+	if err != nil || len(projects) != 2 || projects[0].ID != a.ID {
+		t.Errorf("ListProjects() = %+v, %v; want the probed project first", projects, err)
+	}
+	if _, err := GetProject(db, "missing"); err != sql.ErrNoRows {
+		t.Errorf("GetProject() of a missing project = %v, want sql.ErrNoRows", err)
+	}
+
+	if page, _ := ListProbes(db, ProbeFilter{Project: a.ID}); len(page.Probes) != 3 {
+		t.Errorf("ListProbes() by project returned %d probes, want 3", len(page.Probes))
+	}
+}
+
+func TestUpdateProbeGit(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "p", "full", "/src/app", "")
+	if p, _ := GetProbe(db, "p"); p.GitProvenance != (GitProvenance{}) {
+		t.Errorf("New probe has provenance %+v", p.GitProvenance)
+	}
+
+	want := GitProvenance{GitCommit: "abc123", GitBranch: "main", GitDirty: true, GitRemote: "github.com/org/app", GitChangedFiles: 3}
+	if err := UpdateProbeGit(db, "p", want); err != nil {
+		t.Fatalf("UpdateProbeGit() failed: %v", err)
+	}
+	if p, _ := GetProbe(db, "p"); p.GitProvenance != want {
+		t.Errorf("GitProvenance = %+v, want %+v", p.GitProvenance, want)
+	}
+}
+
+func TestProbeVersionFilters(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	defer Close(db)
+
+	InsertProbe(db, "old", "full", "/src/app", "")
+	InsertProbe(db, "new", "full", "/src/app", "")
+	UpdateProbeUsage(db, "old", Usage{Provider: "openrouter", Model: "model-a"})
+	UpdateProbeUsage(db, "new", Usage{Provider: "openrouter", Model: "model-b"})
+
+	want := Versions{ProbeVersion: "v1.2.0", AgentVersion: "1.0.0", PromptHash: "abcdef01", SkillHash: "12345678"}
+	if err := UpdateProbeVersions(db, "new", want); err != nil {
+		t.Fatalf("UpdateProbeVersions() failed: %v", err)
+	}
+	UpdateProbeVersions(db, "old", Versions{ProbeVersion: "v1.1.0", PromptHash: "99999999"})
+	if p, _ := GetProbe(db, "new"); p.Versions != want {
+		t.Errorf("Versions = %+v, want %+v", p.Versions, want)
+	}
+
+	tests := []struct {
+		filter ProbeFilter
+		want   []string
+	}{
+		{ProbeFilter{Model: "model-a"}, []string{"old"}},
+		{ProbeFilter{Provider: "openrouter"}, []string{"new", "old"}},
+		{ProbeFilter{ProbeVersion: "v1.2.0"}, []string{"new"}},
+		{ProbeFilter{AgentVersion: "1.0.0"}, []string{"new"}},
+		{ProbeFilter{PromptHash: "ABCD"}, []string{"new"}},
+		{ProbeFilter{SkillHash: "1234", Model: "model-a"}, nil},
+	}
+	for _, tt := range tests {
+		page, err := ListProbes(db, tt.filter)
+		if err != nil {
+			t.Fatalf("ListProbes(%+v) failed: %v", tt.filter, err)
+		}
+		var got []string
+		for _, p := range page.Probes {
+			got = append(got, p.ID)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ListProbes(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ndzuma/probeTool/internal/paths"
+)
+
+// ProbeSelector picks probes to delete. Fields combine; a zero field does
+// not restrict the selection.
+type ProbeSelector struct {
+	Target string
+	Before time.Time
+}
+
+// DeleteResult lists what a probe deletion removed, or would remove on a
+// dry run.
+type DeleteResult struct {
+	Probes   []Probe  `json:"probes"`
+	Findings int      `json:"findings"`
+	Files    []string `json:"files"`
+	DryRun   bool     `json:"dry_run"`
+}
+
+// SelectProbes returns the probes matching sel, oldest first. An empty
+// selector matches nothing so a missing flag cannot delete every probe.
+func SelectProbes(db *sql.DB, sel ProbeSelector) ([]Probe, error) {
+	var where []string
+	var args []interface{}
+	if sel.Target != "" {
+		where = append(where, "target = ?")
+		args = append(args, sel.Target)
+	}
+	if !sel.Before.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, FormatTimestamp(sel.Before))
+	}
+	if len(where) == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`SELECT `+probeColumns+` FROM probes WHERE `+strings.Join(where, " AND ")+` ORDER BY created_at ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var probes []Probe
+	for rows.Next() {
+		var p Probe
+		if err := scanProbe(rows, &p); err != nil {
+			return nil, err
+		}
+		probes = append(probes, p)
+	}
+	return probes, rows.Err()
+}
+
+// ProbeFiles returns the files that belong to a probe: its report and any
+// other file in the probes directory named after it, such as a transcript.
+func ProbeFiles(p Probe) []string {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path == "" || seen[path] {
+			return
+		}
+		if _, err := os.Stat(path); err == nil {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	add(p.FilePath)
+
+	dir := paths.GetProbesDir()
+	if p.FilePath != "" {
+		dir = filepath.Dir(p.FilePath)
+	}
+	if matches, err := filepath.Glob(filepath.Join(dir, globEscape(p.ID)+".*")); err == nil {
+		for _, m := range matches {
+			add(m)
+		}
+	}
+
+	return files
+}
+
+func globEscape(s string) string {
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`).Replace(s)
+}
+
+// DeleteProbes removes the given probes, their findings and everything
+// attached to the findings (history, comments, tags, snippets, patches and
+// verifications) in one transaction, then deletes their files. With dryRun
+// nothing is changed and the result lists what would be removed.
+func DeleteProbes(db *sql.DB, ids []string, dryRun bool) (*DeleteResult, error) {
+	result := &DeleteResult{DryRun: dryRun, Probes: []Probe{}, Files: []string{}}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	for _, id := range ids {
+		p, err := GetProbe(db, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("probe %s not found: %w", id, err)
+			}
+			return nil, err
+		}
+		result.Probes = append(result.Probes, *p)
+		result.Files = append(result.Files, ProbeFiles(*p)...)
+
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM findings WHERE probe_id = ?`, id).Scan(&n); err != nil {
+			return nil, err
+		}
+		result.Findings += n
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM probes WHERE id = ?`, id); err != nil {
+			return nil, fmt.Errorf("failed to delete probe %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// The rows are gone, so a file that cannot be removed is only reported.
+	var errs []string
+	for _, file := range result.Files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("probes deleted but some files remain: %s", strings.Join(errs, "; "))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Finding lifecycle states.
+const (
+	StateOpen          = "open"
+	StateInProgress    = "in_progress"
+	StateFixed         = "fixed"
+	StateAcceptedRisk  = "accepted_risk"
+	StateFalsePositive = "false_positive"
+)
+
+// FindingStates lists every valid finding state.
+var FindingStates = []string{StateOpen, StateInProgress, StateFixed, StateAcceptedRisk, StateFalsePositive}
+
+// ValidFindingState reports whether state is a known finding state.
+func ValidFindingState(state string) bool {
+	for _, s := range FindingStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// isClosedState reports whether a finding in this state needs no more work.
+// It backs the legacy completed flag.
+func isClosedState(state string) bool {
+	return state == StateFixed || state == StateAcceptedRisk || state == StateFalsePositive
+}
+
+// StateChange is one recorded transition of a finding.
+type StateChange struct {
+	ID             int64  `json:"id"`
+	FindingID      string `json:"finding_id"`
+	FromState      string `json:"from_state"`
+	ToState        string `json:"to_state"`
+	Actor          string `json:"actor"`
+	Reason         string `json:"reason"`
+	VerificationID int64  `json:"verification_id,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+const stateChangeColumns = `id, finding_id, from_state, to_state, actor, reason, verification_id, created_at`
+
+func scanStateChange(row rowScanner, c *StateChange) error {
+	var verification sql.NullInt64
+	if err := row.Scan(&c.ID, &c.FindingID, &c.FromState, &c.ToState, &c.Actor, &c.Reason, &verification, &c.CreatedAt); err != nil {
+		return err
+	}
+	c.VerificationID = verification.Int64
+	return nil
+}
+
+// SetFindingState moves a finding to state and records the transition.
+// Setting the state a finding is already in is a no-op and returns a nil
+// change, so repeated requests cannot flip a finding back and forth.
+func SetFindingState(db *sql.DB, id, state, actor, reason string) (*StateChange, error) {
+	if !ValidFindingState(state) {
+		return nil, fmt.Errorf("invalid state %q (expected one of %s)", state, strings.Join(FindingStates, ", "))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.QueryRow(`SELECT state FROM findings WHERE id = ?`, id).Scan(&current); err != nil {
+		return nil, err
+	}
+
+	if current == state {
+		return nil, tx.Commit()
+	}
+
+	completed := 0
+	if isClosedState(state) {
+		completed = 1
+	}
+	update, err := txPrepared(db, tx, updateFindingState)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := update.Exec(state, completed, id); err != nil {
+		return nil, err
+	}
+
+	change, err := insertStateChange(db, tx, id, current, state, actor, reason, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+const (
+	updateFindingState = `UPDATE findings SET state = ?, completed = ? WHERE id = ?`
+	insertHistory      = `INSERT INTO finding_history (finding_id, from_state, to_state, actor, reason, verification_id) VALUES (?, ?, ?, ?, ?, ?)`
+)
+
+func insertStateChange(db *sql.DB, tx *sql.Tx, findingID, from, to, actor, reason string, verificationID int64) (*StateChange, error) {
+	var verification interface{}
+	if verificationID != 0 {
+		verification = verificationID
+	}
+
+	stmt, err := txPrepared(db, tx, insertHistory)
+	if err != nil {
+		return nil, err
+	}
+	res, err := stmt.Exec(findingID, from, to, actor, reason, verification)
+	if err != nil {
+		return nil, err
+	}
+
+	rowID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	var change StateChange
+	if err := scanStateChange(tx.QueryRow(`SELECT `+stateChangeColumns+` FROM finding_history WHERE id = ?`, rowID), &change); err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// GetFindingHistory returns the recorded transitions of a finding, oldest first.
+func GetFindingHistory(db *sql.DB, findingID string) ([]StateChange, error) {
+	rows, err := db.Query(`SELECT `+stateChangeColumns+`
+		FROM finding_history WHERE finding_id = ? ORDER BY id ASC`, findingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []StateChange
+	for rows.Next() {
+		var c StateChange
+		if err := scanStateChange(rows, &c); err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
+
+// ResolveFindingID expands a unique ID prefix to the full finding ID so the
+// CLI can accept the short form shown in listings.
+func ResolveFindingID(db *sql.DB, prefix string) (string, error) {
+	return resolveID(db, "findings", "finding", prefix)
+}
+
+// ResolveProbeID expands a unique ID prefix to the full probe ID.
+func ResolveProbeID(db *sql.DB, prefix string) (string, error) {
+	return resolveID(db, "probes", "probe", prefix)
+}
+
+// ResolveProjectID expands a unique ID prefix to the full project ID.
+func ResolveProjectID(db *sql.DB, prefix string) (string, error) {
+	return resolveID(db, "projects", "project", prefix)
+}
+
+func resolveID(db *sql.DB, table, kind, prefix string) (string, error) {
+	if prefix == "" {
+		return "", fmt.Errorf("%s ID is required", kind)
+	}
+
+	var exact string
+	if err := db.QueryRow(`SELECT id FROM `+table+` WHERE id = ?`, prefix).Scan(&exact); err == nil {
+		return exact, nil
+	}
+
+	rows, err := db.Query(`SELECT id FROM `+table+` WHERE id LIKE ? ESCAPE '\' LIMIT 2`, escapeLike(prefix)+"%")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var matches []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return "", err
+		}
+		matches = append(matches, id)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s matches %q", kind, prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%s ID %q is ambiguous", kind, prefix)
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor ListProbes did not produce.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ProbeSortKeys maps the accepted sort values to their SQL expression.
+var ProbeSortKeys = map[string]string{
+	"created_at": "created_at",
+	"cost":       "cost_usd",
+	"duration":   "duration_ms",
+	"findings":   "COALESCE(c.total, 0)",
+}
+
+// DefaultProbeLimit and MaxProbeLimit bound the page size of ListProbes.
+const (
+	DefaultProbeLimit = 100
+	MaxProbeLimit     = 1000
+)
+
+// ProbeFilter selects, orders and pages probes. Zero fields do not
+// restrict the listing; Sort defaults to created_at, newest first.
+type ProbeFilter struct {
+	Target   string
+	Project  string
+	Status   string
+	Type     string
+	Provider string
+	Model    string
+	// ProbeVersion and AgentVersion match exactly; PromptHash and
+	// SkillHash match a prefix, so a short hash is enough.
+	ProbeVersion string
+	AgentVersion string
+	PromptHash   string
+	SkillHash    string
+	Since        time.Time
+	Until        time.Time
+	Sort         string
+	Asc          bool
+	Limit        int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+}
+
+// SeverityCounts are the number of findings of a probe by severity.
+type SeverityCounts struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Info     int `json:"info"`
+	Total    int `json:"total"`
+}
+
+// ProbeListing is a probe with its finding counts.
+type ProbeListing struct {
+	Probe
+	FindingCounts SeverityCounts `json:"finding_counts"`
+}
+
+// ProbePage is one page of ListProbes. NextCursor is empty on the last
+// page.
+type ProbePage struct {
+	Probes     []ProbeListing `json:"probes"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// probeCursor is the sort value and ID of the last probe on a page. Paging
+// by value rather than offset keeps pages stable while probes are added.
+type probeCursor struct {
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// ListProbes returns one page of probes matching f with their finding
+// counts by severity.
+func ListProbes(db *sql.DB, f ProbeFilter) (*ProbePage, error) {
+	sortKey := f.Sort
+	if sortKey == "" {
+		sortKey = "created_at"
+	}
+	sortExpr, ok := ProbeSortKeys[sortKey]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort %q (expected created_at, cost, duration or findings)", f.Sort)
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultProbeLimit
+	}
+	if limit > MaxProbeLimit {
+		limit = MaxProbeLimit
+	}
+
+	var where []string
+	var args []interface{}
+	for _, eq := range []struct{ col, value string }{
+		{"target", f.Target}, {"project_id", f.Project}, {"status", f.Status}, {"type", f.Type},
+		{"provider", f.Provider}, {"model", f.Model},
+		{"probe_version", f.ProbeVersion}, {"agent_version", f.AgentVersion},
+	} {
+		if eq.value != "" {
+			where = append(where, eq.col+" = ?")
+			args = append(args, eq.value)
+		}
+	}
+	for _, prefix := range []struct{ col, value string }{{"prompt_hash", f.PromptHash}, {"skill_hash", f.SkillHash}} {
+		if prefix.value != "" {
+			where = append(where, "substr("+prefix.col+", 1, ?) = ?")
+			args = append(args, len(prefix.value), strings.ToLower(prefix.value))
+		}
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, FormatTimestamp(f.Since))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, FormatTimestamp(f.Until))
+	}
+
+	dir, cmp := "DESC", "<"
+	if f.Asc {
+		dir, cmp = "ASC", ">"
+	}
+	if f.Cursor != "" {
+		c, err := decodeProbeCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sortExpr, cmp))
+		args = append(args, c.Value, c.Value, c.ID)
+	}
+
+	query := `SELECT ` + probeColumns + `,
+		COALESCE(c.critical, 0), COALESCE(c.high, 0), COALESCE(c.medium, 0),
+		COALESCE(c.low, 0), COALESCE(c.info, 0), COALESCE(c.total, 0), ` + sortExpr + `
+		FROM probes
+		LEFT JOIN (
+			SELECT probe_id,
+				SUM(severity = 'critical') AS critical, SUM(severity = 'high') AS high,
+				SUM(severity = 'medium') AS medium, SUM(severity = 'low') AS low,
+				SUM(severity NOT IN ('critical', 'high', 'medium', 'low')) AS info,
+				COUNT(*) AS total
+			FROM findings GROUP BY probe_id
+		) c ON c.probe_id = probes.id`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s, id %s LIMIT ?`, sortExpr, dir, dir)
+	args = append(args, limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &ProbePage{Probes: []ProbeListing{}}
+	var last interface{}
+	for rows.Next() {
+		var l ProbeListing
+		var sortValue interface{}
+		p := &l.Probe
+		c := &l.FindingCounts
+		fields := append(probeFields(p), &c.Critical, &c.High, &c.Medium, &c.Low, &c.Info, &c.Total, &sortValue)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		if len(page.Probes) == limit {
+			page.NextCursor = encodeProbeCursor(probeCursor{Value: last, ID: page.Probes[limit-1].ID})
+			break
+		}
+		page.Probes = append(page.Probes, l)
+		last = sortValue
+	}
+
+	return page, rows.Err()
+}
+
+func encodeProbeCursor(c probeCursor) string {
+	if b, ok := c.Value.([]byte); ok {
+		c.Value = string(b)
+	}
+	if t, ok := c.Value.(time.Time); ok {
+		c.Value = FormatTimestamp(t)
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProbeCursor(s string) (*probeCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c probeCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
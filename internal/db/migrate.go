@@ -0,0 +1,360 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// probe binary than this one.
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of probe supports")
+
+// Migration is one step of the schema. SQL migrations live in
+// migrations/NNNN_name.sql; steps that need Go, such as backfills, are
+// listed in goMigrations. Versions are contiguous from 1.
+type Migration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	up      func(tx *sql.Tx) error
+}
+
+// goMigrations are the migrations written in Go rather than SQL.
+var goMigrations = []Migration{
+	{Version: 2, Name: "legacy_columns", up: adoptLegacyColumns},
+	{Version: 4, Name: "search", up: createSearchIndex},
+	{Version: 5, Name: "report_search", up: indexReports},
+	{Version: 8, Name: "store_reports", up: storeReports},
+	{Version: 10, Name: "link_projects", up: linkProjects},
+}
+
+// MigrationState is a migration and whether it has been applied.
+type MigrationState struct {
+	Migration
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+// MigrationResult describes a Migrate run.
+type MigrationResult struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Applied []Migration `json:"applied"`
+	Backup  string      `json:"backup,omitempty"`
+}
+
+// Migrations returns every migration known to this binary, in order.
+func Migrations() ([]Migration, error) {
+	list := append([]Migration(nil), goMigrations...)
+
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		base := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s is not named NNNN_name.sql", file)
+		}
+
+		data, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		script := string(data)
+		list = append(list, Migration{Version: version, Name: name, up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(script)
+			return err
+		}})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i, m := range list {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d (%s) is out of sequence: expected version %d", m.Version, m.Name, i+1)
+		}
+	}
+
+	return list, nil
+}
+
+// SchemaVersion returns the highest migration applied to the database, or
+// 0 for a database that has never been migrated.
+func SchemaVersion(db *sql.DB) (int, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// MigrationStatus lists every known migration with the time it was applied,
+// if it was.
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]string)
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i] = MigrationState{Migration: m, AppliedAt: applied[m.Version]}
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for version := range applied {
+		if version > latest {
+			return states, fmt.Errorf("%w (database at version %d, this binary knows up to %d)", ErrSchemaTooNew, version, latest)
+		}
+	}
+
+	return states, nil
+}
+
+// Migrate applies every pending migration, each in its own transaction. A
+// database that already holds data is backed up next to the database file
+// first. A database migrated by a newer binary is left untouched and
+// ErrSchemaTooNew returned. A search index built for the other SQLite
+// build (with or without FTS5) is rebuilt first.
+func Migrate(db *sql.DB) (*MigrationResult, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reconcileSearchIndex(db); err != nil {
+		return nil, fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+
+	result := &MigrationResult{}
+	var pending []Migration
+	for _, s := range states {
+		if s.AppliedAt != "" {
+			result.From = s.Version
+		} else {
+			pending = append(pending, s.Migration)
+		}
+	}
+	result.To = result.From
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	if result.Backup, err = backupBeforeMigrating(db, result.From); err != nil {
+		return nil, fmt.Errorf("failed to back up database before migrating: %w", err)
+	}
+
+	for _, m := range pending {
+		applied, err := applyMigration(db, m)
+		if err != nil {
+			return result, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		if applied {
+			result.Applied = append(result.Applied, m)
+		}
+		result.To = m.Version
+	}
+
+	return result, nil
+}
+
+// applyMigration applies m unless another process applied it first, and
+// reports whether it did. Transactions take the write lock at BEGIN, so
+// the check and the migration cannot interleave with another writer.
+func applyMigration(db *sql.DB, m Migration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var done int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, m.Version).Scan(&done); err != nil {
+		return false, err
+	}
+	if done > 0 {
+		return false, nil
+	}
+
+	if err := m.up(tx); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// ensureMigrationsTable creates schema_migrations in a transaction, which
+// waits for the write lock. On its own the statement reads the schema
+// first, and fails at once if another process creates the table in between.
+func ensureMigrationsTable(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backupBeforeMigrating copies a database that holds data to
+// <file>.v<version>-<timestamp>.bak and returns the path. New and in-memory
+// databases are not backed up.
+func backupBeforeMigrating(db *sql.DB, version int) (string, error) {
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')`).Scan(&tables); err != nil {
+		return "", err
+	}
+	if tables == 0 {
+		return "", nil
+	}
+
+	file, err := databaseFile(db)
+	if err != nil || file == "" {
+		return "", err
+	}
+
+	backup := fmt.Sprintf("%s.v%d-%s.bak", file, version, time.Now().Format("20060102-150405"))
+	// VACUUM INTO writes to an empty file, so creating it first claims the
+	// name. If it is taken, another process opening the database at the same
+	// moment is already backing up this version.
+	f, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return backup, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	if _, err := db.Exec(`VACUUM INTO ?`, backup); err != nil {
+		os.Remove(backup)
+		return "", err
+	}
+	return backup, nil
+}
+
+// databaseFile returns the file behind the main database, or "" for an
+// in-memory one.
+func databaseFile(db *sql.DB) (string, error) {
+	rows, err := db.Query(`PRAGMA database_list`)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seq int
+		var name, file string
+		if err := rows.Scan(&seq, &name, &file); err != nil {
+			return "", err
+		}
+		if name == "main" {
+			return file, nil
+		}
+	}
+	return "", rows.Err()
+}
+
+// legacyColumns are the columns added to each table before the schema was
+// versioned, when InitDB altered tables in place.
+var legacyColumns = map[string][]column{
+	"probes": {
+		{"provider", "TEXT DEFAULT ''"},
+		{"model", "TEXT DEFAULT ''"},
+		{"input_tokens", "INTEGER DEFAULT 0"},
+		{"output_tokens", "INTEGER DEFAULT 0"},
+		{"cost_usd", "REAL DEFAULT 0"},
+		{"duration_ms", "INTEGER DEFAULT 0"},
+		{"num_turns", "INTEGER DEFAULT 0"},
+	},
+	"findings": {
+		{"file", "TEXT DEFAULT ''"},
+		{"line_start", "INTEGER DEFAULT 0"},
+		{"line_end", "INTEGER DEFAULT 0"},
+		{"cwe", "TEXT DEFAULT ''"},
+		{"owasp", "TEXT DEFAULT ''"},
+		{"description", "TEXT DEFAULT ''"},
+		{"remediation", "TEXT DEFAULT ''"},
+		{"fingerprint", "TEXT DEFAULT ''"},
+		{"state", "TEXT DEFAULT 'open'"},
+		{"assignee", "TEXT DEFAULT ''"},
+		{"aliases", "TEXT DEFAULT ''"},
+	},
+	"finding_history": {
+		{"verification_id", "INTEGER"},
+	},
+}
+
+// adoptLegacyColumns brings tables created before versioning up to the
+// shape of migration 1 and backfills the columns that need it. On a new
+// database every column already exists and nothing is backfilled.
+func adoptLegacyColumns(tx *sql.Tx) error {
+	tables := make([]string, 0, len(legacyColumns))
+	for table := range legacyColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		if err := ensureColumns(tx, table, legacyColumns[table]); err != nil {
+			return fmt.Errorf("failed to migrate %s table: %w", table, err)
+		}
+	}
+
+	// Findings ticked off before lifecycle states existed count as fixed.
+	if _, err := tx.Exec(`UPDATE findings SET state = 'fixed' WHERE completed = 1 AND state = 'open'`); err != nil {
+		return fmt.Errorf("failed to migrate finding states: %w", err)
+	}
+
+	if err := backfillFingerprints(tx); err != nil {
+		return fmt.Errorf("failed to backfill finding fingerprints: %w", err)
+	}
+
+	return nil
+}
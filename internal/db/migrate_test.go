@@ -0,0 +1,292 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ndzuma/probeTool/internal/findings"
+)
+
+func TestMigrationsAreSequential(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() failed: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations found")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 || m.Name == "" || m.up == nil {
+			t.Errorf("migration %d is malformed: %+v", i, m)
+		}
+	}
+}
+
+func TestMigrateNewDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer Close(db)
+
+	result, err := Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+
+	migrations, _ := Migrations()
+	if result.From != 0 || result.To != len(migrations) || len(result.Applied) != len(migrations) {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if result.Backup != "" {
+		t.Errorf("A new database should not be backed up, got %s", result.Backup)
+	}
+
+	result, err = Migrate(db)
+	if err != nil || len(result.Applied) != 0 || result.From != len(migrations) {
+		t.Errorf("Second Migrate() = %+v, %v; want nothing applied", result, err)
+	}
+
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatalf("MigrationStatus() failed: %v", err)
+	}
+	for _, s := range states {
+		if s.AppliedAt == "" {
+			t.Errorf("migration %d should be applied", s.Version)
+		}
+	}
+}
+
+// legacySchema is the database InitDB created before fingerprints,
+// lifecycle states and schema versioning existed.
+const legacySchema = `
+CREATE TABLE probes (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	target TEXT NOT NULL,
+	file_path TEXT,
+	status TEXT DEFAULT 'running',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE findings (
+	id TEXT PRIMARY KEY,
+	probe_id TEXT NOT NULL,
+	text TEXT NOT NULL,
+	severity TEXT DEFAULT 'info',
+	completed INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (probe_id) REFERENCES probes(id) ON DELETE CASCADE
+);
+INSERT INTO probes (id, type, target, file_path) VALUES ('old', 'full', '/tmp/test', '/tmp/old.md');
+INSERT INTO findings (id, probe_id, text, severity, completed) VALUES ('old-1', 'old', 'Hardcoded API key in config', 'high', 1);
+INSERT INTO findings (id, probe_id, text, severity, completed) VALUES ('old-2', 'old', 'Debug mode enabled', 'low', 0);
+`
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "probes.db")
+
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := legacy.Exec(legacySchema); err != nil {
+		t.Fatalf("failed to create legacy database: %v", err)
+	}
+	legacy.Close()
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer Close(db)
+
+	result, err := Migrate(db)
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+
+	if result.Backup == "" {
+		t.Fatal("Migrating a database with data should back it up")
+	}
+	backup, err := sql.Open("sqlite3", result.Backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	var count int
+	if err := backup.QueryRow(`SELECT COUNT(*) FROM findings`).Scan(&count); err != nil || count != 2 {
+		t.Errorf("Backup should hold the original findings, got %d, %v", count, err)
+	}
+
+	f, err := GetFinding(db, "old-1")
+	if err != nil {
+		t.Fatalf("GetFinding() failed: %v", err)
+	}
+	if want := findings.Fingerprint(f.Text, "", ""); f.Fingerprint != want {
+		t.Errorf("Fingerprint = %q, want backfilled %q", f.Fingerprint, want)
+	}
+	if f.State != StateFixed {
+		t.Errorf("Completed legacy finding should be fixed, got %s", f.State)
+	}
+	if f, _ := GetFinding(db, "old-2"); f.State != StateOpen {
+		t.Errorf("Open legacy finding should stay open, got %s", f.State)
+	}
+
+	// Existing findings are indexed for search
+	if hits, err := Search(db, SearchQuery{Query: "api key"}); err != nil || len(hits) != 1 || hits[0].ID != "old-1" {
+		t.Errorf("Search() on migrated database = %+v, %v", hits, err)
+	}
+
+	// Existing probes are linked to a project for their target
+	if p, err := GetProbe(db, "old"); err != nil || p.ProjectID == "" {
+		t.Errorf("Migrated probe has no project: %+v, %v", p, err)
+	} else if project, err := GetProject(db, p.ProjectID); err != nil || project.Root != "/tmp/test" {
+		t.Errorf("GetProject() = %+v, %v; want a project rooted at the target", project, err)
+	}
+
+	// New tables and columns are usable
+	if err := AddTags(db, "old-2", "legacy"); err != nil {
+		t.Errorf("AddTags() on migrated database failed: %v", err)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB() failed: %v", err)
+	}
+	migrations, _ := Migrations()
+	future := len(migrations) + 1
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, 'from_the_future')`, future); err != nil {
+		t.Fatal(err)
+	}
+	Close(db)
+
+	if _, err := InitDB(dbPath); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("InitDB() on a newer schema = %v, want ErrSchemaTooNew", err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(dbPath))
+	if len(entries) != 1 {
+		t.Errorf("A refused database should not be backed up, found %d files", len(entries))
+	}
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+
+	m := Migration{Version: 1, Name: "broken", up: func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`CREATE TABLE half_done (id INTEGER)`); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}}
+
+	ensureMigrationsTable(db)
+	if _, err := applyMigration(db, m); err == nil {
+		t.Fatal("applyMigration() should fail")
+	}
+
+	var tables int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'`).Scan(&tables)
+	if tables != 0 {
+		t.Error("A failed migration should be rolled back")
+	}
+	if version, _ := SchemaVersion(db); version != 0 {
+		t.Errorf("A failed migration should not be recorded, version = %d", version)
+	}
+}
+
+// otherBuildSearchIndex replaces the search index with the kind the other
+// SQLite build creates. Without FTS5 the virtual table cannot be created,
+// so its schema row and shadow tables are written the way FTS5 leaves them.
+func otherBuildSearchIndex(t *testing.T, db *sql.DB) {
+	t.Helper()
+	fts, err := hasFTS5(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := dropSearchIndex(tx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	statements := []string{searchTablePlain, searchTriggers}
+	if !fts {
+		statements = []string{`PRAGMA writable_schema = ON`,
+			`INSERT INTO sqlite_master (type, name, tbl_name, rootpage, sql) VALUES ('table', 'search_index', 'search_index', 0, 'CREATE VIRTUAL TABLE search_index USING fts5(title, body, kind UNINDEXED, ref_id UNINDEXED, probe_id UNINDEXED)')`,
+			`PRAGMA writable_schema = RESET`}
+		for _, shadow := range ftsShadowTables {
+			statements = append(statements, `CREATE TABLE search_index_`+shadow+` (id INTEGER PRIMARY KEY, block BLOB)`)
+		}
+		// Without the backfill, which would need the module.
+		statements = append(statements, searchTriggers[:strings.LastIndex(searchTriggers, "INSERT INTO search_index")])
+	}
+	for _, s := range statements {
+		if _, err := tx.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateRebuildsSearchIndexOfOtherBuild(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	InsertProbe(db, "p1", "full", "/tmp/app", "")
+	SaveReport(db, "p1", "The webhook handler fetches arbitrary URLs.")
+	CreateFinding(db, &Finding{ID: "f1", ProbeID: "p1", Text: "SSRF in webhook handler", Severity: "high"})
+	otherBuildSearchIndex(t, db)
+	if mismatch, _, _ := searchIndexMismatch(db); !mismatch {
+		t.Fatal("search index should be the other build's")
+	}
+	Close(db)
+
+	db, err = InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB() of a database from the other build failed: %v", err)
+	}
+	defer Close(db)
+
+	if mismatch, _, err := searchIndexMismatch(db); err != nil || mismatch {
+		t.Fatalf("search index should match this build, mismatch = %v, err = %v", mismatch, err)
+	}
+	if err := CreateFinding(db, &Finding{ID: "f2", ProbeID: "p1", Text: "Open redirect after login", Severity: "medium"}); err != nil {
+		t.Fatalf("CreateFinding() after the rebuild failed: %v", err)
+	}
+	if err := DeleteFinding(db, "f2"); err != nil {
+		t.Fatalf("DeleteFinding() after the rebuild failed: %v", err)
+	}
+
+	hits, err := Search(db, SearchQuery{Query: "webhook"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]bool{}
+	for _, h := range hits {
+		kinds[h.Kind+":"+h.ID] = true
+	}
+	if len(hits) != 2 || !kinds["finding:f1"] || !kinds["report:p1"] {
+		t.Errorf("Existing findings and reports should be reindexed, got %+v", hits)
+	}
+}
@@ -0,0 +1,108 @@
+-- Tables as of the first versioned schema. Databases created before
+-- versioning already have some of these; their missing columns are added by
+-- migration 2.
+
+CREATE TABLE IF NOT EXISTS probes (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	target TEXT NOT NULL,
+	file_path TEXT,
+	status TEXT DEFAULT 'running',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	provider TEXT DEFAULT '',
+	model TEXT DEFAULT '',
+	input_tokens INTEGER DEFAULT 0,
+	output_tokens INTEGER DEFAULT 0,
+	cost_usd REAL DEFAULT 0,
+	duration_ms INTEGER DEFAULT 0,
+	num_turns INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS findings (
+	id TEXT PRIMARY KEY,
+	probe_id TEXT NOT NULL,
+	text TEXT NOT NULL,
+	severity TEXT DEFAULT 'info',
+	completed INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	file TEXT DEFAULT '',
+	line_start INTEGER DEFAULT 0,
+	line_end INTEGER DEFAULT 0,
+	cwe TEXT DEFAULT '',
+	owasp TEXT DEFAULT '',
+	description TEXT DEFAULT '',
+	remediation TEXT DEFAULT '',
+	fingerprint TEXT DEFAULT '',
+	state TEXT DEFAULT 'open',
+	assignee TEXT DEFAULT '',
+	aliases TEXT DEFAULT '',
+	FOREIGN KEY (probe_id) REFERENCES probes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS finding_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	finding_id TEXT NOT NULL,
+	from_state TEXT NOT NULL,
+	to_state TEXT NOT NULL,
+	actor TEXT DEFAULT '',
+	reason TEXT DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	verification_id INTEGER,
+	FOREIGN KEY (finding_id) REFERENCES findings(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS finding_verifications (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	finding_id TEXT NOT NULL,
+	verdict TEXT NOT NULL,
+	reasoning TEXT DEFAULT '',
+	provider TEXT DEFAULT '',
+	model TEXT DEFAULT '',
+	cost_usd REAL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (finding_id) REFERENCES findings(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS finding_patches (
+	finding_id TEXT PRIMARY KEY,
+	patch TEXT NOT NULL,
+	summary TEXT DEFAULT '',
+	valid INTEGER DEFAULT 0,
+	check_output TEXT DEFAULT '',
+	base_commit TEXT DEFAULT '',
+	provider TEXT DEFAULT '',
+	model TEXT DEFAULT '',
+	cost_usd REAL DEFAULT 0,
+	branch TEXT DEFAULT '',
+	applied_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (finding_id) REFERENCES findings(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS finding_comments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	finding_id TEXT NOT NULL,
+	parent_id INTEGER,
+	author TEXT DEFAULT '',
+	body TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (finding_id) REFERENCES findings(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS finding_tags (
+	finding_id TEXT NOT NULL,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (finding_id, tag),
+	FOREIGN KEY (finding_id) REFERENCES findings(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS finding_snippets (
+	finding_id TEXT PRIMARY KEY,
+	file TEXT NOT NULL,
+	start_line INTEGER NOT NULL,
+	end_line INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	commit_hash TEXT DEFAULT '',
+	captured_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (finding_id) REFERENCES findings(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_finding_tags_tag ON finding_tags(tag);
+CREATE INDEX IF NOT EXISTS idx_findings_fingerprint ON findings(fingerprint);
@@ -0,0 +1,5 @@
+-- Indexes for filtered and paginated probe listings and their per-probe
+-- finding counts.
+CREATE INDEX IF NOT EXISTS idx_probes_created ON probes(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_probes_target ON probes(target, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_findings_probe ON findings(probe_id, severity);
@@ -0,0 +1,11 @@
+-- Report bodies, so a probe keeps its report when the .md file is deleted
+-- or moved. sha256 is the digest of content; migration 8 imports the
+-- reports of existing probes from their files.
+CREATE TABLE IF NOT EXISTS reports (
+	probe_id TEXT PRIMARY KEY,
+	sha256 TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	stored_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (probe_id) REFERENCES probes(id) ON DELETE CASCADE
+);
@@ -0,0 +1,17 @@
+-- Projects group the probes of one repository, so clones of the same repo
+-- in different places share a history. A repository is identified by its
+-- normalized remote URL, or by its root when it has no remote. Migration 10
+-- links existing probes to projects.
+CREATE TABLE IF NOT EXISTS projects (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	remote_url TEXT NOT NULL DEFAULT '',
+	root TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_remote ON projects(remote_url) WHERE remote_url != '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_root ON projects(root) WHERE remote_url = '';
+
+ALTER TABLE probes ADD COLUMN project_id TEXT REFERENCES projects(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_probes_project ON probes(project_id, created_at DESC);
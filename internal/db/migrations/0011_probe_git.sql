@@ -0,0 +1,8 @@
+-- The state of the target's repository when a probe started, so a report
+-- can be tied to the code it describes. git_remote is normalized and has
+-- no credentials.
+ALTER TABLE probes ADD COLUMN git_commit TEXT NOT NULL DEFAULT '';
+ALTER TABLE probes ADD COLUMN git_branch TEXT NOT NULL DEFAULT '';
+ALTER TABLE probes ADD COLUMN git_dirty INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE probes ADD COLUMN git_remote TEXT NOT NULL DEFAULT '';
+ALTER TABLE probes ADD COLUMN git_changed_files INTEGER NOT NULL DEFAULT 0;
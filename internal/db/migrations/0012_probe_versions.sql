@@ -0,0 +1,10 @@
+-- What produced a probe's report: the probe binary, the agent bundle and
+-- the SHA-256 of its prompt template and security-audit skill. Together
+-- with type (the profile), provider and model they make a report
+-- reproducible. Older probes leave them empty.
+ALTER TABLE probes ADD COLUMN probe_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE probes ADD COLUMN agent_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE probes ADD COLUMN prompt_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE probes ADD COLUMN skill_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_probes_versions ON probes(prompt_hash, skill_hash);
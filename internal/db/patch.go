@@ -0,0 +1,58 @@
+package db
+
+import "database/sql"
+
+// Patch is an agent-proposed fix for a finding, stored as a unified diff
+// together with the result of checking it against the target.
+type Patch struct {
+	FindingID   string  `json:"finding_id"`
+	Patch       string  `json:"patch"`
+	Summary     string  `json:"summary"`
+	Valid       bool    `json:"valid"`
+	CheckOutput string  `json:"check_output"`
+	BaseCommit  string  `json:"base_commit"`
+	Provider    string  `json:"provider"`
+	Model       string  `json:"model"`
+	CostUSD     float64 `json:"cost_usd"`
+	Branch      string  `json:"branch"`
+	AppliedAt   string  `json:"applied_at"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+// SavePatch stores the patch of a finding, replacing any earlier one.
+func SavePatch(db *sql.DB, p *Patch) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO finding_patches
+		(finding_id, patch, summary, valid, check_output, base_commit, provider, model, cost_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.FindingID, p.Patch, p.Summary, p.Valid, p.CheckOutput, p.BaseCommit, p.Provider, p.Model, p.CostUSD)
+	return err
+}
+
+// GetPatch returns the patch of a finding, or sql.ErrNoRows when none was
+// generated.
+func GetPatch(db *sql.DB, findingID string) (*Patch, error) {
+	var p Patch
+	var valid int
+	err := db.QueryRow(`SELECT finding_id, patch, summary, valid, check_output, base_commit, provider, model, cost_usd,
+			branch, COALESCE(applied_at, ''), created_at
+		FROM finding_patches WHERE finding_id = ?`, findingID).
+		Scan(&p.FindingID, &p.Patch, &p.Summary, &valid, &p.CheckOutput, &p.BaseCommit, &p.Provider, &p.Model, &p.CostUSD,
+			&p.Branch, &p.AppliedAt, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.Valid = valid == 1
+	return &p, nil
+}
+
+// MarkPatchApplied records the branch a finding's patch was applied on.
+func MarkPatchApplied(db *sql.DB, findingID, branch string) error {
+	res, err := db.Exec(`UPDATE finding_patches SET branch = ?, applied_at = CURRENT_TIMESTAMP WHERE finding_id = ?`, branch, findingID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/ndzuma/probeTool/internal/gitinfo"
+)
+
+// Project is a repository probes were run against.
+type Project struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	RemoteURL string `json:"remote_url"`
+	Root      string `json:"root"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ProjectSummary is a project with its probe history rolled up.
+type ProjectSummary struct {
+	Project
+	ProbeCount    int    `json:"probe_count"`
+	LastProbeAt   string `json:"last_probe_at,omitempty"`
+	LatestProbeID string `json:"latest_probe_id,omitempty"`
+	// Posture counts the findings of the latest completed probe.
+	Posture SeverityCounts `json:"posture"`
+	// OpenFindings counts distinct findings, by fingerprint, whose latest
+	// occurrence in the project is open or in progress.
+	OpenFindings SeverityCounts `json:"open_findings"`
+	// Trend is the finding counts of the most recent completed probes,
+	// oldest first.
+	Trend []TrendPoint `json:"trend"`
+}
+
+// TrendPoint is the finding counts of one probe.
+type TrendPoint struct {
+	ProbeID   string         `json:"probe_id"`
+	CreatedAt string         `json:"created_at"`
+	Counts    SeverityCounts `json:"counts"`
+}
+
+// ProjectTrendLength is how many probes a project's trend covers.
+const ProjectTrendLength = 20
+
+const projectColumns = `id, name, remote_url, root, created_at`
+
+func severityCountFields(c *SeverityCounts) []interface{} {
+	return []interface{}{&c.Critical, &c.High, &c.Medium, &c.Low, &c.Info, &c.Total}
+}
+
+// EnsureProject returns the project for a repository, creating it on first
+// sight. A repository with a remote is matched by remote alone and its root
+// updated to the latest clone probed; one without is matched by root.
+func EnsureProject(db *sql.DB, repo gitinfo.Repo) (*Project, error) {
+	return ensureProject(db, repo)
+}
+
+func ensureProject(db querier, repo gitinfo.Repo) (*Project, error) {
+	// Insert first and then look the project up, so two probes of a new
+	// repository at once cannot both miss it and collide on insert.
+	if _, err := db.Exec(`INSERT INTO projects (id, name, remote_url, root) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+		uuid.New().String(), repo.Name(), repo.RemoteURL, repo.Root); err != nil {
+		return nil, err
+	}
+
+	var p Project
+	var err error
+	if repo.RemoteURL != "" {
+		err = db.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE remote_url = ?`, repo.RemoteURL).
+			Scan(&p.ID, &p.Name, &p.RemoteURL, &p.Root, &p.CreatedAt)
+	} else {
+		err = db.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE remote_url = '' AND root = ?`, repo.Root).
+			Scan(&p.ID, &p.Name, &p.RemoteURL, &p.Root, &p.CreatedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Root != repo.Root {
+		if _, err := db.Exec(`UPDATE projects SET root = ? WHERE id = ?`, repo.Root, p.ID); err != nil {
+			return nil, err
+		}
+		p.Root = repo.Root
+	}
+	return &p, nil
+}
+
+// SetProbeProject links a probe to its project.
+func SetProbeProject(db *sql.DB, probeID, projectID string) error {
+	res, err := execPrepared(db, `UPDATE probes SET project_id = ? WHERE id = ?`, projectID, probeID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// linkProjects assigns existing probes to projects. Targets that are no
+// longer git work trees get a project keyed by the target path.
+func linkProjects(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT DISTINCT target FROM probes WHERE project_id IS NULL`)
+	if err != nil {
+		return err
+	}
+	var targets []string
+	for rows.Next() {
+		var target string
+		if err := rows.Scan(&target); err != nil {
+			rows.Close()
+			return err
+		}
+		targets = append(targets, target)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		repo, err := gitinfo.Detect(target)
+		if err != nil {
+			repo = &gitinfo.Repo{Root: filepath.Clean(target)}
+		}
+		p, err := ensureProject(tx, *repo)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE probes SET project_id = ? WHERE target = ? AND project_id IS NULL`, p.ID, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListProjects returns every project with its rollup, most recently probed
+// first.
+func ListProjects(db *sql.DB) ([]ProjectSummary, error) {
+	rows, err := db.Query(`SELECT ` + projectColumns + ` FROM projects
+		ORDER BY (SELECT MAX(created_at) FROM probes WHERE project_id = projects.id) DESC, name`)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []ProjectSummary{}
+	for rows.Next() {
+		var s ProjectSummary
+		if err := rows.Scan(&s.ID, &s.Name, &s.RemoteURL, &s.Root, &s.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range summaries {
+		if err := summarizeProject(db, &summaries[i]); err != nil {
+			return nil, err
+		}
+	}
+	return summaries, nil
+}
+
+// GetProject returns one project with its rollup, or sql.ErrNoRows.
+func GetProject(db *sql.DB, id string) (*ProjectSummary, error) {
+	var s ProjectSummary
+	err := db.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE id = ?`, id).
+		Scan(&s.ID, &s.Name, &s.RemoteURL, &s.Root, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := summarizeProject(db, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func summarizeProject(db *sql.DB, s *ProjectSummary) error {
+	var last sql.NullString
+	if err := db.QueryRow(`SELECT COUNT(*), MAX(created_at) FROM probes WHERE project_id = ?`, s.ID).
+		Scan(&s.ProbeCount, &last); err != nil {
+		return err
+	}
+	s.LastProbeAt = last.String
+
+	rows, err := db.Query(`SELECT p.id, p.created_at,
+		COALESCE(c.critical, 0), COALESCE(c.high, 0), COALESCE(c.medium, 0),
+		COALESCE(c.low, 0), COALESCE(c.info, 0), COALESCE(c.total, 0)
+		FROM probes p
+		LEFT JOIN (
+			SELECT probe_id,
+				SUM(severity = 'critical') AS critical, SUM(severity = 'high') AS high,
+				SUM(severity = 'medium') AS medium, SUM(severity = 'low') AS low,
+				SUM(severity NOT IN ('critical', 'high', 'medium', 'low')) AS info,
+				COUNT(*) AS total
+			FROM findings GROUP BY probe_id
+		) c ON c.probe_id = p.id
+		WHERE p.project_id = ? AND p.status = 'completed'
+		ORDER BY p.created_at DESC, p.id DESC LIMIT ?`, s.ID, ProjectTrendLength)
+	if err != nil {
+		return err
+	}
+	s.Trend = []TrendPoint{}
+	for rows.Next() {
+		var t TrendPoint
+		if err := rows.Scan(append([]interface{}{&t.ProbeID, &t.CreatedAt}, severityCountFields(&t.Counts)...)...); err != nil {
+			rows.Close()
+			return err
+		}
+		s.Trend = append(s.Trend, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(s.Trend) > 0 {
+		s.LatestProbeID = s.Trend[0].ProbeID
+		s.Posture = s.Trend[0].Counts
+	}
+	for i, j := 0, len(s.Trend)-1; i < j; i, j = i+1, j-1 {
+		s.Trend[i], s.Trend[j] = s.Trend[j], s.Trend[i]
+	}
+
+	return db.QueryRow(`SELECT COALESCE(SUM(severity = 'critical'), 0), COALESCE(SUM(severity = 'high'), 0),
+			COALESCE(SUM(severity = 'medium'), 0), COALESCE(SUM(severity = 'low'), 0),
+			COALESCE(SUM(severity NOT IN ('critical', 'high', 'medium', 'low')), 0), COUNT(*)
+		FROM (
+			SELECT f.severity, f.state,
+				ROW_NUMBER() OVER (PARTITION BY f.fingerprint ORDER BY p.created_at DESC, f.id DESC) AS latest
+			FROM findings f JOIN probes p ON p.id = f.probe_id
+			WHERE p.project_id = ?
+		) WHERE latest = 1 AND state IN (?, ?)`, s.ID, StateOpen, StateInProgress).
+		Scan(severityCountFields(&s.OpenFindings)...)
+}
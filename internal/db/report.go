@@ -0,0 +1,245 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Report is the stored body of a probe's report.
+type Report struct {
+	ProbeID  string `json:"probe_id"`
+	SHA256   string `json:"sha256"`
+	Size     int64  `json:"size"`
+	Content  string `json:"content"`
+	StoredAt string `json:"stored_at"`
+}
+
+// Report check statuses.
+const (
+	// ReportOK: the file matches the stored report.
+	ReportOK = "ok"
+	// ReportModified: the file differs from the stored report.
+	ReportModified = "modified"
+	// ReportMissing: the file is gone but the report is stored.
+	ReportMissing = "missing"
+	// ReportMoved: the file is gone and an identical copy exists elsewhere.
+	ReportMoved = "moved"
+	// ReportUnstored: the file exists but was never stored.
+	ReportUnstored = "unstored"
+	// ReportLost: neither the file nor a stored copy exists.
+	ReportLost = "lost"
+)
+
+// ReportCheck is the integrity status of one probe's report.
+type ReportCheck struct {
+	ProbeID  string `json:"probe_id"`
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	SHA256   string `json:"sha256,omitempty"`
+	FileHash string `json:"file_sha256,omitempty"`
+	// MovedTo is where an identical copy of a missing file was found.
+	MovedTo string `json:"moved_to,omitempty"`
+}
+
+// HashReport returns the hex SHA-256 of a report body.
+func HashReport(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// SaveReport stores a probe's report body and indexes it for search,
+// replacing any earlier version.
+func SaveReport(db *sql.DB, probeID, content string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveReport(tx, probeID, content); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func saveReport(db querier, probeID, content string) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO reports (probe_id, sha256, size, content) VALUES (?, ?, ?, ?)`,
+		probeID, HashReport(content), len(content), content)
+	if err != nil {
+		return err
+	}
+	return indexReport(db, probeID, content)
+}
+
+// GetReport returns the stored report of a probe, or sql.ErrNoRows if none
+// was stored.
+func GetReport(db *sql.DB, probeID string) (*Report, error) {
+	var r Report
+	err := db.QueryRow(`SELECT probe_id, sha256, size, content, stored_at FROM reports WHERE probe_id = ?`, probeID).
+		Scan(&r.ProbeID, &r.SHA256, &r.Size, &r.Content, &r.StoredAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ReportContent returns a probe's report, preferring the stored copy over
+// the file so a deleted or moved file does not lose it.
+func ReportContent(db *sql.DB, p *Probe) (string, error) {
+	r, err := GetReport(db, p.ID)
+	if err == nil {
+		return r.Content, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	if p.FilePath == "" {
+		return "", fmt.Errorf("probe %s has no report", p.ID)
+	}
+	content, err := os.ReadFile(p.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("report of probe %s is not stored and its file cannot be read: %w", p.ID, err)
+	}
+	return string(content), nil
+}
+
+// storeReports imports the report files of existing probes. Files that can
+// no longer be read are left for CheckReports to flag.
+func storeReports(tx *sql.Tx) error {
+	paths, err := reportPaths(tx)
+	if err != nil {
+		return err
+	}
+	for id, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := saveReport(tx, id, string(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportPaths maps each probe with a report file to its path.
+func reportPaths(db querier) (map[string]string, error) {
+	rows, err := db.Query(`SELECT id, COALESCE(file_path, '') FROM probes WHERE COALESCE(file_path, '') != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make(map[string]string)
+	for rows.Next() {
+		var id, path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return nil, err
+		}
+		paths[id] = path
+	}
+	return paths, rows.Err()
+}
+
+// CheckReports compares every probe's report file with its stored copy.
+// Missing files are looked for by content in searchDirs so moved reports
+// can be relinked.
+func CheckReports(db *sql.DB, searchDirs []string) ([]ReportCheck, error) {
+	rows, err := db.Query(`SELECT p.id, COALESCE(p.file_path, ''), COALESCE(r.sha256, '')
+		FROM probes p LEFT JOIN reports r ON r.probe_id = p.id
+		ORDER BY p.created_at, p.id`)
+	if err != nil {
+		return nil, err
+	}
+	var checks []ReportCheck
+	for rows.Next() {
+		var c ReportCheck
+		if err := rows.Scan(&c.ProbeID, &c.Path, &c.SHA256); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var copies map[string]string
+	for i := range checks {
+		c := &checks[i]
+		if c.Path == "" && c.SHA256 == "" {
+			// Probes that never produced a report
+			c.Status = ReportOK
+			continue
+		}
+
+		content, err := os.ReadFile(c.Path)
+		switch {
+		case err == nil && c.SHA256 == "":
+			c.Status = ReportUnstored
+			c.FileHash = HashReport(string(content))
+		case err == nil:
+			c.FileHash = HashReport(string(content))
+			c.Status = ReportOK
+			if c.FileHash != c.SHA256 {
+				c.Status = ReportModified
+			}
+		case c.SHA256 == "":
+			c.Status = ReportLost
+		default:
+			c.Status = ReportMissing
+			if copies == nil {
+				copies = hashReportFiles(searchDirs)
+			}
+			if path, ok := copies[c.SHA256]; ok {
+				c.Status = ReportMoved
+				c.MovedTo = path
+			}
+		}
+	}
+
+	return checks, nil
+}
+
+// hashReportFiles maps the SHA-256 of every .md file under dirs to its
+// path.
+func hashReportFiles(dirs []string) map[string]string {
+	copies := make(map[string]string)
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			hash := HashReport(string(content))
+			if _, seen := copies[hash]; !seen {
+				copies[hash] = path
+			}
+			return nil
+		})
+	}
+	return copies
+}
+
+// RelinkReport points a probe at a new report file.
+func RelinkReport(db *sql.DB, probeID, path string) error {
+	res, err := db.Exec(`UPDATE probes SET file_path = ? WHERE id = ?`, path, probeID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy decides which probes Prune removes. A probe goes when it
+// is not among the KeepLast most recent probes of its project (of its
+// target, for a probe not linked to a project), or when it is
+// older than MaxAge. Zero fields are no limit. Running probes and probes
+// with an open or in-progress critical finding are always kept.
+type RetentionPolicy struct {
+	KeepLast int
+	MaxAge   time.Duration
+}
+
+// Enabled reports whether the policy limits anything.
+func (p RetentionPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.MaxAge > 0
+}
+
+// PruneCandidate is a probe the retention policy would remove and why.
+type PruneCandidate struct {
+	Probe
+	Reason string `json:"reason"`
+}
+
+// PruneResult lists the probes a prune selected and what deleting them
+// removed.
+type PruneResult struct {
+	Candidates []PruneCandidate `json:"candidates"`
+	*DeleteResult
+}
+
+// PruneCandidates returns the probes policy would remove at now, oldest
+// first.
+func PruneCandidates(db *sql.DB, policy RetentionPolicy, now time.Time) ([]PruneCandidate, error) {
+	candidates := []PruneCandidate{}
+	if !policy.Enabled() {
+		return candidates, nil
+	}
+
+	cutoff := ""
+	if policy.MaxAge > 0 {
+		cutoff = FormatTimestamp(now.Add(-policy.MaxAge))
+	}
+
+	rows, err := db.Query(`SELECT `+probeColumns+`, rank FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY COALESCE('project:' || project_id, 'target:' || target) ORDER BY created_at DESC, id DESC) AS rank
+			FROM probes
+		) ranked
+		WHERE status != 'running'
+		AND ((? > 0 AND rank > ?) OR (? != '' AND created_at < ?))
+		AND NOT EXISTS (
+			SELECT 1 FROM findings f
+			WHERE f.probe_id = ranked.id AND f.severity = 'critical' AND f.state IN (?, ?)
+		)
+		ORDER BY created_at, id`,
+		policy.KeepLast, policy.KeepLast, cutoff, cutoff, StateOpen, StateInProgress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c PruneCandidate
+		var rank int
+		p := &c.Probe
+		if err := rows.Scan(append(probeFields(p), &rank)...); err != nil {
+			return nil, err
+		}
+		if policy.KeepLast > 0 && rank > policy.KeepLast {
+			c.Reason = fmt.Sprintf("not among the last %d probes of its project", policy.KeepLast)
+		} else {
+			c.Reason = fmt.Sprintf("older than %d days", int(policy.MaxAge.Hours()/24))
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// Prune deletes the probes policy selects, with their findings and files.
+// With dryRun nothing is changed.
+func Prune(db *sql.DB, policy RetentionPolicy, now time.Time, dryRun bool) (*PruneResult, error) {
+	candidates, err := PruneCandidates(db, policy, now)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, c.ID)
+	}
+
+	deleted, err := DeleteProbes(db, ids, dryRun)
+	if deleted == nil {
+		return nil, err
+	}
+	return &PruneResult{Candidates: candidates, DeleteResult: deleted}, err
+}
@@ -0,0 +1,465 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Search hit kinds.
+const (
+	SearchFinding = "finding"
+	SearchReport  = "report"
+)
+
+// SearchQuery is a full-text search with optional filters. A severity
+// filter only matches findings, so it leaves out report hits.
+type SearchQuery struct {
+	Query    string
+	Severity []string
+	Target   string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// SearchHit is one finding or report matching a search. Snippet is an
+// excerpt with the matched terms wrapped in <mark> and </mark>.
+type SearchHit struct {
+	Kind      string  `json:"kind"`
+	ID        string  `json:"id"`
+	ProbeID   string  `json:"probe_id"`
+	Title     string  `json:"title"`
+	Snippet   string  `json:"snippet"`
+	Severity  string  `json:"severity,omitempty"`
+	Target    string  `json:"target"`
+	CreatedAt string  `json:"created_at"`
+	Rank      float64 `json:"rank"`
+}
+
+// DefaultSearchLimit caps a search that does not set a limit.
+const DefaultSearchLimit = 50
+
+// searchTableFTS5 is the full-text index. SQLite only has FTS5 when the
+// driver is built with the sqlite_fts5 tag; without it searchTablePlain
+// takes its place and Search falls back to LIKE matching. The two builds
+// can share a database, so Migrate rebuilds the index whenever its type
+// does not match the running build.
+const (
+	searchTableFTS5 = `CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+	title,
+	body,
+	kind UNINDEXED,
+	ref_id UNINDEXED,
+	probe_id UNINDEXED,
+	tokenize = 'porter unicode61'
+)`
+	searchTablePlain = `CREATE TABLE IF NOT EXISTS search_index (
+	title TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL DEFAULT '',
+	kind TEXT NOT NULL,
+	ref_id TEXT NOT NULL,
+	probe_id TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_search_index_ref ON search_index(kind, ref_id)`
+)
+
+// searchTriggers keep findings in sync with the index. Reports are indexed
+// by SaveReport when a probe finishes and backfilled by migration 5.
+const searchTriggers = `
+CREATE TRIGGER IF NOT EXISTS findings_search_insert AFTER INSERT ON findings BEGIN
+	INSERT INTO search_index (title, body, kind, ref_id, probe_id)
+	VALUES (new.text, new.description || char(10) || new.remediation || char(10) || new.file || ' ' || new.cwe || ' ' || new.owasp,
+		'finding', new.id, new.probe_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS findings_search_update
+AFTER UPDATE OF text, description, remediation, file, cwe, owasp ON findings BEGIN
+	DELETE FROM search_index WHERE kind = 'finding' AND ref_id = old.id;
+	INSERT INTO search_index (title, body, kind, ref_id, probe_id)
+	VALUES (new.text, new.description || char(10) || new.remediation || char(10) || new.file || ' ' || new.cwe || ' ' || new.owasp,
+		'finding', new.id, new.probe_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS findings_search_delete AFTER DELETE ON findings BEGIN
+	DELETE FROM search_index WHERE kind = 'finding' AND ref_id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS probes_search_delete AFTER DELETE ON probes BEGIN
+	DELETE FROM search_index WHERE kind = 'report' AND ref_id = old.id;
+END;
+
+INSERT INTO search_index (title, body, kind, ref_id, probe_id)
+SELECT text, description || char(10) || remediation || char(10) || file || ' ' || cwe || ' ' || owasp, 'finding', id, probe_id
+FROM findings;
+`
+
+// createSearchIndex creates the search index over findings, using FTS5
+// when SQLite has it and a plain table otherwise.
+func createSearchIndex(tx *sql.Tx) error {
+	fts, err := hasFTS5(tx)
+	if err != nil {
+		return err
+	}
+	table := searchTablePlain
+	if fts {
+		table = searchTableFTS5
+	}
+	if _, err := tx.Exec(table); err != nil {
+		return err
+	}
+	_, err = tx.Exec(searchTriggers)
+	return err
+}
+
+// hasFTS5 reports whether the running SQLite was built with FTS5.
+func hasFTS5(db querier) (bool, error) {
+	var used bool
+	err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&used)
+	return used, err
+}
+
+// searchIndexMismatch reports whether the search index exists and is not
+// the type this build creates, and whether it is an FTS5 table.
+func searchIndexMismatch(db querier) (mismatch, fts bool, err error) {
+	var schema string
+	err = db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'search_index'`).Scan(&schema)
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	fts = strings.Contains(strings.ToLower(schema), "using fts5")
+	supported, err := hasFTS5(db)
+	if err != nil {
+		return false, false, err
+	}
+	return fts != supported, fts, nil
+}
+
+// reconcileSearchIndex rebuilds a search index created by a build with
+// the other kind of index: an FTS5 index breaks every finding write in a
+// build without FTS5, and a plain index never gains full-text search.
+func reconcileSearchIndex(db *sql.DB) error {
+	if mismatch, _, err := searchIndexMismatch(db); err != nil || !mismatch {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Another process may have rebuilt it while this one waited for the lock.
+	mismatch, fts, err := searchIndexMismatch(tx)
+	if err != nil || !mismatch {
+		return err
+	}
+	if err := dropSearchIndex(tx, fts); err != nil {
+		return err
+	}
+	if err := createSearchIndex(tx); err != nil {
+		return err
+	}
+	if err := reindexReports(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// reindexReports indexes the stored reports, or the report files of a
+// database from before reports were stored.
+func reindexReports(tx *sql.Tx) error {
+	var stored int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'reports'`).Scan(&stored); err != nil {
+		return err
+	}
+	if stored == 0 {
+		return indexReports(tx)
+	}
+
+	rows, err := tx.Query(`SELECT probe_id, content FROM reports`)
+	if err != nil {
+		return err
+	}
+	reports := make(map[string]string)
+	for rows.Next() {
+		var id, content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		reports[id] = content
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, content := range reports {
+		if err := indexReport(tx, id, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ftsShadowTables are the tables FTS5 keeps the search_index content in.
+var ftsShadowTables = []string{"data", "idx", "content", "docsize", "config"}
+
+// dropSearchIndex drops the search index and its triggers. DROP TABLE on
+// an FTS5 table needs the FTS5 module, so without it the shadow tables are
+// dropped as ordinary tables and the virtual table's schema row deleted.
+func dropSearchIndex(tx *sql.Tx, fts bool) error {
+	for _, trigger := range []string{"findings_search_insert", "findings_search_update", "findings_search_delete", "probes_search_delete"} {
+		if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + trigger); err != nil {
+			return err
+		}
+	}
+	if !fts {
+		_, err := tx.Exec(`DROP TABLE search_index`)
+		return err
+	}
+
+	for _, shadow := range ftsShadowTables {
+		if _, err := tx.Exec(`DROP TABLE IF EXISTS search_index_` + shadow); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`PRAGMA writable_schema = ON`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM sqlite_master WHERE type = 'table' AND name = 'search_index'`); err != nil {
+		return err
+	}
+	// RESET also reloads this connection's copy of the schema, which still
+	// has the FTS5 table in it.
+	_, err := tx.Exec(`PRAGMA writable_schema = RESET`)
+	return err
+}
+
+// fullTextSearch reports whether the search index is an FTS5 table.
+func fullTextSearch(db querier) (bool, error) {
+	var schema string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'search_index'`).Scan(&schema)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(strings.ToLower(schema), "using fts5"), nil
+}
+
+// indexReport replaces the indexed report content of a probe. Findings are
+// indexed by triggers; reports are indexed when SaveReport stores them.
+func indexReport(db querier, probeID, content string) error {
+	if _, err := db.Exec(`DELETE FROM search_index WHERE kind = ? AND ref_id = ?`, SearchReport, probeID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	_, err := db.Exec(`INSERT INTO search_index (title, body, kind, ref_id, probe_id) VALUES ('', ?, ?, ?, ?)`,
+		content, SearchReport, probeID, probeID)
+	return err
+}
+
+// indexReports backfills the index with the reports of existing probes.
+// Reports that can no longer be read are skipped.
+func indexReports(tx *sql.Tx) error {
+	paths, err := reportPaths(tx)
+	if err != nil {
+		return err
+	}
+	for id, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := indexReport(tx, id, string(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search runs q against finding text and report content, best matches
+// first. Without FTS5, every word is matched as a substring and the newest
+// probes come first.
+func Search(db *sql.DB, q SearchQuery) ([]SearchHit, error) {
+	terms := searchTerms(q.Query)
+	if len(terms) == 0 {
+		return []SearchHit{}, nil
+	}
+
+	fts, err := fullTextSearch(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []interface{}
+	if fts {
+		where = append(where, "search_index MATCH ?")
+		args = append(args, MatchExpression(q.Query))
+	} else {
+		for _, t := range terms {
+			where = append(where, `(s.title LIKE ? ESCAPE '\' OR s.body LIKE ? ESCAPE '\')`)
+			pattern := "%" + likeEscape(t.word) + "%"
+			args = append(args, pattern, pattern)
+		}
+	}
+	if len(q.Severity) > 0 {
+		where = append(where, "f.severity IN (?"+strings.Repeat(", ?", len(q.Severity)-1)+")")
+		for _, s := range q.Severity {
+			args = append(args, s)
+		}
+	}
+	if q.Target != "" {
+		where = append(where, "p.target = ?")
+		args = append(args, q.Target)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "p.created_at >= ?")
+		args = append(args, FormatTimestamp(q.Since))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "p.created_at < ?")
+		args = append(args, FormatTimestamp(q.Until))
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	args = append(args, limit)
+
+	snippet, rank, order := "snippet(search_index, -1, '<mark>', '</mark>', '…', 16)", "s.rank", "s.rank"
+	if !fts {
+		snippet, rank, order = "CASE WHEN s.kind = 'report' THEN s.body ELSE s.title || char(10) || s.body END", "0", "p.created_at DESC"
+	}
+
+	rows, err := db.Query(`SELECT s.kind, s.ref_id, s.probe_id,
+		CASE WHEN s.kind = 'report' THEN p.id ELSE s.title END,
+		`+snippet+`,
+		COALESCE(f.severity, ''), p.target, p.created_at, `+rank+`
+		FROM search_index s
+		JOIN probes p ON p.id = s.probe_id
+		LEFT JOIN findings f ON s.kind = 'finding' AND f.id = s.ref_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+order+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []SearchHit{}
+	for rows.Next() {
+		var h SearchHit
+		if err := rows.Scan(&h.Kind, &h.ID, &h.ProbeID, &h.Title, &h.Snippet, &h.Severity, &h.Target, &h.CreatedAt, &h.Rank); err != nil {
+			return nil, err
+		}
+		if !fts {
+			h.Snippet = highlight(h.Snippet, terms)
+		}
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// searchTerm is one word of a search. A prefix term was written with a
+// trailing *.
+type searchTerm struct {
+	word   string
+	prefix bool
+}
+
+func searchTerms(text string) []searchTerm {
+	var terms []searchTerm
+	for _, word := range strings.Fields(text) {
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.TrimRight(word, "*")
+		if word == "" {
+			continue
+		}
+		terms = append(terms, searchTerm{word: word, prefix: prefix})
+	}
+	return terms
+}
+
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// highlight cuts an excerpt of about the length FTS5 snippets have around
+// the first matched word of text, wrapping each matched word in <mark> and
+// </mark> the way snippet() does.
+func highlight(text string, terms []searchTerm) string {
+	const before, after = 40, 120
+
+	lower := strings.ToLower(text)
+	first := -1
+	for _, t := range terms {
+		if i := strings.Index(lower, strings.ToLower(t.word)); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	if first < 0 || first > len(text) {
+		first = 0
+	}
+
+	start, end := first-before, first+after
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	excerpt := strings.Join(strings.Fields(text[start:end]), " ")
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	lowerExcerpt := strings.ToLower(excerpt)
+	for i := 0; i < len(excerpt); {
+		matched := 0
+		for _, t := range terms {
+			if w := strings.ToLower(t.word); i < len(lowerExcerpt) && strings.HasPrefix(lowerExcerpt[i:], w) && len(w) > matched && i+len(w) <= len(excerpt) {
+				matched = len(w)
+			}
+		}
+		if matched == 0 {
+			b.WriteByte(excerpt[i])
+			i++
+			continue
+		}
+		b.WriteString("<mark>" + excerpt[i:i+matched] + "</mark>")
+		i += matched
+	}
+	b.WriteString(suffix)
+	return b.String()
+}
+
+// MatchExpression turns free text into an FTS5 query that matches rows
+// containing every word. Words are quoted so punctuation such as the dash in
+// "path-traversal" is not read as query syntax; a trailing * keeps prefix
+// matching.
+func MatchExpression(text string) string {
+	var terms []string
+	for _, t := range searchTerms(text) {
+		term := `"` + strings.ReplaceAll(t.word, `"`, `""`) + `"`
+		if t.prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,41 @@
+package db
+
+import "database/sql"
+
+// Snippet is the source around a finding, captured when the probe ran so
+// the finding stays readable after the code changes.
+type Snippet struct {
+	FindingID  string `json:"finding_id"`
+	File       string `json:"file"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	LineStart  int    `json:"line_start"`
+	LineEnd    int    `json:"line_end"`
+	Content    string `json:"content"`
+	Commit     string `json:"commit"`
+	CapturedAt string `json:"captured_at"`
+}
+
+// SaveSnippet stores the snippet of a finding, replacing any earlier one.
+func SaveSnippet(db *sql.DB, s *Snippet) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO finding_snippets (finding_id, file, start_line, end_line, content, commit_hash)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		s.FindingID, s.File, s.StartLine, s.EndLine, s.Content, s.Commit)
+	return err
+}
+
+// GetSnippet returns the snippet of a finding, with the finding's own line
+// range so clients can highlight it. It returns sql.ErrNoRows when none was
+// captured.
+func GetSnippet(db *sql.DB, findingID string) (*Snippet, error) {
+	var s Snippet
+	err := db.QueryRow(`SELECT s.finding_id, s.file, s.start_line, s.end_line, s.content, s.commit_hash, s.captured_at,
+			f.line_start, f.line_end
+		FROM finding_snippets s JOIN findings f ON f.id = s.finding_id
+		WHERE s.finding_id = ?`, findingID).
+		Scan(&s.FindingID, &s.File, &s.StartLine, &s.EndLine, &s.Content, &s.Commit, &s.CapturedAt, &s.LineStart, &s.LineEnd)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// Statements on the hot write paths, such as probe updates, finding
+// inserts and history entries, are prepared once per database and reused.
+// database/sql prepares a statement again on each pooled connection it
+// runs on, so they are safe to share between goroutines. Close drops a
+// database's statements along with it.
+var (
+	stmtMu    sync.Mutex
+	stmtCache = make(map[*sql.DB]map[string]*sql.Stmt)
+)
+
+// prepared returns the cached statement for query on db, preparing it on
+// first use.
+func prepared(db *sql.DB, query string) (*sql.Stmt, error) {
+	stmtMu.Lock()
+	defer stmtMu.Unlock()
+
+	stmts := stmtCache[db]
+	if stmt, ok := stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	if stmts == nil {
+		stmts = make(map[string]*sql.Stmt)
+		stmtCache[db] = stmts
+	}
+	stmts[query] = stmt
+	return stmt, nil
+}
+
+// execPrepared runs query on db through its cached statement.
+func execPrepared(db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := prepared(db, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Exec(args...)
+}
+
+// txPrepared returns the cached statement for query on db, bound to tx.
+// The bound statement is closed with the transaction.
+func txPrepared(db *sql.DB, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	stmt, err := prepared(db, query)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Stmt(stmt), nil
+}
+
+// Close closes db and the statements cached for it. Databases opened with
+// Open or InitDB are closed with it rather than db.Close, so the cache
+// does not keep them alive.
+func Close(db *sql.DB) error {
+	stmtMu.Lock()
+	for _, stmt := range stmtCache[db] {
+		stmt.Close()
+	}
+	delete(stmtCache, db)
+	stmtMu.Unlock()
+
+	return db.Close()
+}
@@ -0,0 +1,298 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Comment is a note left on a finding during triage. Replies point at the
+// comment they answer through ParentID.
+type Comment struct {
+	ID        int64  `json:"id"`
+	FindingID string `json:"finding_id"`
+	ParentID  int64  `json:"parent_id,omitempty"`
+	Author    string `json:"author"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+}
+
+// FindingFilter narrows ListFindings. Empty fields match everything.
+type FindingFilter struct {
+	ProbeID  string
+	Severity string
+	State    string
+	Tag      string
+	Assignee string
+}
+
+// AddComment appends a comment to a finding.
+func AddComment(db *sql.DB, findingID string, parentID int64, author, body string) (*Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("comment body is required")
+	}
+
+	if _, err := GetFinding(db, findingID); err != nil {
+		return nil, err
+	}
+
+	var parent interface{}
+	if parentID != 0 {
+		var owner string
+		if err := db.QueryRow(`SELECT finding_id FROM finding_comments WHERE id = ?`, parentID).Scan(&owner); err != nil || owner != findingID {
+			return nil, fmt.Errorf("parent comment %d not found on this finding", parentID)
+		}
+		parent = parentID
+	}
+
+	res, err := db.Exec(`INSERT INTO finding_comments (finding_id, parent_id, author, body) VALUES (?, ?, ?, ?)`,
+		findingID, parent, author, body)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return getComment(db, id)
+}
+
+func getComment(db *sql.DB, id int64) (*Comment, error) {
+	var c Comment
+	var parent sql.NullInt64
+	err := db.QueryRow(`SELECT id, finding_id, parent_id, author, body, created_at FROM finding_comments WHERE id = ?`, id).
+		Scan(&c.ID, &c.FindingID, &parent, &c.Author, &c.Body, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.ParentID = parent.Int64
+	return &c, nil
+}
+
+// GetComments returns the comments on a finding, oldest first.
+func GetComments(db *sql.DB, findingID string) ([]Comment, error) {
+	rows, err := db.Query(`SELECT id, finding_id, parent_id, author, body, created_at
+		FROM finding_comments WHERE finding_id = ? ORDER BY id ASC`, findingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		var c Comment
+		var parent sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.FindingID, &parent, &c.Author, &c.Body, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.ParentID = parent.Int64
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
+
+// DeleteComment removes a comment and its whole thread of replies,
+// including replies to replies.
+func DeleteComment(db *sql.DB, findingID string, id int64) error {
+	res, err := db.Exec(`DELETE FROM finding_comments WHERE id IN (
+			WITH RECURSIVE thread(id) AS (
+				SELECT id FROM finding_comments WHERE finding_id = ? AND id = ?
+				UNION
+				SELECT c.id FROM finding_comments c JOIN thread t ON c.parent_id = t.id
+			)
+			SELECT id FROM thread
+		)`, findingID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetAssignee assigns a finding to someone. An empty assignee unassigns it.
+func SetAssignee(db *sql.DB, findingID, assignee string) error {
+	res, err := db.Exec(`UPDATE findings SET assignee = ? WHERE id = ?`, strings.TrimSpace(assignee), findingID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// NormalizeTag trims and lowercases a tag so "Auth" and "auth " match.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// AddTags attaches tags to a finding. Existing tags are left alone.
+func AddTags(db *sql.DB, findingID string, tags ...string) error {
+	if _, err := GetFinding(db, findingID); err != nil {
+		return err
+	}
+
+	return execTags(db, `INSERT OR IGNORE INTO finding_tags (finding_id, tag) VALUES (?, ?)`, findingID, tags)
+}
+
+// RemoveTags detaches tags from a finding.
+func RemoveTags(db *sql.DB, findingID string, tags ...string) error {
+	return execTags(db, `DELETE FROM finding_tags WHERE finding_id = ? AND tag = ?`, findingID, tags)
+}
+
+// execTags runs query for each normalized tag in one transaction.
+func execTags(db *sql.DB, query, findingID string, tags []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+		if _, err := stmt.Exec(findingID, tag); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetTags returns the tags on a finding in alphabetical order.
+func GetTags(db *sql.DB, findingID string) ([]string, error) {
+	rows, err := db.Query(`SELECT tag FROM finding_tags WHERE finding_id = ? ORDER BY tag ASC`, findingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// ListFindings returns findings across probes matching filter, newest first.
+func ListFindings(db *sql.DB, filter FindingFilter) ([]Finding, error) {
+	var where []string
+	var args []interface{}
+
+	if filter.ProbeID != "" {
+		where = append(where, "probe_id = ?")
+		args = append(args, filter.ProbeID)
+	}
+	if filter.Severity != "" {
+		where = append(where, "severity = ?")
+		args = append(args, filter.Severity)
+	}
+	if filter.State != "" {
+		where = append(where, "state = ?")
+		args = append(args, filter.State)
+	}
+	if filter.Assignee != "" {
+		where = append(where, "assignee = ?")
+		args = append(args, filter.Assignee)
+	}
+	if filter.Tag != "" {
+		where = append(where, "id IN (SELECT finding_id FROM finding_tags WHERE tag = ?)")
+		args = append(args, NormalizeTag(filter.Tag))
+	}
+
+	query := `SELECT ` + findingColumns + ` FROM findings`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id ASC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Finding
+	for rows.Next() {
+		var f Finding
+		if err := scanFinding(rows, &f); err != nil {
+			return nil, err
+		}
+		results = append(results, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := attachTags(db, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// attachTags loads the tags of every finding, batching the IN clause to stay
+// under SQLite's bound parameter limit.
+func attachTags(db *sql.DB, list []Finding) error {
+	const batch = 500
+
+	index := make(map[string]int, len(list))
+	for i := range list {
+		list[i].Tags = []string{}
+		index[list[i].ID] = i
+	}
+
+	for start := 0; start < len(list); start += batch {
+		end := start + batch
+		if end > len(list) {
+			end = len(list)
+		}
+
+		placeholders := make([]string, 0, end-start)
+		args := make([]interface{}, 0, end-start)
+		for _, f := range list[start:end] {
+			placeholders = append(placeholders, "?")
+			args = append(args, f.ID)
+		}
+
+		rows, err := db.Query(`SELECT finding_id, tag FROM finding_tags WHERE finding_id IN (`+
+			strings.Join(placeholders, ",")+`) ORDER BY tag ASC`, args...)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var id, tag string
+			if err := rows.Scan(&id, &tag); err != nil {
+				rows.Close()
+				return err
+			}
+			if i, ok := index[id]; ok {
+				list[i].Tags = append(list[i].Tags, tag)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
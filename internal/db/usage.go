@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UsageGroups maps the accepted --by values to their probes column.
+var UsageGroups = map[string]string{
+	"provider": "provider",
+	"model":    "model",
+	"target":   "target",
+}
+
+// UsageSummary is the aggregated usage for one group of probes.
+type UsageSummary struct {
+	Key          string  `json:"key"`
+	Probes       int     `json:"probes"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	DurationMS   int64   `json:"duration_ms"`
+	NumTurns     int64   `json:"num_turns"`
+}
+
+// GetUsage aggregates probe usage grouped by provider, model or target.
+// Probes created before since are ignored; a zero since includes everything.
+func GetUsage(db *sql.DB, groupBy string, since time.Time) ([]UsageSummary, error) {
+	col, ok := UsageGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group %q (expected provider, model or target)", groupBy)
+	}
+
+	query := fmt.Sprintf(`SELECT COALESCE(%[1]s, ''), COUNT(*), COALESCE(SUM(input_tokens), 0),
+		COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_usd), 0),
+		COALESCE(SUM(duration_ms), 0), COALESCE(SUM(num_turns), 0)
+		FROM probes WHERE created_at >= ? GROUP BY %[1]s ORDER BY SUM(cost_usd) DESC, %[1]s ASC`, col)
+
+	rows, err := db.Query(query, FormatTimestamp(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []UsageSummary
+	for rows.Next() {
+		var s UsageSummary
+		if err := rows.Scan(&s.Key, &s.Probes, &s.InputTokens, &s.OutputTokens, &s.CostUSD, &s.DurationMS, &s.NumTurns); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// FormatTimestamp formats t the way SQLite's CURRENT_TIMESTAMP stores it.
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// ParseSince converts a relative window such as "30d", "2w" or "12h" into
+// the absolute time that far in the past. A date such as "2025-01-31" is
+// taken as local midnight. An empty string means no limit.
+func ParseSince(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+
+	unit := s[len(s)-1]
+	switch unit {
+	case 'd', 'w':
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid duration %q", s)
+		}
+		days := n
+		if unit == 'w' {
+			days = n * 7
+		}
+		return time.Now().AddDate(0, 0, -days), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid duration %q", s)
+	}
+	return time.Now().Add(-d), nil
+}
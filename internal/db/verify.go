@@ -0,0 +1,113 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ndzuma/probeTool/internal/findings"
+)
+
+// Verification is the outcome of re-checking a single finding with the
+// agent.
+type Verification struct {
+	ID        int64   `json:"id"`
+	FindingID string  `json:"finding_id"`
+	Verdict   string  `json:"verdict"`
+	Reasoning string  `json:"reasoning"`
+	Provider  string  `json:"provider"`
+	Model     string  `json:"model"`
+	CostUSD   float64 `json:"cost_usd"`
+	CreatedAt string  `json:"created_at"`
+}
+
+const verificationColumns = `id, finding_id, verdict, reasoning, provider, model, cost_usd, created_at`
+
+// verifiedState is the state a finding moves to after a verification. A
+// fixed verdict closes an open finding; a finding marked fixed that is
+// still present is reopened. Every other state and verdict is left alone,
+// so a verification never overrides a triage decision.
+func verifiedState(current, verdict string) string {
+	switch {
+	case verdict == findings.VerdictFixed && current == StateOpen:
+		return StateFixed
+	case verdict == findings.VerdictStillPresent && current == StateFixed:
+		return StateOpen
+	}
+	return current
+}
+
+// RecordVerification stores a verification, updates the finding's state to
+// match the verdict and adds an entry to its history that links back to the
+// verification, even when the state does not change.
+func RecordVerification(db *sql.DB, v *Verification, actor string) (*StateChange, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.QueryRow(`SELECT state FROM findings WHERE id = ?`, v.FindingID).Scan(&current); err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO finding_verifications (finding_id, verdict, reasoning, provider, model, cost_usd) VALUES (?, ?, ?, ?, ?, ?)`,
+		v.FindingID, v.Verdict, v.Reasoning, v.Provider, v.Model, v.CostUSD)
+	if err != nil {
+		return nil, err
+	}
+	if v.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRow(`SELECT created_at FROM finding_verifications WHERE id = ?`, v.ID).Scan(&v.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	next := verifiedState(current, v.Verdict)
+	if next != current {
+		update, err := txPrepared(db, tx, updateFindingState)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := update.Exec(next, isClosedState(next), v.FindingID); err != nil {
+			return nil, err
+		}
+	}
+
+	reason := fmt.Sprintf("Verification: %s", strings.ReplaceAll(v.Verdict, "_", " "))
+	if v.Reasoning != "" {
+		reason += " — " + v.Reasoning
+	}
+
+	change, err := insertStateChange(db, tx, v.FindingID, current, next, actor, reason, v.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// GetVerifications returns the verifications of a finding, newest first.
+func GetVerifications(db *sql.DB, findingID string) ([]Verification, error) {
+	rows, err := db.Query(`SELECT `+verificationColumns+` FROM finding_verifications WHERE finding_id = ? ORDER BY id DESC`, findingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Verification
+	for rows.Next() {
+		var v Verification
+		if err := rows.Scan(&v.ID, &v.FindingID, &v.Verdict, &v.Reasoning, &v.Provider, &v.Model, &v.CostUSD, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+
+	return list, rows.Err()
+}
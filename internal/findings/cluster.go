@@ -0,0 +1,174 @@
+package findings
+
+import "strings"
+
+// DefaultSimilarity is the shingle Jaccard similarity at or above which two
+// findings in the same file are treated as one issue reported twice.
+const DefaultSimilarity = 0.5
+
+// shingleSize is the length of the character shingles compared between
+// titles. Character shingles tolerate "hardcoded" vs "hard-coded" and
+// "config" vs "configuration" where word shingles do not.
+const shingleSize = 3
+
+// lineSlack is how far apart two line ranges may be and still describe the
+// same code.
+const lineSlack = 10
+
+var severityRank = map[string]int{
+	"info":     0,
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// shingleStopWords carry no meaning in a finding title.
+var shingleStopWords = wordSet("a an the in of on to for and is are via with at from by")
+
+// Consolidate merges near-duplicate findings: reports often restate an
+// issue under a different heading in different words. Two findings merge
+// when they point at the same file (and nearby lines, when both give lines)
+// and the Jaccard similarity of their title shingles is at least threshold.
+// The first occurrence is kept, takes the highest severity and any fields it
+// lacks, and lists the other titles in Aliases. A threshold of zero uses
+// DefaultSimilarity; one above 1 disables merging.
+func Consolidate(list []Finding, threshold float64) []Finding {
+	if threshold <= 0 {
+		threshold = DefaultSimilarity
+	}
+	if threshold > 1 || len(list) < 2 {
+		return list
+	}
+
+	var merged []Finding
+	var shingles []map[string]bool
+
+	for _, f := range list {
+		s := Shingles(f.Text)
+		match := -1
+		for i := range merged {
+			if sameLocation(merged[i], f) && Jaccard(shingles[i], s) >= threshold {
+				match = i
+				break
+			}
+		}
+
+		if match == -1 {
+			merged = append(merged, f)
+			shingles = append(shingles, s)
+			continue
+		}
+
+		absorb(&merged[match], f)
+		for k := range s {
+			shingles[match][k] = true
+		}
+	}
+
+	return merged
+}
+
+// Similarity is the Jaccard similarity of the titles of two findings from
+// different reports when they could be the same issue: in the same file and
+// category. Line numbers are not compared, since code moves between probes.
+// Otherwise it is zero.
+func Similarity(a, b Finding) float64 {
+	if a.File == "" || normalizeFile(a.File) != normalizeFile(b.File) {
+		return 0
+	}
+	if !strings.EqualFold(Category(a.CWE, a.OWASP), Category(b.CWE, b.OWASP)) {
+		return 0
+	}
+	return Jaccard(Shingles(a.Text), Shingles(b.Text))
+}
+
+// Shingles returns the character shingles of a finding title, ignoring case,
+// punctuation and stop words.
+func Shingles(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(NormalizeText(text)) {
+		if shingleStopWords[word] {
+			continue
+		}
+		padded := " " + word + " "
+		if len(padded) <= shingleSize {
+			set[padded] = true
+			continue
+		}
+		for i := 0; i+shingleSize <= len(padded); i++ {
+			set[padded[i:i+shingleSize]] = true
+		}
+	}
+	return set
+}
+
+// Jaccard is the size of the intersection of a and b over their union.
+func Jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func sameLocation(a, b Finding) bool {
+	if a.File == "" || normalizeFile(a.File) != normalizeFile(b.File) {
+		return false
+	}
+	if a.LineStart == 0 || b.LineStart == 0 {
+		return true
+	}
+	return a.LineStart <= lastLine(b)+lineSlack && b.LineStart <= lastLine(a)+lineSlack
+}
+
+func lastLine(f Finding) int {
+	if f.LineEnd > f.LineStart {
+		return f.LineEnd
+	}
+	return f.LineStart
+}
+
+// absorb folds duplicate d into f.
+func absorb(f *Finding, d Finding) {
+	if severityRank[d.Severity] > severityRank[f.Severity] {
+		f.Severity = d.Severity
+	}
+	if f.LineStart == 0 {
+		f.LineStart, f.LineEnd = d.LineStart, d.LineEnd
+	}
+	if f.CWE == "" {
+		f.CWE = d.CWE
+	}
+	if f.OWASP == "" {
+		f.OWASP = d.OWASP
+	}
+	if f.Description == "" {
+		f.Description = d.Description
+	}
+	if f.Remediation == "" {
+		f.Remediation = d.Remediation
+	}
+
+	for _, alias := range append([]string{d.Text}, d.Aliases...) {
+		f.addAlias(alias)
+	}
+}
+
+func (f *Finding) addAlias(alias string) {
+	key := NormalizeText(alias)
+	if key == NormalizeText(f.Text) {
+		return
+	}
+	for _, a := range f.Aliases {
+		if NormalizeText(a) == key {
+			return
+		}
+	}
+	f.Aliases = append(f.Aliases, alias)
+}
@@ -0,0 +1,79 @@
+package findings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJaccard(t *testing.T) {
+	same := Shingles("SQL injection in login handler")
+	if got := Jaccard(same, Shingles("sql-injection in the LOGIN handler!")); got != 1 {
+		t.Errorf("Jaccard() of cosmetically different titles = %v, want 1", got)
+	}
+	if got := Jaccard(same, Shingles("Debug mode enabled")); got > 0.1 {
+		t.Errorf("Jaccard() of unrelated titles = %v, want close to 0", got)
+	}
+	if got := Jaccard(Shingles(""), Shingles("")); got != 0 {
+		t.Errorf("Jaccard() of empty sets = %v, want 0", got)
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	list := []Finding{
+		{Text: "SQL injection in login handler", Severity: "high", File: "src/login.js", LineStart: 42},
+		{Text: "SQL Injection vulnerability in the login handler", Severity: "critical", File: "./src/login.js", LineStart: 44, CWE: "CWE-89"},
+		{Text: "SQL injection in login handler", Severity: "low", File: "src/login.js", LineStart: 300},
+		{Text: "SQL injection in login handler", Severity: "low"},
+		{Text: "SQL injection in login handler", Severity: "low"},
+		{Text: "Hardcoded API key in config", Severity: "high", File: "config.js"},
+		{Text: "API key hard-coded in configuration file", Severity: "medium", File: "config.js", LineStart: 3},
+	}
+
+	got := Consolidate(list, 0)
+
+	var texts []string
+	for _, f := range got {
+		texts = append(texts, f.Text)
+	}
+	want := []string{
+		"SQL injection in login handler",
+		"SQL injection in login handler",
+		"SQL injection in login handler",
+		"SQL injection in login handler",
+		"Hardcoded API key in config",
+	}
+	if !reflect.DeepEqual(texts, want) {
+		t.Fatalf("Consolidate() kept %q, want %q", texts, want)
+	}
+
+	first := got[0]
+	if first.Severity != "critical" || first.CWE != "CWE-89" || first.LineStart != 42 {
+		t.Errorf("Merged finding should keep its location and take the highest severity and missing fields, got %+v", first)
+	}
+	if !reflect.DeepEqual(first.Aliases, []string{"SQL Injection vulnerability in the login handler"}) {
+		t.Errorf("Aliases = %q", first.Aliases)
+	}
+	if got[1].Aliases != nil || got[2].Aliases != nil {
+		t.Error("Findings far apart or without a file should not merge")
+	}
+	if got[4].LineStart != 3 || got[4].Severity != "high" {
+		t.Errorf("Merged finding should pick up the duplicate's lines, got %+v", got[4])
+	}
+}
+
+func TestConsolidateThreshold(t *testing.T) {
+	list := []Finding{
+		{Text: "Hardcoded API key in config", Severity: "high", File: "config.js"},
+		{Text: "API key hard-coded in configuration file", Severity: "medium", File: "config.js"},
+	}
+
+	if got := Consolidate(list, 0.9); len(got) != 2 {
+		t.Errorf("A strict threshold should keep both findings, got %d", len(got))
+	}
+	if got := Consolidate(list, 2); len(got) != 2 {
+		t.Errorf("A threshold above 1 should disable merging, got %d", len(got))
+	}
+	if got := Consolidate(list, 0.3); len(got) != 1 {
+		t.Errorf("A loose threshold should merge, got %d", len(got))
+	}
+}
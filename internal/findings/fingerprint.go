@@ -0,0 +1,63 @@
+package findings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	nonWordPattern     = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+	lineSuffixPattern  = regexp.MustCompile(`(?::\d+)+(?:-\d+)?$`)
+	fingerprintVersion = "v1"
+)
+
+// Fingerprint returns a stable identity for a finding so the same issue can
+// be recognised across probes. It hashes the normalized text, the file the
+// finding points at and its category (CWE, falling back to OWASP).
+//
+// Line numbers and severity are deliberately left out: code moves and the
+// agent re-rates issues between runs, but it is still the same finding.
+func Fingerprint(text, file, category string) string {
+	parts := []string{
+		fingerprintVersion,
+		NormalizeText(text),
+		normalizeFile(file),
+		strings.ToUpper(strings.TrimSpace(category)),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// FingerprintOf computes the fingerprint of a parsed finding.
+func FingerprintOf(f Finding) string {
+	return Fingerprint(f.Text, f.File, Category(f.CWE, f.OWASP))
+}
+
+// Category picks the classification used for fingerprinting.
+func Category(cwe, owasp string) string {
+	if cwe != "" {
+		return cwe
+	}
+	return owasp
+}
+
+// NormalizeText lowercases text and strips markdown and punctuation so
+// cosmetic differences between reports do not change the fingerprint.
+func NormalizeText(text string) string {
+	text = strings.ToLower(text)
+	text = nonWordPattern.ReplaceAllString(text, " ")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func normalizeFile(file string) string {
+	file = strings.TrimSpace(strings.ReplaceAll(file, "\\", "/"))
+	if file == "" {
+		return ""
+	}
+	file = lineSuffixPattern.ReplaceAllString(file, "")
+	file = path.Clean(file)
+	return strings.TrimPrefix(file, "./")
+}
@@ -0,0 +1,66 @@
+package findings
+
+import "testing"
+
+func TestFingerprintStable(t *testing.T) {
+	a := Fingerprint("SQL injection in **login** handler.", "./src/auth/login.js", "CWE-89")
+	b := Fingerprint("sql injection in login handler", "src/auth/login.js:42", "cwe-89")
+
+	if a != b {
+		t.Errorf("Fingerprints should ignore formatting, case and line numbers: %s != %s", a, b)
+	}
+	if len(a) != 64 {
+		t.Errorf("Fingerprint should be a full SHA-256 hex digest, got %d chars", len(a))
+	}
+}
+
+func TestFingerprintDistinguishes(t *testing.T) {
+	base := Fingerprint("SQL injection in login handler", "src/auth/login.js", "CWE-89")
+
+	tests := []struct {
+		name     string
+		text     string
+		file     string
+		category string
+	}{
+		{"different text", "SQL injection in signup handler", "src/auth/login.js", "CWE-89"},
+		{"different file", "SQL injection in login handler", "src/auth/signup.js", "CWE-89"},
+		{"different category", "SQL injection in login handler", "src/auth/login.js", "CWE-564"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Fingerprint(tt.text, tt.file, tt.category) == base {
+				t.Error("Fingerprint should differ")
+			}
+		})
+	}
+}
+
+func TestFingerprintWindowsPaths(t *testing.T) {
+	a := Fingerprint("Hardcoded secret", `src\config\keys.go`, "")
+	b := Fingerprint("Hardcoded secret", "src/config/keys.go", "")
+	if a != b {
+		t.Error("Fingerprint should normalize path separators")
+	}
+}
+
+func TestParseMarkdownFingerprints(t *testing.T) {
+	content := "# Report\n\n## Critical\n- SQL injection in login form allows unauthorized access\n"
+
+	first := ParseMarkdown(content)
+	second := ParseMarkdown(content)
+
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("Expected one finding per parse, got %d and %d", len(first), len(second))
+	}
+	if first[0].Fingerprint == "" {
+		t.Fatal("Parsed finding should have a fingerprint")
+	}
+	if first[0].Fingerprint != second[0].Fingerprint {
+		t.Error("Fingerprint should be identical across runs")
+	}
+	if first[0].ID == second[0].ID {
+		t.Error("IDs should remain unique per occurrence")
+	}
+}
@@ -0,0 +1,66 @@
+package findings
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/reports")
+
+// TestGoldenReports parses every report in testdata/reports and compares the
+// findings with the matching .golden.json file. Run with -update after an
+// intentional parser change and review the diff.
+func TestGoldenReports(t *testing.T) {
+	reports, err := filepath.Glob(filepath.Join("testdata", "reports", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) == 0 {
+		t.Fatal("no reports found in testdata/reports")
+	}
+
+	for _, report := range reports {
+		name := strings.TrimSuffix(filepath.Base(report), ".md")
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(report)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			findings := ParseMarkdown(string(content))
+			for i := range findings {
+				findings[i].ID = ""
+			}
+			if findings == nil {
+				findings = []Finding{}
+			}
+
+			got, err := json.MarshalIndent(findings, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(report, ".md") + ".golden.json"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("findings differ from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package findings
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mdKind identifies a markdown block.
+type mdKind int
+
+const (
+	mdHeading mdKind = iota
+	mdParagraph
+	mdList
+	mdItem
+	mdTable
+	mdCode
+)
+
+// mdNode is one block of a parsed markdown document. Only the block structure
+// the findings walker needs is modelled; inline markup is left in Text.
+type mdNode struct {
+	Kind     mdKind
+	Level    int        // heading level
+	Text     string     // heading, paragraph or code text; leading paragraph of a list item
+	Info     string     // fenced code info string
+	Ordered  bool       // numbered list
+	Rows     [][]string // table cells, header row first
+	Children []*mdNode  // items of a list, or the blocks nested in an item
+}
+
+var (
+	atxHeadingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceOpenPattern  = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+	listMarkerPattern = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	thematicPattern   = regexp.MustCompile(`^ {0,3}([-*_])(?:[ \t]*[-*_]){2,}[ \t]*$`)
+	tableDelimPattern = regexp.MustCompile(`^ *\|? *:?-+:? *(?:\| *:?-+:? *)*\|? *$`)
+)
+
+// parseMarkdownBlocks splits a document into its block-level tree.
+func parseMarkdownBlocks(content string) []*mdNode {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = expandLeadingTabs(line)
+	}
+	return parseBlocks(lines)
+}
+
+func parseBlocks(lines []string) []*mdNode {
+	var nodes []*mdNode
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+		case fenceOpenPattern.MatchString(line):
+			var node *mdNode
+			node, i = parseFence(lines, i)
+			nodes = append(nodes, node)
+		case atxHeadingPattern.MatchString(line):
+			m := atxHeadingPattern.FindStringSubmatch(line)
+			nodes = append(nodes, &mdNode{Kind: mdHeading, Level: len(m[1]), Text: strings.TrimSpace(m[2])})
+			i++
+		case thematicPattern.MatchString(line):
+			i++
+		case isTableStart(lines, i):
+			var node *mdNode
+			node, i = parseTable(lines, i)
+			nodes = append(nodes, node)
+		case listMarkerPattern.MatchString(line):
+			var node *mdNode
+			node, i = parseList(lines, i)
+			nodes = append(nodes, node)
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(t, ">") {
+					break
+				}
+				t = strings.TrimPrefix(t, ">")
+				quoted = append(quoted, strings.TrimPrefix(t, " "))
+			}
+			nodes = append(nodes, parseBlocks(quoted)...)
+		default:
+			para := []string{trimmed}
+			for i++; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) == "" || startsBlock(lines, i) {
+					break
+				}
+				para = append(para, strings.TrimSpace(lines[i]))
+			}
+			nodes = append(nodes, &mdNode{Kind: mdParagraph, Text: strings.Join(para, "\n")})
+		}
+	}
+
+	return nodes
+}
+
+// startsBlock reports whether line i opens a block that interrupts a
+// paragraph or a lazy list continuation.
+func startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	return fenceOpenPattern.MatchString(line) ||
+		atxHeadingPattern.MatchString(line) ||
+		thematicPattern.MatchString(line) ||
+		listMarkerPattern.MatchString(line) ||
+		strings.HasPrefix(strings.TrimSpace(line), ">") ||
+		isTableStart(lines, i)
+}
+
+func parseFence(lines []string, start int) (*mdNode, int) {
+	m := fenceOpenPattern.FindStringSubmatch(lines[start])
+	fence := m[1]
+	node := &mdNode{Kind: mdCode, Info: strings.TrimSpace(m[2])}
+
+	var body []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+			i++
+			break
+		}
+		body = append(body, lines[i])
+	}
+
+	node.Text = strings.Join(body, "\n")
+	return node, i
+}
+
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") {
+		return false
+	}
+	next := lines[i+1]
+	return strings.Contains(next, "|") && strings.Contains(next, "-") && tableDelimPattern.MatchString(next)
+}
+
+func parseTable(lines []string, start int) (*mdNode, int) {
+	node := &mdNode{Kind: mdTable, Rows: [][]string{splitTableRow(lines[start])}}
+
+	i := start + 2
+	for ; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" || !strings.Contains(lines[i], "|") {
+			break
+		}
+		node.Rows = append(node.Rows, splitTableRow(lines[i]))
+	}
+
+	return node, i
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = strings.TrimSuffix(line, "|")
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func parseList(lines []string, start int) (*mdNode, int) {
+	first := listMarkerPattern.FindStringSubmatch(lines[start])
+	indent := len(first[1])
+	list := &mdNode{Kind: mdList, Ordered: isOrderedMarker(first[2])}
+
+	i := start
+	for i < len(lines) {
+		m := listMarkerPattern.FindStringSubmatch(lines[i])
+		if m == nil || len(m[1]) > indent+1 || isOrderedMarker(m[2]) != list.Ordered {
+			break
+		}
+		var item *mdNode
+		item, i = parseItem(lines, i, m)
+		list.Children = append(list.Children, item)
+
+		// A blank line between items keeps the list going; anything else
+		// at the list's indentation ends it.
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			i++
+		}
+	}
+
+	return list, i
+}
+
+// parseItem collects a list item and everything nested under it: lines
+// indented past the marker, plus lazy continuation lines of its paragraph.
+func parseItem(lines []string, start int, m []string) (*mdNode, int) {
+	line := lines[start]
+	markerIndent := len(m[1])
+
+	contentIndent := markerIndent + len(m[2]) + 1
+	if m[3] != "" {
+		k := markerIndent + len(m[2])
+		for k < len(line) && line[k] == ' ' {
+			k++
+		}
+		if k-markerIndent-len(m[2]) <= 4 {
+			contentIndent = k
+		}
+	}
+
+	body := []string{m[3]}
+	blankBefore := false
+	i := start + 1
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if strings.TrimSpace(l) == "" {
+			body = append(body, "")
+			blankBefore = true
+			continue
+		}
+
+		ind := leadingSpaces(l)
+		nested := ind > markerIndent
+		if nested && listMarkerPattern.MatchString(l) {
+			// A marker only one space in is a sibling, not a child.
+			nested = ind >= contentIndent || ind >= markerIndent+2
+		}
+		if nested {
+			if ind > contentIndent {
+				ind = contentIndent
+			}
+			body = append(body, l[ind:])
+			blankBefore = false
+			continue
+		}
+
+		if blankBefore || startsBlock(lines, i) {
+			break
+		}
+		body = append(body, strings.TrimSpace(l))
+	}
+
+	// Hand trailing blank lines back to the caller.
+	for len(body) > 1 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		i--
+	}
+
+	item := &mdNode{Kind: mdItem}
+	children := parseBlocks(body)
+	if m[3] != "" && len(children) > 0 && children[0].Kind == mdParagraph {
+		item.Text = children[0].Text
+		children = children[1:]
+	}
+	item.Children = children
+
+	return item, i
+}
+
+func isOrderedMarker(marker string) bool {
+	return marker != "-" && marker != "*" && marker != "+"
+}
+
+func leadingSpaces(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+func expandLeadingTabs(line string) string {
+	i := 0
+	for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	if !strings.Contains(line[:i], "\t") {
+		return line
+	}
+	return strings.ReplaceAll(line[:i], "\t", "    ") + line[i:]
+}
@@ -0,0 +1,58 @@
+package findings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkdownBlocks(t *testing.T) {
+	content := "# Title\n\nIntro paragraph\nwrapped.\n\n" +
+		"1. First item\n   - nested bullet\n2. Second item\n\n" +
+		"| Severity | Finding |\n|---|---|\n| High | XSS in search |\n\n" +
+		"```js\nconst a = 1\n```\n\n> quoted text\n"
+
+	nodes := parseMarkdownBlocks(content)
+
+	var kinds []mdKind
+	for _, n := range nodes {
+		kinds = append(kinds, n.Kind)
+	}
+	want := []mdKind{mdHeading, mdParagraph, mdList, mdTable, mdCode, mdParagraph}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("block kinds = %v, want %v", kinds, want)
+	}
+
+	if nodes[0].Level != 1 || nodes[0].Text != "Title" {
+		t.Errorf("Unexpected heading: %+v", nodes[0])
+	}
+	if nodes[1].Text != "Intro paragraph\nwrapped." {
+		t.Errorf("Unexpected paragraph: %q", nodes[1].Text)
+	}
+
+	list := nodes[2]
+	if !list.Ordered || len(list.Children) != 2 {
+		t.Fatalf("Expected ordered list with 2 items, got %+v", list)
+	}
+	first := list.Children[0]
+	if first.Text != "First item" || len(first.Children) != 1 || first.Children[0].Kind != mdList {
+		t.Errorf("Nested bullet should be a child list of the first item: %+v", first)
+	}
+
+	if !reflect.DeepEqual(nodes[3].Rows, [][]string{{"Severity", "Finding"}, {"High", "XSS in search"}}) {
+		t.Errorf("Unexpected table rows: %v", nodes[3].Rows)
+	}
+	if nodes[4].Info != "js" || nodes[4].Text != "const a = 1" {
+		t.Errorf("Unexpected code block: %+v", nodes[4])
+	}
+	if nodes[5].Text != "quoted text" {
+		t.Errorf("Unexpected quote: %q", nodes[5].Text)
+	}
+}
+
+func TestSplitTableRow(t *testing.T) {
+	got := splitTableRow(`| a | b \| c | d |`)
+	want := []string{"a", "b | c", "d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitTableRow() = %q, want %q", got, want)
+	}
+}
@@ -2,123 +2,745 @@ package findings
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/google/uuid"
 )
 
 type Finding struct {
-	ID       string `json:"id"`
-	Text     string `json:"text"`
-	Severity string `json:"severity"`
+	ID          string `json:"id"`
+	Fingerprint string `json:"fingerprint"`
+	Text        string `json:"text"`
+	Severity    string `json:"severity"`
+	File        string `json:"file,omitempty"`
+	LineStart   int    `json:"line_start,omitempty"`
+	LineEnd     int    `json:"line_end,omitempty"`
+	CWE         string `json:"cwe,omitempty"`
+	OWASP       string `json:"owasp,omitempty"`
+	Description string `json:"description,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+	// Aliases are the titles of near-duplicates merged into this finding.
+	Aliases []string `json:"aliases,omitempty"`
 }
 
-var severityPatterns = map[string]*regexp.Regexp{
-	"critical": regexp.MustCompile(`(?i)(?:critical|severe)\s*[:\-]?\s*(.+?)(?:\n|$)`),
-	"high":     regexp.MustCompile(`(?i)(?:high)\s*[:\-]?\s*(.+?)(?:\n|$)`),
-	"medium":   regexp.MustCompile(`(?i)(?:medium|moderate)\s*[:\-]?\s*(.+?)(?:\n|$)`),
-	"low":      regexp.MustCompile(`(?i)(?:low|minor)\s*[:\-]?\s*(.+?)(?:\n|$)`),
-	"info":     regexp.MustCompile(`(?i)(?:info|note|suggestion)\s*[:\-]?\s*(.+?)(?:\n|$)`),
+// minBulletLength drops list items too short to describe an issue, such as
+// "Point one" or "See above".
+const minBulletLength = 10
+
+// ParseMarkdown extracts findings from an audit report. The structured
+// probe-findings block is preferred; reports without one are scraped.
+// Near-duplicates are merged at DefaultSimilarity.
+func ParseMarkdown(content string) []Finding {
+	return ParseMarkdownWithSimilarity(content, DefaultSimilarity)
 }
 
-var bulletPattern = regexp.MustCompile(`(?m)^\s*[-*]\s*(.+)$`)
-var headingSeverityPattern = regexp.MustCompile(`(?i)#+\s*(critical|high|medium|low|info|note|suggestion)s?\s*$`)
+// ParseMarkdownWithSimilarity is ParseMarkdown with the threshold used to
+// merge near-duplicate findings; see Consolidate.
+func ParseMarkdownWithSimilarity(content string, threshold float64) []Finding {
+	findings := Consolidate(parseReport(content), threshold)
+	for i := range findings {
+		findings[i].Fingerprint = FingerprintOf(findings[i])
+	}
+	return findings
+}
 
-func ParseMarkdown(content string) []Finding {
-	var findings []Finding
-	seenTexts := make(map[string]bool)
+func parseReport(content string) []Finding {
+	if structured, ok := ParseStructured(content); ok {
+		return structured
+	}
+
+	w := &walker{seen: make(map[string]bool)}
+	w.walk(parseMarkdownBlocks(content))
+	return w.findings
+}
+
+// section is an open severity heading such as "## 🔴 Critical Vulnerabilities".
+type section struct {
+	level    int
+	severity string
+}
+
+// walker turns the markdown tree into findings. It tracks which severity
+// section it is in; list items, table rows and sub-headings inside a section
+// become findings of that severity. Outside a section only entries carrying
+// their own severity marker count.
+type walker struct {
+	sections []section
+	seen     map[string]bool
+	findings []Finding
+}
+
+// pseudoHeadingLevel is the level given to a bold line standing in for a
+// heading ("**🔴 Critical Issues**"). It nests under every real heading.
+const pseudoHeadingLevel = 7
 
-	sections := strings.Split(content, "\n#")
-	for _, section := range sections {
-		if !strings.HasPrefix(section, "#") {
-			section = "#" + section
+func (w *walker) severity() string {
+	if len(w.sections) == 0 {
+		return ""
+	}
+	return w.sections[len(w.sections)-1].severity
+}
+
+// leave closes the sections a heading of this level ends.
+func (w *walker) leave(level int) {
+	for len(w.sections) > 0 && w.sections[len(w.sections)-1].level >= level {
+		w.sections = w.sections[:len(w.sections)-1]
+	}
+}
+
+func (w *walker) walk(nodes []*mdNode) {
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[i]
+
+		switch n.Kind {
+		case mdHeading:
+			w.leave(n.Level)
+			if severity, ok := sectionSeverity(n.Text); ok {
+				w.sections = append(w.sections, section{level: n.Level, severity: severity})
+				continue
+			}
+			end := sectionEnd(nodes, i)
+			if w.headingFinding(n, nodes[i+1:end]) {
+				i = end - 1
+			}
+		case mdParagraph:
+			if title, ok := boldLine(n.Text); ok {
+				if severity, ok := sectionSeverity(title); ok {
+					w.leave(pseudoHeadingLevel)
+					w.sections = append(w.sections, section{level: pseudoHeadingLevel, severity: severity})
+				}
+			}
+		case mdList:
+			w.listFindings(n)
+		case mdTable:
+			w.tableFindings(n)
 		}
-		findings = append(findings, parseSection(section, seenTexts)...)
 	}
+}
 
-	bulletFindings := parseBulletPoints(content, seenTexts)
-	findings = append(findings, bulletFindings...)
+// sectionEnd returns the index of the next heading that closes the heading
+// at nodes[i].
+func sectionEnd(nodes []*mdNode, i int) int {
+	for j := i + 1; j < len(nodes); j++ {
+		if nodes[j].Kind == mdHeading && nodes[j].Level <= nodes[i].Level {
+			return j
+		}
+	}
+	return len(nodes)
+}
 
-	return findings
+// headingFinding treats a heading as a finding title when it carries a
+// severity marker, declares one in its body, or sits in a severity section
+// and reads like an issue rather than a category. The heading's whole
+// subtree becomes the finding body.
+func (w *walker) headingFinding(n *mdNode, body []*mdNode) bool {
+	title, marker, numbered := splitHeading(n.Text)
+
+	immediate := body
+	for j, b := range body {
+		if b.Kind == mdHeading {
+			immediate = body[:j]
+			break
+		}
+	}
+
+	declared := bodySeverity(immediate)
+	if marker == "" && declared == "" {
+		if w.severity() == "" {
+			return false
+		}
+		// "### Authentication" followed by a bullet list is a category;
+		// its items are the findings.
+		if !numbered && !describesIssue(immediate) {
+			return false
+		}
+	}
+
+	w.add(title, marker, nil, body, 0)
+	return true
 }
 
-func parseSection(section string, seenTexts map[string]bool) []Finding {
-	var findings []Finding
+// describesIssue reports whether a heading's body reads like a single issue:
+// prose, code or "Key: value" fields rather than a plain list of items.
+func describesIssue(body []*mdNode) bool {
+	for _, b := range body {
+		switch b.Kind {
+		case mdParagraph, mdCode:
+			return true
+		case mdList:
+			for _, item := range b.Children {
+				if _, _, ok := parseField(firstLine(item.Text)); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
 
-	headingMatch := headingSeverityPattern.FindStringSubmatch(section)
-	if len(headingMatch) < 2 {
-		return findings
+func (w *walker) listFindings(list *mdNode) {
+	for _, item := range list.Children {
+		first, rest := splitItemText(item.Text)
+		if first == "" {
+			w.walk(item.Children)
+			continue
+		}
+		if _, _, ok := parseField(first); ok {
+			continue
+		}
+
+		title, marker := itemMarker(first)
+		if marker == "" && w.severity() == "" && bodySeverity(item.Children) == "" {
+			// Not a finding, but a nested list may still hold marked ones.
+			for _, child := range item.Children {
+				if child.Kind == mdList {
+					w.listFindings(child)
+				}
+			}
+			continue
+		}
+
+		var lead []string
+		if rest != "" {
+			lead = strings.Split(rest, "\n")
+		}
+		w.add(title, marker, lead, item.Children, minBulletLength)
 	}
+}
 
-	severity := normalizeSeverity(headingMatch[1])
+// Table columns recognised by header text, checked in this order.
+var tableColumns = []struct {
+	field    string
+	keywords []string
+}{
+	{"severity", []string{"severity", "risk", "priority", "level", "rating"}},
+	{"cwe", []string{"cwe"}},
+	{"owasp", []string{"owasp"}},
+	{"file", []string{"file", "location", "path", "where"}},
+	{"remediation", []string{"remediation", "fix", "recommend", "mitigation", "solution"}},
+	{"title", []string{"finding", "issue", "title", "vulnerab", "name", "summary", "problem"}},
+	{"description", []string{"description", "detail", "impact"}},
+}
 
-	bullets := bulletPattern.FindAllStringSubmatch(section, -1)
-	for _, match := range bullets {
-		if len(match) > 1 {
-			text := strings.TrimSpace(match[1])
-			if text == "" || len(text) < 10 {
+func (w *walker) tableFindings(t *mdNode) {
+	if len(t.Rows) < 2 {
+		return
+	}
+
+	cols := make(map[string]int)
+	for i, header := range t.Rows[0] {
+		h := strings.ToLower(plainText(header))
+		for _, c := range tableColumns {
+			if _, taken := cols[c.field]; taken {
 				continue
 			}
-			if seenTexts[strings.ToLower(text)] {
-				continue
+			if containsAny(h, c.keywords) {
+				cols[c.field] = i
+				break
 			}
-			seenTexts[strings.ToLower(text)] = true
-			findings = append(findings, Finding{
-				ID:       uuid.New().String()[:8],
-				Text:     text,
-				Severity: severity,
-			})
 		}
 	}
 
-	return findings
+	titleCol, ok := cols["title"]
+	if !ok {
+		if titleCol, ok = cols["description"]; !ok {
+			return
+		}
+		delete(cols, "description")
+	}
+
+	for _, row := range t.Rows[1:] {
+		cell := func(field string) string {
+			i, ok := cols[field]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(htmlBreakPattern.ReplaceAllString(row[i], "\n"))
+		}
+
+		marker := ""
+		if _, ok := cols["severity"]; ok {
+			marker = leadingSeverity(cell("severity"))
+		}
+		if marker == "" && w.severity() == "" {
+			continue
+		}
+		if titleCol >= len(row) {
+			continue
+		}
+
+		var lead []string
+		lead = append(lead, strings.Split(cell("description"), "\n")...)
+		for _, field := range []struct{ key, name string }{
+			{"file", "File"}, {"cwe", "CWE"}, {"owasp", "OWASP"}, {"remediation", "Remediation"},
+		} {
+			if v := cell(field.key); v != "" {
+				lead = append(lead, field.name+": "+v)
+			}
+		}
+
+		w.add(row[titleCol], marker, lead, nil, 0)
+	}
+}
+
+// add builds a finding from its title and body and records it unless an
+// identical title was already seen. Severity comes from the title's own
+// marker, then a "Severity:" field in the body, then the enclosing section.
+func (w *walker) add(title, marker string, lead []string, body []*mdNode, minLength int) {
+	title, extra := splitTitle(title)
+	text := strings.TrimSuffix(plainText(title), ":")
+	text = strings.TrimSpace(text)
+	if text == "" || len(text) < minLength || countPattern.MatchString(text) {
+		return
+	}
+	key := strings.ToLower(text)
+	if w.seen[key] {
+		return
+	}
+
+	c := &collector{}
+	if extra != "" {
+		c.line(extra)
+	}
+	for _, l := range lead {
+		c.line(l)
+	}
+	c.collect(body)
+
+	severity := marker
+	if severity == "" {
+		severity = c.severity
+	}
+	if severity == "" {
+		severity = w.severity()
+	}
+	if severity == "" {
+		return
+	}
+	w.seen[key] = true
+
+	f := Finding{
+		ID:          uuid.New().String(),
+		Text:        text,
+		Severity:    severity,
+		File:        c.file,
+		LineStart:   c.lineStart,
+		LineEnd:     c.lineEnd,
+		CWE:         c.cwe,
+		OWASP:       c.owasp,
+		Description: strings.TrimSpace(strings.Join(c.description, "\n")),
+		Remediation: strings.TrimSpace(strings.Join(c.remediation, "\n")),
+	}
+
+	if f.File == "" {
+		f.File, f.LineStart, f.LineEnd = inlineLocation(title)
+	}
+	if f.File == "" {
+		for _, raw := range c.raw {
+			if f.File, f.LineStart, f.LineEnd = inlineLocation(raw); f.File != "" {
+				break
+			}
+		}
+	}
+	if f.LineEnd < f.LineStart {
+		f.LineEnd = f.LineStart
+	}
+
+	scanned := text + "\n" + f.Description
+	if f.CWE == "" {
+		if m := cweMentionPattern.FindStringSubmatch(scanned); m != nil {
+			f.CWE = "CWE-" + m[1]
+		}
+	}
+	if f.OWASP == "" {
+		f.OWASP = owaspMentionPattern.FindString(scanned)
+	}
+
+	w.findings = append(w.findings, f)
 }
 
-func parseBulletPoints(content string, seenTexts map[string]bool) []Finding {
-	var findings []Finding
+// ─── Finding bodies ─────────────────────────────────────────────────────────
 
-	inlineSeverity := regexp.MustCompile(`(?i)^\s*[-*]\s*\[?(critical|high|medium|low|info)\]?\s*[:\-]?\s*(.+)$`)
+// Field keys recognised in "Key: value" lines and sub-headings of a finding.
+const (
+	fieldFile        = "file"
+	fieldLine        = "line"
+	fieldCWE         = "cwe"
+	fieldOWASP       = "owasp"
+	fieldSeverity    = "severity"
+	fieldDescription = "description"
+	fieldRemediation = "remediation"
+)
 
-	lines := strings.Split(content, "\n")
-	var currentSeverity string
+var fieldKeys = map[string]string{
+	"file":               fieldFile,
+	"files":              fieldFile,
+	"location":           fieldFile,
+	"locations":          fieldFile,
+	"path":               fieldFile,
+	"affected file":      fieldFile,
+	"affected files":     fieldFile,
+	"affected code":      fieldFile,
+	"line":               fieldLine,
+	"lines":              fieldLine,
+	"line number":        fieldLine,
+	"line numbers":       fieldLine,
+	"cwe":                fieldCWE,
+	"cwe id":             fieldCWE,
+	"owasp":              fieldOWASP,
+	"owasp category":     fieldOWASP,
+	"severity":           fieldSeverity,
+	"severity level":     fieldSeverity,
+	"risk":               fieldSeverity,
+	"risk level":         fieldSeverity,
+	"rating":             fieldSeverity,
+	"description":        fieldDescription,
+	"details":            fieldDescription,
+	"issue":              fieldDescription,
+	"problem":            fieldDescription,
+	"summary":            fieldDescription,
+	"remediation":        fieldRemediation,
+	"fix":                fieldRemediation,
+	"recommendation":     fieldRemediation,
+	"recommendations":    fieldRemediation,
+	"recommended fix":    fieldRemediation,
+	"suggested fix":      fieldRemediation,
+	"mitigation":         fieldRemediation,
+	"solution":           fieldRemediation,
+	"how to fix":         fieldRemediation,
+	"remediation steps":  fieldRemediation,
+	"recommended action": fieldRemediation,
+}
 
-	for _, line := range lines {
-		headingMatch := headingSeverityPattern.FindStringSubmatch(line)
-		if len(headingMatch) > 1 {
-			currentSeverity = normalizeSeverity(headingMatch[1])
+var fieldPattern = regexp.MustCompile(`^(?:[-*]\s+)?(?:\*\*|__)?([A-Za-z][A-Za-z /]{0,30}?)(?:\*\*|__)?\s*:\s*(?:\*\*|__)?\s*(.*)$`)
+
+// parseField splits a "**File:** src/app.js" style line into its
+// normalized key and value.
+func parseField(line string) (key, value string, ok bool) {
+	m := fieldPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return "", "", false
+	}
+	key, ok = fieldKeys[strings.ToLower(strings.TrimSpace(m[1]))]
+	if !ok {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(m[2]), true
+}
+
+// collector gathers the body of one finding.
+type collector struct {
+	field       string // field that bare lines currently belong to
+	severity    string
+	file        string
+	lineStart   int
+	lineEnd     int
+	cwe         string
+	owasp       string
+	description []string
+	remediation []string
+	raw         []string // unprocessed lines, for location scanning
+}
+
+func (c *collector) collect(nodes []*mdNode) {
+	for _, n := range nodes {
+		switch n.Kind {
+		case mdHeading:
+			c.field = ""
+			if key, ok := fieldKeys[strings.ToLower(strings.TrimSuffix(plainText(n.Text), ":"))]; ok {
+				c.field = key
+			}
+		case mdParagraph:
+			for _, l := range strings.Split(n.Text, "\n") {
+				c.line(l)
+			}
+		case mdList:
+			for _, item := range n.Children {
+				for _, l := range strings.Split(item.Text, "\n") {
+					c.line(l)
+				}
+				c.collect(item.Children)
+			}
+		case mdCode:
+			if c.field == fieldRemediation {
+				c.remediation = append(c.remediation, "```"+n.Info+"\n"+n.Text+"\n```")
+			}
+		}
+	}
+}
+
+func (c *collector) line(raw string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return
+	}
+	c.raw = append(c.raw, raw)
+
+	key, value, ok := parseField(raw)
+	if !ok {
+		if c.field == fieldRemediation {
+			c.remediation = append(c.remediation, plainText(raw))
+		} else {
+			c.description = append(c.description, plainText(raw))
+		}
+		return
+	}
+
+	value = plainText(value)
+	c.field = ""
+
+	switch key {
+	case fieldFile:
+		if c.file == "" {
+			c.file, c.lineStart, c.lineEnd = parseLocation(value)
+		}
+	case fieldLine:
+		if m := lineRangePattern.FindStringSubmatch(value); m != nil && c.lineStart == 0 {
+			c.lineStart, c.lineEnd = atoi(m[1]), atoi(m[2])
+		}
+	case fieldCWE:
+		if c.cwe == "" {
+			c.cwe = normalizeCWEString(firstWord(value))
+		}
+	case fieldOWASP:
+		if c.owasp == "" {
+			c.owasp = value
+		}
+	case fieldSeverity:
+		if c.severity == "" {
+			c.severity = leadingSeverity(value)
+		}
+	case fieldDescription:
+		c.field = fieldDescription
+		if value != "" {
+			c.description = append(c.description, value)
+		}
+	case fieldRemediation:
+		c.field = fieldRemediation
+		if value != "" {
+			c.remediation = append(c.remediation, value)
+		}
+	}
+}
+
+// bodySeverity returns the severity declared by a "Severity:" field among
+// the nodes, without descending into sub-headings.
+func bodySeverity(nodes []*mdNode) string {
+	c := &collector{}
+	for _, n := range nodes {
+		if n.Kind == mdHeading {
+			break
+		}
+		c.collect([]*mdNode{n})
+	}
+	return c.severity
+}
+
+var (
+	locationPattern     = regexp.MustCompile(`([\w.@/\\-]*[\w-]\.[A-Za-z0-9]+)(?:(?::|#L|,?\s*\(?\s*lines?\s+)(\d+)(?:\s*[-–]\s*L?(\d+))?)?`)
+	lineRangePattern    = regexp.MustCompile(`(\d+)(?:\s*[-–]\s*(\d+))?`)
+	codeSpanPattern     = regexp.MustCompile("`([^`]+)`")
+	cweMentionPattern   = regexp.MustCompile(`(?i)\bCWE[-\s:]*(\d+)\b`)
+	owaspMentionPattern = regexp.MustCompile(`\bA\d{2}:20\d{2}(?:-[A-Za-z][\w-]*)?`)
+	countPattern        = regexp.MustCompile(`^\d+(?:\s|$)`)
+	htmlBreakPattern    = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// parseLocation reads "src/app.js:42-48", "src/app.js (line 42)" and
+// similar into a path and line range.
+func parseLocation(s string) (string, int, int) {
+	m := locationPattern.FindStringSubmatch(s)
+	if m == nil {
+		return firstWord(s), 0, 0
+	}
+	return m[1], atoi(m[2]), atoi(m[3])
+}
+
+// inlineLocation finds a file reference in a code span of raw markdown,
+// such as "in `src/db/query.js:17`".
+func inlineLocation(raw string) (string, int, int) {
+	for _, span := range codeSpanPattern.FindAllStringSubmatch(raw, -1) {
+		m := locationPattern.FindStringSubmatch(span[1])
+		if m == nil || (m[2] == "" && !strings.Contains(m[1], "/")) {
 			continue
 		}
+		return m[1], atoi(m[2]), atoi(m[3])
+	}
+	return "", 0, 0
+}
+
+// ─── Severity detection ─────────────────────────────────────────────────────
 
-		inlineMatch := inlineSeverity.FindStringSubmatch(line)
-		if len(inlineMatch) >= 3 {
-			severity := normalizeSeverity(inlineMatch[1])
-			text := strings.TrimSpace(inlineMatch[2])
-			if text != "" && len(text) >= 10 && !seenTexts[strings.ToLower(text)] {
-				seenTexts[strings.ToLower(text)] = true
-				findings = append(findings, Finding{
-					ID:       uuid.New().String()[:8],
-					Text:     text,
-					Severity: severity,
-				})
+var severityKeywords = map[string]string{
+	"critical":      "critical",
+	"criticals":     "critical",
+	"severe":        "critical",
+	"high":          "high",
+	"highs":         "high",
+	"medium":        "medium",
+	"mediums":       "medium",
+	"moderate":      "medium",
+	"low":           "low",
+	"lows":          "low",
+	"minor":         "low",
+	"info":          "info",
+	"informational": "info",
+	"note":          "info",
+	"notes":         "info",
+	"suggestion":    "info",
+	"suggestions":   "info",
+}
+
+// severityEmoji are the markers reports (and the agent's progress output)
+// put in front of severities.
+var severityEmoji = []struct {
+	emoji    string
+	severity string
+}{
+	{"🔴", "critical"},
+	{"🟠", "high"},
+	{"🟡", "medium"},
+	{"🟢", "low"},
+	{"🔵", "info"},
+	{"⚪", "info"},
+}
+
+// sectionWords may accompany a severity in a section heading, as in
+// "Critical Vulnerabilities" or "Findings: High Severity".
+var sectionWords = wordSet("severity severities risk risks priority level rated issues issue findings finding " +
+	"vulnerabilities vulnerability vulns vuln security concerns concern problems bugs weaknesses " +
+	"recommendations observations items found identified and of the s")
+
+// sectionNouns following a leading severity make a section heading even when
+// more words follow: "High Severity Issues in the API Layer".
+var sectionNouns = wordSet("severity risk risks priority issues issue findings finding vulnerabilities " +
+	"vulnerability vulns security concerns problems bugs weaknesses observations recommendations")
+
+var (
+	sevAlternation      = `critical|severe|high|medium|moderate|low|minor|informational|info`
+	bracketMarkerRegexp = regexp.MustCompile(`(?i)^(?:\*\*|__)?[\[(]\s*(` + sevAlternation + `)(?:\s+(?:severity|risk))?\s*[\])](?:\*\*|__)?\s*(?:[:\-–—|]\s*)?`)
+	labelMarkerRegexp   = regexp.MustCompile(`(?i)^(?:\*\*|__)?(` + sevAlternation + `)(?:\s+(?:severity|risk))?(?:\*\*|__)?(?:\s*:|\s+[-–—|]\s)\s*(?:\*\*|__)?\s*`)
+	trailingMarker      = regexp.MustCompile(`(?i)(?:\s*[\[(](` + sevAlternation + `)(?:\s+(?:severity|risk))?[\])]|\s+[-–—|]\s+(?:\*\*|__)?(` + sevAlternation + `)(?:\s+(?:severity|risk))?(?:\*\*|__)?)\s*$`)
+	numberingPattern    = regexp.MustCompile(`(?i)^(?:(?:finding|issue|vulnerability|vuln)\s*)?#?\d+(?:\.\d+)*[.):]?\s+`)
+)
+
+// sectionSeverity reports whether a heading opens a severity section and
+// which severity it is.
+func sectionSeverity(heading string) (string, bool) {
+	s := plainText(heading)
+	emoji := ""
+	for _, e := range severityEmoji {
+		if strings.Contains(s, e.emoji) {
+			emoji = e.severity
+			break
+		}
+	}
+
+	words := wordsOf(s)
+	severity := ""
+	onlySectionWords := true
+	for _, w := range words {
+		if sev, ok := severityKeywords[w]; ok {
+			if severity == "" {
+				severity = sev
 			}
 			continue
 		}
+		if !sectionWords[w] {
+			onlySectionWords = false
+		}
+	}
+
+	if severity == "" {
+		if emoji != "" && onlySectionWords {
+			return emoji, true
+		}
+		return "", false
+	}
+	if onlySectionWords {
+		return severity, true
+	}
+	if _, ok := severityKeywords[words[0]]; ok && len(words) > 1 && sectionNouns[words[1]] {
+		return severity, true
+	}
+	return "", false
+}
+
+// splitHeading strips numbering and severity markers from a finding heading
+// like "3. 🔴 SQL Injection in Login (Critical)".
+func splitHeading(heading string) (title, marker string, numbered bool) {
+	s := strings.TrimSpace(heading)
+
+	marker, s = stripSeverityEmoji(s)
+	if m := numberingPattern.FindString(s); m != "" {
+		numbered = true
+		s = s[len(m):]
+	}
+
+	rest, sev := itemMarker(s)
+	if marker == "" {
+		marker = sev
+	}
+	s = rest
+
+	if m := trailingMarker.FindStringSubmatchIndex(s); m != nil {
+		sev := ""
+		if m[2] != -1 {
+			sev = s[m[2]:m[3]]
+		} else {
+			sev = s[m[4]:m[5]]
+		}
+		if marker == "" {
+			marker = normalizeSeverity(sev)
+		}
+		s = s[:m[0]]
+	}
+
+	return strings.TrimSpace(s), marker, numbered
+}
+
+// itemMarker strips a leading severity marker such as "[HIGH]", "High:",
+// "**Critical** -" or "🟠" from a line. Bare words are not markers:
+// "High memory use in worker" has no severity.
+func itemMarker(line string) (rest, severity string) {
+	severity, line = stripSeverityEmoji(line)
 
-		bulletMatch := bulletPattern.FindStringSubmatch(line)
-		if len(bulletMatch) > 1 && currentSeverity != "" {
-			text := strings.TrimSpace(bulletMatch[1])
-			if text != "" && len(text) >= 10 && !seenTexts[strings.ToLower(text)] {
-				seenTexts[strings.ToLower(text)] = true
-				findings = append(findings, Finding{
-					ID:       uuid.New().String()[:8],
-					Text:     text,
-					Severity: currentSeverity,
-				})
+	for _, p := range []*regexp.Regexp{bracketMarkerRegexp, labelMarkerRegexp} {
+		if m := p.FindStringSubmatch(line); m != nil {
+			if severity == "" {
+				severity = normalizeSeverity(m[1])
 			}
+			return strings.TrimSpace(line[len(m[0]):]), severity
 		}
 	}
 
-	return findings
+	return line, severity
+}
+
+func stripSeverityEmoji(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	for _, e := range severityEmoji {
+		if strings.HasPrefix(s, e.emoji) {
+			rest := strings.TrimPrefix(s[len(e.emoji):], "\uFE0F")
+			return e.severity, strings.TrimSpace(rest)
+		}
+	}
+	return "", s
+}
+
+// leadingSeverity reads the severity at the start of a cell or field value,
+// e.g. "🔴 Critical", "**High** (CVSS 8.1)".
+func leadingSeverity(s string) string {
+	if sev, _ := stripSeverityEmoji(plainText(s)); sev != "" {
+		return sev
+	}
+	words := wordsOf(plainText(s))
+	if len(words) == 0 {
+		return ""
+	}
+	return severityKeywords[words[0]]
 }
 
 func normalizeSeverity(s string) string {
@@ -136,3 +758,104 @@ func normalizeSeverity(s string) string {
 		return "info"
 	}
 }
+
+// ─── Inline text ────────────────────────────────────────────────────────────
+
+var (
+	linkPattern     = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	boldLinePattern = regexp.MustCompile(`^(?:\*\*|__)(.+?)(?:\*\*|__):?$`)
+	boldLeadPattern = regexp.MustCompile(`^(?:\*\*|__)(.+?)(?:\*\*|__)(.*)$`)
+	titleSeparator  = regexp.MustCompile(`^\s*(?::|[-–—]\s)\s*`)
+	inlineMarkup    = strings.NewReplacer("**", "", "__", "", "~~", "", "`", "")
+)
+
+// plainText drops inline markup: emphasis, code spans and link targets.
+func plainText(s string) string {
+	s = linkPattern.ReplaceAllString(s, "$1")
+	return strings.TrimSpace(inlineMarkup.Replace(s))
+}
+
+// splitTitle separates a bold lead from the sentence after it:
+// "**SQL injection**: user input reaches the query" gives the title and
+// the rest as description.
+func splitTitle(s string) (title, rest string) {
+	m := boldLeadPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return s, ""
+	}
+	title, rest = m[1], m[2]
+
+	if strings.HasSuffix(title, ":") {
+		title = strings.TrimSuffix(title, ":")
+	} else if sep := titleSeparator.FindString(rest); sep != "" {
+		rest = rest[len(sep):]
+	} else if strings.TrimSpace(rest) != "" {
+		return s, ""
+	}
+
+	return title, strings.TrimSpace(rest)
+}
+
+// boldLine returns the text of a paragraph that is a single bold line.
+func boldLine(s string) (string, bool) {
+	if strings.Contains(s, "\n") {
+		return "", false
+	}
+	m := boldLinePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// splitItemText separates the title of a list item from the rest of its
+// paragraph. A sentence wrapped over several lines stays one title; the
+// title ends at the first "Key: value" field.
+func splitItemText(s string) (string, string) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	n := 1
+	for n < len(lines) {
+		if _, _, ok := parseField(lines[n]); ok {
+			break
+		}
+		n++
+	}
+	title := strings.Join(strings.Fields(strings.Join(lines[:n], " ")), " ")
+	return title, strings.Join(lines[n:], "\n")
+}
+
+func wordsOf(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) })
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstWord(s string) string {
+	if fields := strings.Fields(s); len(fields) > 0 {
+		return strings.TrimRight(fields[0], ",;")
+	}
+	return ""
+}
+
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return n
+}
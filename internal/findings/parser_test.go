@@ -83,66 +83,6 @@ But these are not security issues.
 	t.Logf("Found %d items in non-security document", len(findings))
 }
 
-func TestParseSection(t *testing.T) {
-	seenTexts := make(map[string]bool)
-	// Note: The pattern matches severity at end of line, so test with single-line heading first
-	// In real usage, the section would be split and processed line by line
-	section := "## Critical"
-
-	findings := parseSection(section, seenTexts)
-
-	// parseSection returns empty for headings without bullet points
-	// This is expected behavior
-	if len(findings) != 0 {
-		t.Errorf("parseSection should return 0 findings for heading-only section, got %d", len(findings))
-	}
-
-	// Test with bullets - need to use the actual parser flow
-	content := "# Security Report\n\n## Critical\n- SQL injection vulnerability in user input\n- Remote code execution via file upload"
-	allFindings := ParseMarkdown(content)
-
-	if len(allFindings) == 0 {
-		t.Error("ParseMarkdown should find findings")
-	}
-
-	for _, f := range allFindings {
-		if f.ID == "" {
-			t.Error("Finding should have an ID")
-		}
-		if f.Text == "" {
-			t.Error("Finding should have text")
-		}
-		if f.Severity == "" {
-			t.Error("Finding should have severity")
-		}
-	}
-}
-
-func TestParseBulletPoints(t *testing.T) {
-	seenTexts := make(map[string]bool)
-	content := `## Findings
-- High: Missing rate limiting on API endpoints
-- Medium: Verbose error messages in production
-- Low: Missing Content-Security-Policy header`
-
-	findings := parseBulletPoints(content, seenTexts)
-
-	if len(findings) == 0 {
-		t.Error("parseBulletPoints should return findings")
-	}
-
-	// Check for inline severity markers
-	var hasHigh bool
-	for _, f := range findings {
-		if f.Severity == "high" {
-			hasHigh = true
-		}
-	}
-	if !hasHigh {
-		t.Error("Should detect inline high severity markers")
-	}
-}
-
 func TestNormalizeSeverity(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -243,37 +183,197 @@ func TestParseMarkdownWithSeverityMarkers(t *testing.T) {
 	}
 }
 
-func TestBulletPattern(t *testing.T) {
-	// Test various bullet formats
-	lines := []string{
-		"- This is a bullet",
-		"* This is also a bullet",
-		"  - Indented bullet",
-		"  * Indented asterisk",
+func TestParseStructured(t *testing.T) {
+	content := "# Security Audit Report\n\n## Critical\n- SQL injection in login handler allows bypass\n\n" +
+		"```json probe-findings\n" + `{
+  "findings": [
+    {
+      "title": "SQL injection in login handler",
+      "severity": "Critical",
+      "file": "src/auth/login.js",
+      "line_start": 42,
+      "line_end": "48",
+      "cwe": 89,
+      "owasp": "A03:2021-Injection",
+      "description": "User input is concatenated into the SQL query.",
+      "remediation": "Use parameterised queries."
+    },
+    {
+      "severity": "low",
+      "description": "Missing security headers on static assets\nMore detail here."
+    }
+  ]
+}` + "\n```\n"
+
+	findings := ParseMarkdown(content)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 structured findings, got %d", len(findings))
+	}
+
+	f := findings[0]
+	if f.Text != "SQL injection in login handler" || f.Severity != "critical" {
+		t.Errorf("Unexpected text/severity: %q %q", f.Text, f.Severity)
+	}
+	if f.File != "src/auth/login.js" || f.LineStart != 42 || f.LineEnd != 48 {
+		t.Errorf("Unexpected location: %s:%d-%d", f.File, f.LineStart, f.LineEnd)
+	}
+	if f.CWE != "CWE-89" {
+		t.Errorf("CWE = %q, want CWE-89", f.CWE)
+	}
+	if f.OWASP != "A03:2021-Injection" || f.Remediation != "Use parameterised queries." {
+		t.Errorf("Unexpected classification: %+v", f)
+	}
+
+	if findings[1].Text != "Missing security headers on static assets" {
+		t.Errorf("Title should fall back to first description line, got %q", findings[1].Text)
+	}
+}
+
+func TestParseStructuredFallback(t *testing.T) {
+	content := "# Report\n\n## High\n- Missing CSRF protection on payment endpoint\n\n" +
+		"```json probe-findings\nnot valid json\n```\n"
+
+	if _, ok := ParseStructured(content); ok {
+		t.Error("ParseStructured should reject a malformed block")
+	}
+
+	findings := ParseMarkdown(content)
+	if len(findings) == 0 {
+		t.Error("ParseMarkdown should fall back to markdown scraping")
+	}
+}
+
+func TestParseStructuredUntaggedJSON(t *testing.T) {
+	content := "```json\n{\"other\": true}\n```\n\n```json\n{\"findings\": [{\"title\": \"Hardcoded secret in config\", \"severity\": \"high\", \"cwe\": \"cwe-798\"}]}\n```"
+
+	findings, ok := ParseStructured(content)
+	if !ok || len(findings) != 1 {
+		t.Fatalf("Expected 1 finding from untagged block, got %d (ok=%v)", len(findings), ok)
+	}
+	if findings[0].CWE != "CWE-798" {
+		t.Errorf("CWE = %q, want CWE-798", findings[0].CWE)
+	}
+}
+
+func TestParseStructuredIgnoresJSONEvidence(t *testing.T) {
+	content := "# Report\n\n## High\n- Admin API returns every user's password hash\n\n" +
+		"The endpoint responds with:\n\n```json\n[{\"id\": 1, \"email\": \"a@example.com\", \"hash\": \"$2b$10$abc\"}]\n```\n\n" +
+		"```json\n{\"findings\": []}\n```\n"
+
+	if findings, ok := ParseStructured(content); ok {
+		t.Fatalf("JSON evidence should not count as structured findings, got %d (ok=%v)", len(findings), ok)
+	}
+	findings := ParseMarkdown(content)
+	if len(findings) != 1 || findings[0].Text != "Admin API returns every user's password hash" {
+		t.Errorf("ParseMarkdown should scrape the markdown findings, got %+v", findings)
+	}
+}
+
+func TestSectionSeverity(t *testing.T) {
+	tests := []struct {
+		heading string
+		want    string
+		ok      bool
+	}{
+		{"Critical", "critical", true},
+		{"HIGH", "high", true},
+		{"Criticals", "critical", true},
+		{"🔴 Critical Vulnerabilities", "critical", true},
+		{"🟠 Issues", "high", true},
+		{"High Severity Issues in the API Layer", "high", true},
+		{"Findings: Medium Risk", "medium", true},
+		{"Low Priority Recommendations", "low", true},
+		{"**Informational**", "info", true},
+		{"High-Level Design", "", false},
+		{"Low-Hanging Improvements", "", false},
+		{"Executive Summary", "", false},
+		{"🔴 SQL Injection in Login", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := sectionSeverity(tt.heading)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("sectionSeverity(%q) = %q, %v; want %q, %v", tt.heading, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestItemMarker(t *testing.T) {
+	tests := []struct {
+		line     string
+		rest     string
+		severity string
+	}{
+		{"[CRITICAL] Database credentials exposed", "Database credentials exposed", "critical"},
+		{"High: Missing rate limiting", "Missing rate limiting", "high"},
+		{"**High:** Missing rate limiting", "Missing rate limiting", "high"},
+		{"**Medium** - Weak session timeout", "Weak session timeout", "medium"},
+		{"(Low) Missing security headers", "Missing security headers", "low"},
+		{"🟠 Open redirect on logout", "Open redirect on logout", "high"},
+		{"High memory usage in the worker", "High memory usage in the worker", ""},
+		{"High-risk endpoints lack auth", "High-risk endpoints lack auth", ""},
 	}
 
-	for _, line := range lines {
-		matches := bulletPattern.FindStringSubmatch(line)
-		if matches == nil {
-			t.Errorf("Should match bullet: %s", line)
+	for _, tt := range tests {
+		rest, severity := itemMarker(tt.line)
+		if rest != tt.rest || severity != tt.severity {
+			t.Errorf("itemMarker(%q) = %q, %q; want %q, %q", tt.line, rest, severity, tt.rest, tt.severity)
 		}
 	}
 }
 
-func TestHeadingSeverityPattern(t *testing.T) {
-	// Test various heading formats - pattern expects severity at END of heading
-	headings := []string{
-		"## Critical",
-		"## HIGH",
-		"## Criticals",
-		"### Low",
-		"## Info",
+func TestSplitHeading(t *testing.T) {
+	tests := []struct {
+		heading  string
+		title    string
+		marker   string
+		numbered bool
+	}{
+		{"1. SQL Injection in Login", "SQL Injection in Login", "", true},
+		{"Finding 2: Outdated OpenSSL (Medium)", "Outdated OpenSSL", "medium", true},
+		{"🔴 Hardcoded secret", "Hardcoded secret", "critical", false},
+		{"3.1 [HIGH] Open redirect", "Open redirect", "high", true},
+		{"2FA can be bypassed — Critical", "2FA can be bypassed", "critical", false},
 	}
 
-	for _, heading := range headings {
-		matches := headingSeverityPattern.FindStringSubmatch(heading)
-		if matches == nil {
-			t.Errorf("Should match severity heading: %s", heading)
+	for _, tt := range tests {
+		title, marker, numbered := splitHeading(tt.heading)
+		if title != tt.title || marker != tt.marker || numbered != tt.numbered {
+			t.Errorf("splitHeading(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.heading, title, marker, numbered, tt.title, tt.marker, tt.numbered)
 		}
 	}
 }
+
+func TestParseMarkdownIgnoresUnmarkedBullets(t *testing.T) {
+	content := `# Notes from the review meeting
+
+- The API handles high volumes of read traffic
+- Workers process low priority jobs from the queue
+- Critical path latency is within budget`
+
+	if findings := ParseMarkdown(content); len(findings) != 0 {
+		t.Errorf("Bullets that merely mention a severity should not be findings, got %+v", findings)
+	}
+}
+
+func TestParseMarkdownNestedBulletsAreBody(t *testing.T) {
+	content := `## High
+- Debug mode enabled in production settings
+  - File: settings/prod.py:4
+  - Exposes the interactive debugger to anyone
+- Pickle deserialization of cached session data`
+
+	findings := ParseMarkdown(content)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %+v", len(findings), findings)
+	}
+
+	f := findings[0]
+	if f.File != "settings/prod.py" || f.LineStart != 4 {
+		t.Errorf("Unexpected location: %s:%d", f.File, f.LineStart)
+	}
+	if f.Description != "Exposes the interactive debugger to anyone" {
+		t.Errorf("Unexpected description: %q", f.Description)
+	}
+}
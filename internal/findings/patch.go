@@ -0,0 +1,43 @@
+package findings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PatchBlockTag is the info string of the fenced code block holding a
+// proposed fix.
+const PatchBlockTag = "probe-patch"
+
+// Proposal is a fix suggested by the agent.
+type Proposal struct {
+	Patch   string `json:"patch"`
+	Summary string `json:"summary"`
+}
+
+// ParsePatch reads the unified diff from the probe-patch block of a fix
+// transcript, falling back to the last diff block. The text outside the
+// blocks becomes the summary.
+func ParsePatch(content string) (*Proposal, error) {
+	var patch string
+	for _, match := range fencedBlockPattern.FindAllStringSubmatch(content, -1) {
+		info := strings.ToLower(match[1])
+		if strings.Contains(info, PatchBlockTag) {
+			patch = match[2]
+			break
+		}
+		if strings.HasPrefix(strings.TrimSpace(info), "diff") || strings.HasPrefix(strings.TrimSpace(info), "patch") {
+			patch = match[2]
+		}
+	}
+
+	patch = strings.Trim(patch, "\n")
+	if !strings.Contains(patch, "\n+++ ") && !strings.HasPrefix(patch, "+++ ") {
+		return nil, fmt.Errorf("the agent did not return a unified diff")
+	}
+
+	return &Proposal{
+		Patch:   patch + "\n",
+		Summary: lastParagraph(fencedBlockPattern.ReplaceAllString(content, "")),
+	}, nil
+}
@@ -0,0 +1,53 @@
+package findings
+
+import "testing"
+
+func TestParsePatch(t *testing.T) {
+	diff := "--- a/src/login.js\n+++ b/src/login.js\n@@ -1,1 +1,1 @@\n-db.query(sql + user)\n+db.query(sql, [user])"
+
+	tests := []struct {
+		name        string
+		content     string
+		wantSummary string
+		wantErr     bool
+	}{
+		{
+			name:        "tagged block",
+			content:     "Looking at login.js.\n\nBind the user as a parameter.\n\n```diff probe-patch\n" + diff + "\n```\n",
+			wantSummary: "Bind the user as a parameter.",
+		},
+		{
+			name:        "tagged block wins over earlier diff",
+			content:     "```diff\n--- a/other\n+++ b/other\n```\n\n```diff probe-patch\n" + diff + "\n```",
+			wantSummary: "",
+		},
+		{
+			name:        "plain diff block",
+			content:     "Fix:\n\n```diff\n" + diff + "\n```",
+			wantSummary: "Fix:",
+		},
+		{name: "no block", content: "I could not find the code.", wantErr: true},
+		{name: "not a diff", content: "```diff probe-patch\nchange line 4\n```", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePatch(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParsePatch() should fail, got %+v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePatch() failed: %v", err)
+			}
+			if p.Patch != diff+"\n" {
+				t.Errorf("Patch = %q", p.Patch)
+			}
+			if p.Summary != tt.wantSummary {
+				t.Errorf("Summary = %q, want %q", p.Summary, tt.wantSummary)
+			}
+		})
+	}
+}
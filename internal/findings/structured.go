@@ -0,0 +1,158 @@
+package findings
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// StructuredBlockTag is the info string of the fenced code block the agent
+// is asked to emit alongside the markdown report.
+const StructuredBlockTag = "probe-findings"
+
+var fencedBlockPattern = regexp.MustCompile("(?s)```([^\\n`]*)\\n(.*?)\\n\\s*```")
+
+var cwePattern = regexp.MustCompile(`(?i)^(?:cwe[-\s:]*)?(\d+)$`)
+
+// structuredFinding mirrors the JSON schema requested in the agent prompt.
+type structuredFinding struct {
+	Title       string          `json:"title"`
+	Severity    string          `json:"severity"`
+	File        string          `json:"file"`
+	LineStart   json.RawMessage `json:"line_start"`
+	LineEnd     json.RawMessage `json:"line_end"`
+	CWE         json.RawMessage `json:"cwe"`
+	OWASP       string          `json:"owasp"`
+	Description string          `json:"description"`
+	Remediation string          `json:"remediation"`
+}
+
+// ParseStructured extracts findings from a fenced ```json probe-findings
+// block. The second return value reports whether such a block was found;
+// callers fall back to scraping the markdown when it is false. A plain
+// ```json block only counts when it is an object with a "findings" list
+// holding at least one finding, so JSON quoted as evidence in a report is
+// not mistaken for the findings.
+func ParseStructured(content string) ([]Finding, bool) {
+	for _, match := range fencedBlockPattern.FindAllStringSubmatch(content, -1) {
+		info := strings.ToLower(strings.TrimSpace(match[1]))
+		body := strings.TrimSpace(match[2])
+
+		tagged := strings.Contains(info, StructuredBlockTag)
+		if !tagged && info != "json" {
+			continue
+		}
+
+		raw, ok := decodeStructured(body, tagged)
+		if !ok {
+			if tagged {
+				return nil, false
+			}
+			continue
+		}
+
+		findings := convertStructured(raw)
+		if !tagged && len(findings) == 0 {
+			continue
+		}
+		return findings, true
+	}
+
+	return nil, false
+}
+
+// decodeStructured decodes a {"findings": [...]} object, or for a tagged
+// block also a bare list of findings.
+func decodeStructured(body string, tagged bool) ([]structuredFinding, bool) {
+	var wrapped struct {
+		Findings *[]structuredFinding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(body), &wrapped); err == nil && wrapped.Findings != nil {
+		return *wrapped.Findings, true
+	}
+
+	var list []structuredFinding
+	if tagged && strings.HasPrefix(body, "[") {
+		if err := json.Unmarshal([]byte(body), &list); err == nil {
+			return list, true
+		}
+	}
+
+	return nil, false
+}
+
+func convertStructured(raw []structuredFinding) []Finding {
+	var findings []Finding
+	seenTexts := make(map[string]bool)
+
+	for _, r := range raw {
+		text := strings.TrimSpace(r.Title)
+		if text == "" {
+			text = firstLine(r.Description)
+		}
+		if text == "" || seenTexts[strings.ToLower(text)] {
+			continue
+		}
+		seenTexts[strings.ToLower(text)] = true
+
+		f := Finding{
+			ID:          uuid.New().String(),
+			Text:        text,
+			Severity:    normalizeSeverity(r.Severity),
+			File:        strings.TrimSpace(r.File),
+			LineStart:   parseLine(r.LineStart),
+			LineEnd:     parseLine(r.LineEnd),
+			CWE:         normalizeCWE(r.CWE),
+			OWASP:       strings.TrimSpace(r.OWASP),
+			Description: strings.TrimSpace(r.Description),
+			Remediation: strings.TrimSpace(r.Remediation),
+		}
+		if f.LineEnd < f.LineStart {
+			f.LineEnd = f.LineStart
+		}
+
+		findings = append(findings, f)
+	}
+
+	return findings
+}
+
+// parseLine accepts line numbers as JSON numbers or numeric strings.
+func parseLine(raw json.RawMessage) int {
+	if len(raw) == 0 {
+		return 0
+	}
+	s := strings.Trim(string(raw), `"`)
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// normalizeCWE turns 89, "89" or "cwe 89" into "CWE-89".
+func normalizeCWE(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	return normalizeCWEString(strings.Trim(string(raw), `"`))
+}
+
+func normalizeCWEString(s string) string {
+	s = strings.TrimSpace(s)
+	if m := cwePattern.FindStringSubmatch(s); m != nil {
+		return "CWE-" + m[1]
+	}
+	return s
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
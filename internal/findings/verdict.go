@@ -0,0 +1,66 @@
+package findings
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// VerdictBlockTag is the info string of the fenced code block the agent ends
+// a verification with.
+const VerdictBlockTag = "probe-verdict"
+
+// Verification verdicts.
+const (
+	VerdictFixed        = "fixed"
+	VerdictStillPresent = "still_present"
+	VerdictInconclusive = "inconclusive"
+)
+
+// Verdict is the agent's answer to whether a finding is still present.
+type Verdict struct {
+	Verdict   string `json:"verdict"`
+	Reasoning string `json:"reasoning"`
+}
+
+// ParseVerdict reads the probe-verdict block from a verification transcript.
+// A missing or unreadable block, or an unknown verdict, is inconclusive; the
+// transcript's last paragraph then stands in for the reasoning.
+func ParseVerdict(content string) Verdict {
+	for _, match := range fencedBlockPattern.FindAllStringSubmatch(content, -1) {
+		if !strings.Contains(strings.ToLower(match[1]), VerdictBlockTag) {
+			continue
+		}
+
+		var v Verdict
+		if err := json.Unmarshal([]byte(strings.TrimSpace(match[2])), &v); err != nil {
+			break
+		}
+		v.Verdict = normalizeVerdict(v.Verdict)
+		v.Reasoning = strings.TrimSpace(v.Reasoning)
+		return v
+	}
+
+	reasoning := "The agent did not return a verdict."
+	if last := lastParagraph(fencedBlockPattern.ReplaceAllString(content, "")); last != "" {
+		reasoning += " Last output: " + last
+	}
+	return Verdict{Verdict: VerdictInconclusive, Reasoning: reasoning}
+}
+
+func normalizeVerdict(v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	v = strings.NewReplacer(" ", "_", "-", "_").Replace(v)
+	switch v {
+	case VerdictFixed, "resolved":
+		return VerdictFixed
+	case VerdictStillPresent, "present", "not_fixed", "open":
+		return VerdictStillPresent
+	default:
+		return VerdictInconclusive
+	}
+}
+
+func lastParagraph(s string) string {
+	paragraphs := strings.Split(strings.TrimSpace(s), "\n\n")
+	return strings.TrimSpace(paragraphs[len(paragraphs)-1])
+}
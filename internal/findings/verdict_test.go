@@ -0,0 +1,58 @@
+package findings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		verdict string
+		reason  string
+	}{
+		{
+			name:    "fixed",
+			content: "Checked login.js.\n\n```json probe-verdict\n{\"verdict\": \"fixed\", \"reasoning\": \"The query is parameterised now.\"}\n```",
+			verdict: VerdictFixed,
+			reason:  "The query is parameterised now.",
+		},
+		{
+			name:    "still present spelled out",
+			content: "```json probe-verdict\n{\"verdict\": \"Still Present\", \"reasoning\": \"Input is still concatenated.\"}\n```",
+			verdict: VerdictStillPresent,
+			reason:  "Input is still concatenated.",
+		},
+		{
+			name:    "unknown verdict",
+			content: "```json probe-verdict\n{\"verdict\": \"maybe\", \"reasoning\": \"File moved.\"}\n```",
+			verdict: VerdictInconclusive,
+			reason:  "File moved.",
+		},
+		{
+			name:    "no block",
+			content: "I could not find the file.\n\nIt may have been deleted.",
+			verdict: VerdictInconclusive,
+			reason:  "It may have been deleted.",
+		},
+		{
+			name:    "malformed block",
+			content: "```json probe-verdict\nnot json\n```",
+			verdict: VerdictInconclusive,
+			reason:  "did not return a verdict",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := ParseVerdict(tt.content)
+			if v.Verdict != tt.verdict {
+				t.Errorf("Verdict = %q, want %q", v.Verdict, tt.verdict)
+			}
+			if !strings.Contains(v.Reasoning, tt.reason) {
+				t.Errorf("Reasoning = %q, want it to contain %q", v.Reasoning, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package gitinfo reads what a probe needs to know about the git repository
+// it scans.
+package gitinfo
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Repo identifies the repository containing a directory. RemoteURL is
+// normalized so clones over https and ssh of the same repository match; it
+// is empty when the repository has no remote.
+type Repo struct {
+	Root      string `json:"root"`
+	RemoteURL string `json:"remote_url"`
+}
+
+// Name is a short display name: the last part of the remote, or the name
+// of the root directory.
+func (r *Repo) Name() string {
+	if r.RemoteURL != "" {
+		return path.Base(r.RemoteURL)
+	}
+	return filepath.Base(r.Root)
+}
+
+// Detect returns the repository containing dir, or an error if dir is not
+// in a git work tree.
+func Detect(dir string) (*Repo, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &Repo{Root: filepath.Clean(strings.TrimSpace(root))}
+
+	remote, err := git(dir, "remote", "get-url", "origin")
+	if err != nil {
+		// No origin: fall back to the first remote, if any
+		if remotes, err := git(dir, "remote"); err == nil {
+			if fields := strings.Fields(remotes); len(fields) > 0 {
+				remote, _ = git(dir, "remote", "get-url", fields[0])
+			}
+		}
+	}
+	repo.RemoteURL = NormalizeRemote(strings.TrimSpace(remote))
+
+	return repo, nil
+}
+
+// NormalizeRemote reduces a remote URL to host/path, so that
+// https://github.com/org/repo.git, git@github.com:org/repo and
+// ssh://git@github.com/org/repo all become github.com/org/repo. Credentials
+// are dropped. Local paths are returned cleaned.
+func NormalizeRemote(remote string) string {
+	if remote == "" {
+		return ""
+	}
+
+	var host, repoPath string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		host, repoPath = u.Hostname(), u.Path
+	} else if at := strings.Index(remote, "@"); at >= 0 && strings.Contains(remote[at:], ":") {
+		// scp-like syntax: user@host:path
+		rest := remote[at+1:]
+		colon := strings.Index(rest, ":")
+		host, repoPath = rest[:colon], rest[colon+1:]
+	} else if u != nil && u.Scheme == "file" {
+		return filepath.Clean(u.Path)
+	} else {
+		return filepath.Clean(remote)
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	return strings.ToLower(host) + "/" + repoPath
+}
+
+// State is the checkout a directory is at.
+type State struct {
+	// Commit is the SHA of HEAD, empty before the first commit.
+	Commit string `json:"commit"`
+	// Branch is empty when HEAD is detached.
+	Branch string `json:"branch"`
+	Dirty  bool   `json:"dirty"`
+	// ChangedFiles counts modified, staged and untracked files.
+	ChangedFiles int `json:"changed_files"`
+}
+
+// Status returns the checkout state of the repository containing dir.
+func Status(dir string) (*State, error) {
+	out, err := git(dir, "status", "--porcelain=v2", "--branch", "--untracked-files=normal")
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				state.Commit = oid
+			}
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				state.Branch = head
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			state.ChangedFiles++
+		}
+	}
+	state.Dirty = state.ChangedFiles > 0
+	return state, nil
+}
+
+// BlobURL links to a line of a file at a commit on the web host of a
+// normalized remote, or returns "" when the remote is not a web host.
+// GitLab hosts get GitLab's layout; everything else gets GitHub's, which
+// Gitea and Forgejo share.
+func BlobURL(remote, commit, file string, line int) string {
+	host, _, ok := strings.Cut(remote, "/")
+	if !ok || commit == "" || file == "" || !strings.Contains(host, ".") {
+		return ""
+	}
+
+	blob := "/blob/"
+	if strings.Contains(host, "gitlab") {
+		blob = "/-/blob/"
+	}
+	u := "https://" + remote + blob + commit + "/" + strings.TrimPrefix(filepath.ToSlash(file), "./")
+	if line > 0 {
+		u += "#L" + strconv.Itoa(line)
+	}
+	return u
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
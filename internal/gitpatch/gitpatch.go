@@ -0,0 +1,99 @@
+// Package gitpatch checks and applies unified diffs in a git work tree.
+package gitpatch
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Check reports whether patch applies cleanly in dir. On failure the error
+// carries git's explanation.
+func Check(dir, patch string) error {
+	_, err := git(dir, patch, "apply", "--check", "--recount", "-")
+	return err
+}
+
+// Apply creates branch from the current HEAD of dir, switches to it and
+// applies patch to the work tree without committing, so the change can be
+// reviewed with git diff. If the patch does not apply the repository is left
+// as it was: on the same branch, without the new one.
+func Apply(dir, patch, branch string) error {
+	if err := Check(dir, patch); err != nil {
+		return err
+	}
+
+	if _, err := git(dir, "", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		return fmt.Errorf("branch %s already exists", branch)
+	}
+
+	// Remember where HEAD was, as a branch name or a detached commit
+	previous := []string{"switch", "--detach"}
+	if name, err := git(dir, "", "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		previous = []string{"switch", strings.TrimSpace(name)}
+	} else if commit, err := git(dir, "", "rev-parse", "--verify", "HEAD"); err == nil {
+		previous = append(previous, strings.TrimSpace(commit))
+	} else {
+		return err
+	}
+
+	if _, err := git(dir, "", "switch", "-c", branch); err != nil {
+		return err
+	}
+
+	if _, err := git(dir, patch, "apply", "--recount", "-"); err != nil {
+		if _, switchErr := git(dir, "", previous...); switchErr != nil {
+			return fmt.Errorf("%w (and could not switch back: %v)", err, switchErr)
+		}
+		if _, deleteErr := git(dir, "", "branch", "-D", branch); deleteErr != nil {
+			return fmt.Errorf("%w (and could not delete branch %s: %v)", err, branch, deleteErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Changed lists the files a patch touches, relative to dir. Run from a
+// subdirectory, git apply roots patch paths there but reports them from
+// the top of the work tree, so that prefix is stripped.
+func Changed(dir, patch string) ([]string, error) {
+	out, err := git(dir, patch, "apply", "--numstat", "--recount", "-")
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := ""
+	if p, err := git(dir, "", "rev-parse", "--show-prefix"); err == nil {
+		prefix = strings.TrimSpace(p)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if fields := strings.Split(line, "\t"); len(fields) == 3 {
+			files = append(files, strings.TrimPrefix(fields[2], prefix))
+		}
+	}
+	return files, nil
+}
+
+func git(dir, stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
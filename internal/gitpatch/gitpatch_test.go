@@ -0,0 +1,134 @@
+package gitpatch
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const fix = `--- a/login.js
++++ b/login.js
+@@ -1,3 +1,3 @@
+ function login(user) {
+-  return db.query("SELECT * FROM users WHERE name = '" + user + "'")
++  return db.query("SELECT * FROM users WHERE name = ?", [user])
+ }
+`
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	return setupRepoIn(t, "")
+}
+
+// setupRepoIn creates a repository with login.js in sub, a directory below
+// the top of the work tree, and returns the repository root.
+func setupRepoIn(t *testing.T, sub string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, sub), 0755)
+	os.WriteFile(filepath.Join(dir, sub, "login.js"), []byte("function login(user) {\n  return db.query(\"SELECT * FROM users WHERE name = '\" + user + \"'\")\n}\n"), 0644)
+
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestCheck(t *testing.T) {
+	dir := setupRepo(t)
+
+	if err := Check(dir, fix); err != nil {
+		t.Errorf("Check() failed for a clean patch: %v", err)
+	}
+
+	stale := strings.Replace(fix, "SELECT *", "SELECT id", 2)
+	if err := Check(dir, stale); err == nil {
+		t.Error("Check() should fail when the context does not match")
+	}
+
+	if files, err := Changed(dir, fix); err != nil || len(files) != 1 || files[0] != "login.js" {
+		t.Errorf("Changed() = %v, %v", files, err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := setupRepo(t)
+
+	if err := Apply(dir, fix, "probe/fix-test"); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "login.js"))
+	if !strings.Contains(string(data), "[user]") {
+		t.Errorf("patch not applied:\n%s", data)
+	}
+
+	branch, _ := git(dir, "", "branch", "--show-current")
+	if strings.TrimSpace(branch) != "probe/fix-test" {
+		t.Errorf("Apply() should switch to the new branch, on %q", branch)
+	}
+
+	if err := Apply(dir, fix, "probe/fix-test"); err == nil {
+		t.Error("Apply() should refuse a patch that no longer applies")
+	}
+}
+
+func TestApplyInSubdirectory(t *testing.T) {
+	root := setupRepoIn(t, "services/api")
+	dir := filepath.Join(root, "services", "api")
+
+	if err := Check(dir, fix); err != nil {
+		t.Errorf("Check() failed for a target below the repository root: %v", err)
+	}
+	if files, err := Changed(dir, fix); err != nil || len(files) != 1 || files[0] != "login.js" {
+		t.Errorf("Changed() = %v, %v; want paths relative to the target", files, err)
+	}
+
+	if err := Apply(dir, fix, "probe/fix-sub"); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "login.js"))
+	if !strings.Contains(string(data), "[user]") {
+		t.Errorf("patch not applied:\n%s", data)
+	}
+}
+
+func TestApplyLeavesRepositoryOnFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a POSIX shell")
+	}
+	dir := setupRepo(t)
+
+	// The patch checks cleanly, but a hook changes the file once the new
+	// branch is checked out, so applying it fails
+	hook := filepath.Join(dir, ".git", "hooks", "post-checkout")
+	os.WriteFile(hook, []byte("#!/bin/sh\necho changed > login.js\n"), 0755)
+
+	if err := Apply(dir, fix, "probe/fix-stale"); err == nil {
+		t.Fatal("Apply() should fail when the patch no longer applies on the new branch")
+	}
+	os.Remove(hook)
+
+	branch, _ := git(dir, "", "branch", "--show-current")
+	if strings.TrimSpace(branch) != "main" {
+		t.Errorf("Apply() should switch back to main after failing, on %q", branch)
+	}
+	if _, err := git(dir, "", "rev-parse", "--verify", "--quiet", "refs/heads/probe/fix-stale"); err == nil {
+		t.Error("Apply() should not leave the new branch behind")
+	}
+}
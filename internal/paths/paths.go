@@ -42,6 +42,11 @@ func GetConfigPath() string {
 	return filepath.Join(GetAppDir(), "config.json")
 }
 
+// GetAPITokenPath returns the path of the token that guards the local API
+func GetAPITokenPath() string {
+	return filepath.Join(GetAppDir(), "api-token")
+}
+
 // GetProbesDir returns the directory where probe reports are stored
 func GetProbesDir() string {
 	return filepath.Join(GetAppDir(), "probes")
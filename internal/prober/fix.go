@@ -0,0 +1,133 @@
+package prober
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/findings"
+	"github.com/ndzuma/probeTool/internal/gitpatch"
+	"github.com/ndzuma/probeTool/internal/snippet"
+)
+
+// FixArgs configures a fix run.
+type FixArgs struct {
+	Provider string
+	Model    string
+	Verbose  bool
+}
+
+// GeneratePatch asks the agent for a minimal diff that remediates a finding,
+// checks it with git apply --check against the probe's target and stores it
+// with the finding. A patch that does not apply is still stored, with
+// Valid unset and git's output in CheckOutput.
+func GeneratePatch(ctx context.Context, database *sql.DB, findingID string, args FixArgs) (*db.Patch, error) {
+	agentScript, err := getAgentPath()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, model, apiKey, err := resolveProvider(args.Provider, args.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	return generatePatch(ctx, database, findingID, agentScript, provider, model, agentEnv(apiKey), args)
+}
+
+func generatePatch(ctx context.Context, database *sql.DB, findingID, agentScript, provider, model string, env []string, args FixArgs) (*db.Patch, error) {
+	session := findingSession{
+		Mode:        "fix",
+		AgentScript: agentScript,
+		Provider:    provider,
+		Model:       model,
+		Env:         env,
+		Verbose:     args.Verbose,
+	}
+
+	f, probe, output, usage, err := session.run(ctx, database, findingID)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal, err := findings.ParsePatch(output)
+	if err != nil {
+		return nil, err
+	}
+
+	// A patch that touches a secret no longer applies once it is masked;
+	// it is stored redacted anyway and fails the check below
+	redactor := loadRedactor()
+	diff, masked := redactor.Redact(proposal.Patch)
+	summary, _ := redactor.Redact(proposal.Summary)
+
+	p := &db.Patch{
+		FindingID:  f.ID,
+		Patch:      diff,
+		Summary:    summary,
+		BaseCommit: snippet.Commit(probe.Target),
+		Provider:   usage.Provider,
+		Model:      usage.Model,
+		CostUSD:    usage.CostUSD,
+	}
+
+	if err := gitpatch.Check(probe.Target, p.Patch); err != nil {
+		p.CheckOutput = err.Error()
+		if masked > 0 {
+			p.CheckOutput = fmt.Sprintf("%d secret value(s) in the patch were redacted: %s", masked, p.CheckOutput)
+		}
+	} else {
+		p.Valid = true
+	}
+
+	if err := db.SavePatch(database, p); err != nil {
+		return nil, fmt.Errorf("failed to save patch: %w", err)
+	}
+
+	return db.GetPatch(database, f.ID)
+}
+
+// PatchBranch is the branch a finding's patch is applied on by default.
+func PatchBranch(findingID string) string {
+	if len(findingID) > 8 {
+		findingID = findingID[:8]
+	}
+	return "probe/fix-" + findingID
+}
+
+// ApplyPatch applies the stored patch of a finding to its probe's target on
+// a new branch, leaving the change uncommitted for review. An empty branch
+// uses PatchBranch.
+func ApplyPatch(database *sql.DB, findingID, branch string) (*db.Patch, error) {
+	p, err := db.GetPatch(database, findingID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no patch for this finding. Run: probe fix %s", findingID)
+		}
+		return nil, err
+	}
+
+	f, err := db.GetFinding(database, findingID)
+	if err != nil {
+		return nil, err
+	}
+	probe, err := db.GetProbe(database, f.ProbeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load probe %s: %w", f.ProbeID, err)
+	}
+
+	if branch == "" {
+		branch = PatchBranch(findingID)
+	}
+
+	if err := gitpatch.Apply(probe.Target, p.Patch, branch); err != nil {
+		return nil, err
+	}
+
+	if err := db.MarkPatchApplied(database, findingID, branch); err != nil {
+		return nil, err
+	}
+
+	return db.GetPatch(database, findingID)
+}
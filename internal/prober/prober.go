@@ -3,6 +3,8 @@ package prober
 import (
 	"bufio"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,10 +13,16 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/ndzuma/probeTool/internal/agent"
 	"github.com/ndzuma/probeTool/internal/config"
 	"github.com/ndzuma/probeTool/internal/db"
 	"github.com/ndzuma/probeTool/internal/findings"
+	"github.com/ndzuma/probeTool/internal/gitinfo"
 	"github.com/ndzuma/probeTool/internal/paths"
+	"github.com/ndzuma/probeTool/internal/process"
+	"github.com/ndzuma/probeTool/internal/redact"
+	"github.com/ndzuma/probeTool/internal/snippet"
+	"github.com/ndzuma/probeTool/internal/version"
 )
 
 var (
@@ -43,27 +51,18 @@ func getAgentPath() (string, error) {
 	return agentScript, nil
 }
 
-func RunProbe(ctx context.Context, args ProbeArgs) (string, error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("failed to get working directory: %w", err)
-	}
-
-	agentScript, err := getAgentPath()
-	if err != nil {
-		return "", err
-	}
-
+// resolveProvider picks the provider and model for an agent run, falling
+// back to the configured defaults, and returns the provider's API key.
+func resolveProvider(provider, model string) (string, string, string, error) {
 	cfg, err := config.Load()
 	if err != nil {
-		return "", fmt.Errorf("config load failed: %w", err)
+		return "", "", "", fmt.Errorf("config load failed: %w", err)
 	}
 
 	if len(cfg.Providers) == 0 {
-		return "", fmt.Errorf("no provider configured\n\nFirst, add a provider:\n  probe config add-provider openrouter\n\nYou will be prompted for your API key and model preferences.")
+		return "", "", "", fmt.Errorf("no provider configured\n\nFirst, add a provider:\n  probe config add-provider openrouter\n\nYou will be prompted for your API key and model preferences.")
 	}
 
-	provider := args.Provider
 	if provider == "" {
 		if cfg.Default != "" {
 			provider = cfg.Default
@@ -77,14 +76,13 @@ func RunProbe(ctx context.Context, args ProbeArgs) (string, error) {
 
 	providerCfg, ok := cfg.Providers[provider]
 	if !ok {
-		return "", fmt.Errorf("provider '%s' not configured\nRun: probe config add-provider %s", provider, provider)
+		return "", "", "", fmt.Errorf("provider '%s' not configured\nRun: probe config add-provider %s", provider, provider)
 	}
 
 	if providerCfg.APIKey == "" {
-		return "", fmt.Errorf("API key missing for provider '%s'\nRun: probe config set-key %s <key>", provider, provider)
+		return "", "", "", fmt.Errorf("API key missing for provider '%s'\nRun: probe config set-key %s <key>", provider, provider)
 	}
 
-	model := args.Model
 	if model == "" {
 		model = providerCfg.DefaultModel
 		if model == "" {
@@ -92,6 +90,85 @@ func RunProbe(ctx context.Context, args ProbeArgs) (string, error) {
 		}
 	}
 
+	return provider, model, providerCfg.APIKey, nil
+}
+
+// agentEnv is the environment the agent needs to reach the provider.
+func agentEnv(apiKey string) []string {
+	return append(os.Environ(),
+		"ANTHROPIC_BASE_URL=https://openrouter.ai/api",
+		"ANTHROPIC_AUTH_TOKEN="+apiKey,
+		"ANTHROPIC_API_KEY=",
+	)
+}
+
+// recordRepository files a probe under the project of its target and
+// records the commit, branch and local changes the scan starts from.
+// Targets outside a git work tree are their own project and have no
+// provenance. Failures are warnings; they do not stop the probe.
+func recordRepository(database *sql.DB, id, target string) {
+	repo, err := gitinfo.Detect(target)
+	if err != nil {
+		repo = &gitinfo.Repo{Root: target}
+	}
+	project, err := db.EnsureProject(database, *repo)
+	if err == nil {
+		err = db.SetProbeProject(database, id, project.ID)
+	}
+	if err != nil {
+		fmt.Printf("%s Warning: failed to link probe to its project: %v\n", yellow("⚠️"), err)
+	}
+
+	state, err := gitinfo.Status(target)
+	if err != nil {
+		return
+	}
+	err = db.UpdateProbeGit(database, id, db.GitProvenance{
+		GitCommit:       state.Commit,
+		GitBranch:       state.Branch,
+		GitDirty:        state.Dirty,
+		GitRemote:       repo.RemoteURL,
+		GitChangedFiles: state.ChangedFiles,
+	})
+	if err != nil {
+		fmt.Printf("%s Warning: failed to record git provenance: %v\n", yellow("⚠️"), err)
+	}
+}
+
+// recordVersions notes the binary, agent bundle, provider and model a probe
+// runs with before it starts, so a failed probe keeps them too.
+func recordVersions(database *sql.DB, id, provider, model string) {
+	bundle := agent.Describe(paths.GetAgentDir())
+	err := db.UpdateProbeVersions(database, id, db.Versions{
+		ProbeVersion: version.Version,
+		AgentVersion: bundle.Version,
+		PromptHash:   bundle.PromptHash,
+		SkillHash:    bundle.SkillHash,
+	})
+	if err == nil {
+		err = db.UpdateProbeUsage(database, id, db.Usage{Provider: provider, Model: model})
+	}
+	if err != nil {
+		fmt.Printf("%s Warning: failed to record probe versions: %v\n", yellow("⚠️"), err)
+	}
+}
+
+func RunProbe(ctx context.Context, args ProbeArgs) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	agentScript, err := getAgentPath()
+	if err != nil {
+		return "", err
+	}
+
+	provider, model, apiKey, err := resolveProvider(args.Provider, args.Model)
+	if err != nil {
+		return "", err
+	}
+
 	id := fmt.Sprintf("%s-%s", time.Now().Format("2006-01-02-150405"), args.Type)
 
 	probesDir := paths.GetProbesDir()
@@ -104,11 +181,13 @@ func RunProbe(ctx context.Context, args ProbeArgs) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to initialize database: %w", err)
 	}
-	defer database.Close()
+	defer db.Close(database)
 
 	if err := db.InsertProbe(database, id, args.Type, cwd, absPath); err != nil {
 		return "", fmt.Errorf("failed to insert probe: %w", err)
 	}
+	recordRepository(database, id, cwd)
+	recordVersions(database, id, provider, model)
 
 	fmt.Printf("%s Starting probe audit...\n", cyan("🔍"))
 	fmt.Printf("  Target: %s\n", cwd)
@@ -124,23 +203,29 @@ func RunProbe(ctx context.Context, args ProbeArgs) (string, error) {
 		"--verbose="+fmt.Sprintf("%t", args.Verbose),
 	)
 
-	cmd.Env = append(os.Environ(),
-		"ANTHROPIC_BASE_URL=https://openrouter.ai/api",
-		"ANTHROPIC_AUTH_TOKEN="+providerCfg.APIKey,
-		"ANTHROPIC_API_KEY=",
-	)
+	cmd.Env = agentEnv(apiKey)
 
 	cmd.Dir = cwd
 
 	stdout, _ := cmd.StdoutPipe()
 	stderr, _ := cmd.StderrPipe()
 
+	usage := db.Usage{Provider: provider, Model: model}
+	stdoutDone := make(chan struct{})
+
 	go func() {
+		defer close(stdoutDone)
 		scanner := bufio.NewScanner(stdout)
 		for scanner.Scan() {
 			line := scanner.Text()
 
-			if strings.HasPrefix(line, "PROGRESS:") {
+			if strings.HasPrefix(line, "USAGE:") {
+				if u, err := ParseUsageLine(line); err == nil {
+					u.Provider = provider
+					u.Model = model
+					usage = u
+				}
+			} else if strings.HasPrefix(line, "PROGRESS:") {
 				parts := strings.Split(strings.TrimPrefix(line, "PROGRESS:"), ":")
 				stage := parts[0]
 
@@ -184,31 +269,153 @@ func RunProbe(ctx context.Context, args ProbeArgs) (string, error) {
 		return "", fmt.Errorf("failed to start probe: %w", err)
 	}
 
-	if err := cmd.Wait(); err != nil {
+	<-stdoutDone
+	waitErr := cmd.Wait()
+
+	if err := db.UpdateProbeUsage(database, id, usage); err != nil {
+		fmt.Printf("%s Warning: failed to record probe usage: %v\n", yellow("⚠️"), err)
+	}
+
+	// The report file is redacted before anything else, so a failed run
+	// does not leave a partial report with secrets on disk
+	redactor := loadRedactor()
+	fileContent, readErr := os.ReadFile(absPath)
+	var report string
+	var masked int
+	if readErr == nil {
+		report, masked = redactor.Redact(string(fileContent))
+		if masked > 0 {
+			if err := os.WriteFile(absPath, []byte(report), 0644); err != nil {
+				fmt.Printf("%s Warning: failed to redact report file: %v\n", yellow("⚠️"), err)
+			}
+		}
+	}
+
+	if waitErr != nil {
 		db.UpdateProbeStatus(database, id, "failed")
-		return "", fmt.Errorf("probe failed: %w", err)
+		return "", fmt.Errorf("probe failed: %w", waitErr)
 	}
 
 	if err := db.UpdateProbeStatus(database, id, "completed"); err != nil {
 		fmt.Printf("%s Warning: failed to update probe status: %v\n", yellow("⚠️"), err)
 	}
 
-	if fileContent, err := os.ReadFile(absPath); err == nil {
-		parsedFindings := findings.ParseMarkdown(string(fileContent))
+	if readErr == nil {
+		// Findings are parsed from the original report so their file and
+		// line references survive; their text is redacted below.
+		if err := db.SaveReport(database, id, report); err != nil {
+			fmt.Printf("%s Warning: failed to store report: %v\n", yellow("⚠️"), err)
+		}
+		parsedFindings := findings.ParseMarkdownWithSimilarity(string(fileContent), similarityThreshold(args.Type))
+		records := make([]db.Finding, 0, len(parsedFindings))
 		for _, f := range parsedFindings {
-			if err := db.InsertFinding(database, f.ID, id, f.Text, f.Severity); err != nil {
-				fmt.Printf("%s Warning: failed to insert finding: %v\n", yellow("⚠️"), err)
+			masked += redactFinding(redactor, &f)
+			records = append(records, db.Finding{
+				ID:          f.ID,
+				ProbeID:     id,
+				Fingerprint: f.Fingerprint,
+				Text:        f.Text,
+				Severity:    f.Severity,
+				File:        f.File,
+				LineStart:   f.LineStart,
+				LineEnd:     f.LineEnd,
+				CWE:         f.CWE,
+				OWASP:       f.OWASP,
+				Description: f.Description,
+				Remediation: f.Remediation,
+				Aliases:     f.Aliases,
+			})
+		}
+		if err := db.CreateFindings(database, records); err != nil {
+			fmt.Printf("%s Warning: failed to insert findings: %v\n", yellow("⚠️"), err)
+			records = nil
+		}
+		commit := snippet.Commit(cwd)
+		for _, record := range records {
+			if err := captureSnippet(database, redactor, cwd, commit, record); err != nil && args.Verbose {
+				fmt.Printf("%s No snippet for %s: %v\n", blue("🔍"), record.File, err)
 			}
 		}
-		if len(parsedFindings) > 0 {
-			fmt.Printf("%s Extracted %d findings from report\n", green("📋"), len(parsedFindings))
+		if len(records) > 0 {
+			fmt.Printf("%s Extracted %d findings from report\n", green("📋"), len(records))
+		}
+		if masked > 0 {
+			fmt.Printf("%s Redacted %d secret values\n", green("🔒"), masked)
 		}
 	}
 
-	url := fmt.Sprintf("http://localhost:37330/probes/%s", id)
+	url := fmt.Sprintf("%s/probes/%s", process.DashboardURL(), id)
 	fmt.Println()
 	fmt.Printf("%s View assessment: %s\n", green("🔗"), cyan(url))
 	fmt.Printf("%s Report saved: %s\n", green("📄"), absPath)
 
 	return id, nil
 }
+
+// loadRedactor builds the configured redactor. A config that cannot be
+// read or has an invalid pattern falls back to the built-in patterns, so a
+// typo never stores secrets unmasked.
+func loadRedactor() *redact.Redactor {
+	cfg, err := config.Load()
+	if err == nil {
+		var r *redact.Redactor
+		if r, err = cfg.Redactor(); err == nil {
+			return r
+		}
+	}
+	fmt.Printf("%s Warning: using the built-in redaction patterns: %v\n", yellow("⚠️"), err)
+	r, _ := redact.New(nil, false)
+	return r
+}
+
+// redactFinding masks secrets in the text fields of a parsed finding. The
+// file and line references are left as they are.
+func redactFinding(r *redact.Redactor, f *findings.Finding) int {
+	count := 0
+	for _, field := range []*string{&f.Text, &f.Description, &f.Remediation} {
+		var n int
+		*field, n = r.Redact(*field)
+		count += n
+	}
+	return count + r.Strings(f.Aliases)
+}
+
+// similarityThreshold is the duplicate-merging threshold configured for a
+// probe type, or zero for the default.
+func similarityThreshold(probeType string) float64 {
+	cfg, err := config.Load()
+	if err != nil {
+		return 0
+	}
+	return cfg.SimilarityThreshold(probeType)
+}
+
+// captureSnippet stores the code a finding points at as it is now, so the
+// finding still shows the vulnerable lines after they are fixed.
+func captureSnippet(database *sql.DB, redactor *redact.Redactor, target, commit string, f db.Finding) error {
+	if f.File == "" || f.LineStart <= 0 {
+		return nil
+	}
+
+	s, err := snippet.Capture(target, f.File, f.LineStart, f.LineEnd)
+	if err != nil {
+		return err
+	}
+
+	content, _ := redactor.Redact(s.Content)
+	return db.SaveSnippet(database, &db.Snippet{
+		FindingID: f.ID,
+		File:      s.File,
+		StartLine: s.StartLine,
+		EndLine:   s.EndLine,
+		Content:   content,
+		Commit:    commit,
+	})
+}
+
+// ParseUsageLine decodes a "USAGE:{...}" line emitted by the agent.
+func ParseUsageLine(line string) (db.Usage, error) {
+	var u db.Usage
+	err := json.Unmarshal([]byte(strings.TrimPrefix(line, "USAGE:")), &u)
+	return u, err
+}
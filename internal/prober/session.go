@@ -0,0 +1,121 @@
+package prober
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ndzuma/probeTool/internal/db"
+)
+
+// runAgent starts node with args in dir and calls onLine for every line the
+// agent prints to stdout. Tests replace it to avoid spawning the agent.
+var runAgent = func(ctx context.Context, dir string, env, args []string, onLine func(string)) error {
+	cmd := exec.CommandContext(ctx, "node", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start agent: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		onLine(scanner.Text())
+	}
+
+	return cmd.Wait()
+}
+
+// findingSession is an agent run scoped to a single finding, such as a
+// verification or a fix.
+type findingSession struct {
+	Mode        string
+	AgentScript string
+	Provider    string
+	Model       string
+	Env         []string
+	Verbose     bool
+}
+
+// run hands the finding to the agent in the probe's target directory and
+// returns the finding, its probe and the agent's raw output.
+func (s findingSession) run(ctx context.Context, database *sql.DB, findingID string) (*db.Finding, *db.Probe, string, db.Usage, error) {
+	usage := db.Usage{Provider: s.Provider, Model: s.Model}
+
+	f, err := db.GetFinding(database, findingID)
+	if err != nil {
+		return nil, nil, "", usage, err
+	}
+
+	probe, err := db.GetProbe(database, f.ProbeID)
+	if err != nil {
+		return nil, nil, "", usage, fmt.Errorf("failed to load probe %s: %w", f.ProbeID, err)
+	}
+	if info, err := os.Stat(probe.Target); err != nil || !info.IsDir() {
+		return nil, nil, "", usage, fmt.Errorf("probe target %s is no longer available", probe.Target)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "probe-"+s.Mode+"-")
+	if err != nil {
+		return nil, nil, "", usage, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	findingPath := filepath.Join(tmpDir, "finding.json")
+	outPath := filepath.Join(tmpDir, "output.md")
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, nil, "", usage, err
+	}
+	if err := os.WriteFile(findingPath, data, 0600); err != nil {
+		return nil, nil, "", usage, err
+	}
+
+	onLine := func(line string) {
+		switch {
+		case strings.HasPrefix(line, "USAGE:"):
+			if u, err := ParseUsageLine(line); err == nil {
+				usage.CostUSD = u.CostUSD
+			}
+		case strings.HasPrefix(line, "VERBOSE:"):
+			if s.Verbose {
+				fmt.Printf("%s %s\n", blue("🔍"), strings.TrimPrefix(line, "VERBOSE:"))
+			}
+		case strings.HasPrefix(line, "ERROR:"):
+			fmt.Printf("%s %s\n", red("❌"), strings.TrimPrefix(line, "ERROR:"))
+		}
+	}
+
+	agentArgs := []string{
+		s.AgentScript,
+		"--mode=" + s.Mode,
+		"--finding=" + findingPath,
+		"--target=" + probe.Target,
+		"--out=" + outPath,
+		"--model=" + s.Model,
+		"--verbose=" + fmt.Sprintf("%t", s.Verbose),
+	}
+	if err := runAgent(ctx, probe.Target, s.Env, agentArgs, onLine); err != nil {
+		return nil, nil, "", usage, fmt.Errorf("agent run failed: %w", err)
+	}
+
+	output, err := os.ReadFile(outPath)
+	if err != nil {
+		return nil, nil, "", usage, fmt.Errorf("agent produced no output: %w", err)
+	}
+
+	return f, probe, string(output), usage, nil
+}
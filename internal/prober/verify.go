@@ -0,0 +1,75 @@
+package prober
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/findings"
+)
+
+// VerifyArgs configures a verification run. Actor is recorded in the
+// finding's history.
+type VerifyArgs struct {
+	Provider string
+	Model    string
+	Verbose  bool
+	Actor    string
+}
+
+// VerifyFinding asks the agent whether a finding is still present in its
+// probe's target, records the verdict and updates the finding's state. The
+// returned state change is the history entry linked to the verification.
+func VerifyFinding(ctx context.Context, database *sql.DB, findingID string, args VerifyArgs) (*db.Verification, *db.StateChange, error) {
+	agentScript, err := getAgentPath()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider, model, apiKey, err := resolveProvider(args.Provider, args.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return verifyFinding(ctx, database, findingID, agentScript, provider, model, agentEnv(apiKey), args)
+}
+
+func verifyFinding(ctx context.Context, database *sql.DB, findingID, agentScript, provider, model string, env []string, args VerifyArgs) (*db.Verification, *db.StateChange, error) {
+	session := findingSession{
+		Mode:        "verify",
+		AgentScript: agentScript,
+		Provider:    provider,
+		Model:       model,
+		Env:         env,
+		Verbose:     args.Verbose,
+	}
+
+	f, _, output, usage, err := session.run(ctx, database, findingID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verdict := findings.ParseVerdict(output)
+	// The reasoning is also copied into the finding's history
+	reasoning, _ := loadRedactor().Redact(verdict.Reasoning)
+	v := &db.Verification{
+		FindingID: f.ID,
+		Verdict:   verdict.Verdict,
+		Reasoning: reasoning,
+		Provider:  usage.Provider,
+		Model:     usage.Model,
+		CostUSD:   usage.CostUSD,
+	}
+
+	actor := args.Actor
+	if actor == "" {
+		actor = "verify"
+	}
+	change, err := db.RecordVerification(database, v, actor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to record verification: %w", err)
+	}
+
+	return v, change, nil
+}
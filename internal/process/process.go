@@ -9,11 +9,6 @@ import (
 	"syscall"
 )
 
-const (
-	ServerPort = "37330"
-	NextJSPort = "37331"
-)
-
 var (
 	serverPIDFile string
 	trayPIDFile   string
@@ -114,6 +109,7 @@ func StopServer() error {
 	}
 
 	RemoveServerPID()
+	RemoveServerState()
 	return nil
 }
 
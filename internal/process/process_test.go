@@ -1,6 +1,7 @@
 package process
 
 import (
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -119,3 +120,76 @@ func TestStopTrayNoPIDFile(t *testing.T) {
 		t.Error("StopTray should return error when no PID file exists")
 	}
 }
+
+func TestListenFallsBackWhenPortBusy(t *testing.T) {
+	busy, err := Listen("127.0.0.1", 0)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer busy.Close()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	ln, err := Listen("127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("Listen() on a busy port should fall back: %v", err)
+	}
+	defer ln.Close()
+	if got := ln.Addr().(*net.TCPAddr).Port; got == port || got == 0 {
+		t.Errorf("Listen() on busy port %d got port %d, want another port", port, got)
+	}
+
+	if free, err := FreePort("127.0.0.1", port); err != nil || free == port {
+		t.Errorf("FreePort() = %d, %v; want a port other than %d", free, err, port)
+	}
+	if _, err := Listen("256.0.0.1", 0); err == nil {
+		t.Error("Listen() on an invalid host should fail")
+	}
+}
+
+func TestServerState(t *testing.T) {
+	defer RemoveServerPID()
+	defer RemoveServerState()
+
+	want := ServerState{PID: os.Getpid(), Host: "127.0.0.1", Port: 41000, URL: URL("127.0.0.1", 41000), NextJSPort: 41001}
+	if err := WriteServerState(want); err != nil {
+		t.Fatalf("WriteServerState() failed: %v", err)
+	}
+	if _, err := RunningServer(); err == nil {
+		t.Error("RunningServer() without a running server should fail")
+	}
+
+	WriteServerPID(os.Getpid())
+	got, err := RunningServer()
+	if err != nil {
+		t.Fatalf("RunningServer() failed: %v", err)
+	}
+	if *got != want {
+		t.Errorf("RunningServer() = %+v, want %+v", *got, want)
+	}
+	if DashboardURL() != "http://127.0.0.1:41000" {
+		t.Errorf("DashboardURL() = %q, want the running server's", DashboardURL())
+	}
+
+	WriteServerPID(os.Getpid() + 1)
+	if _, err := RunningServer(); err == nil {
+		t.Error("RunningServer() should ignore state written by another server")
+	}
+}
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"127.0.0.1", "http://127.0.0.1:8080"},
+		{"0.0.0.0", "http://127.0.0.1:8080"},
+		{"::1", "http://[::1]:8080"},
+		{"[::]", "http://127.0.0.1:8080"},
+		{"192.168.1.5", "http://192.168.1.5:8080"},
+	}
+	for _, tt := range tests {
+		if got := URL(tt.host, 8080); got != tt.want {
+			t.Errorf("URL(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ndzuma/probeTool/internal/config"
+)
+
+// DefaultNextJSPort is the port the internal Next.js server tries first.
+const DefaultNextJSPort = 37331
+
+// ServerState is what a running server records about itself, so that
+// probe status, the tray and other commands can reach it on the address it
+// actually got.
+type ServerState struct {
+	PID        int       `json:"pid"`
+	Host       string    `json:"host"`
+	Port       int       `json:"port"`
+	URL        string    `json:"url"`
+	NextJSPort int       `json:"nextjs_port"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+func serverStateFile() string {
+	return filepath.Join(filepath.Dir(serverPIDFile), "server.json")
+}
+
+func WriteServerState(s ServerState) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(serverStateFile(), data, 0644)
+}
+
+func ReadServerState() (*ServerState, error) {
+	data, err := os.ReadFile(serverStateFile())
+	if err != nil {
+		return nil, err
+	}
+	var s ServerState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func RemoveServerState() error {
+	return os.Remove(serverStateFile())
+}
+
+// RunningServer returns the state of the running server. It fails when no
+// server is running or the running one has not recorded its address yet,
+// so a state file left by a crashed server is never used.
+func RunningServer() (*ServerState, error) {
+	if !IsServerRunning() {
+		return nil, fmt.Errorf("server not running")
+	}
+	state, err := ReadServerState()
+	if err != nil {
+		return nil, fmt.Errorf("server address unknown: %w", err)
+	}
+	if pid, _ := ReadServerPID(); pid != state.PID {
+		return nil, fmt.Errorf("server address unknown: state is from another server")
+	}
+	return state, nil
+}
+
+// DashboardURL is the dashboard of the running server or, when none is
+// running, the address a server would listen on.
+func DashboardURL() string {
+	if state, err := RunningServer(); err == nil {
+		return state.URL
+	}
+	host, port := config.DefaultHost, config.DefaultPort
+	if cfg, err := config.Load(); err == nil {
+		if h, p, err := cfg.ServerAddress(); err == nil {
+			host, port = h, p
+		}
+	}
+	return URL(host, port)
+}
+
+// URL is the address a browser uses for a server listening on host and
+// port. Wildcard hosts are reached through the loopback interface.
+func URL(host string, port int) string {
+	switch host {
+	case "", "0.0.0.0", "::", "[::]":
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+}
+
+// Listen listens on host and port. When the port is taken it falls back to
+// a free port the system picks, so a second program on the default port
+// does not keep the dashboard from starting; compare the listener's port
+// with the one asked for to tell.
+func Listen(host string, port int) (net.Listener, error) {
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err == nil || !isAddrInUse(err) {
+		return ln, err
+	}
+	return net.Listen("tcp", net.JoinHostPort(host, "0"))
+}
+
+// FreePort returns port if it is free on host, or else a free port the
+// system picks.
+func FreePort(host string, port int) (int, error) {
+	ln, err := Listen(host, port)
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+func isAddrInUse(err error) bool {
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return true
+	}
+	// Windows reports WSAEADDRINUSE, which syscall does not map
+	msg := err.Error()
+	return strings.Contains(msg, "address already in use") ||
+		strings.Contains(msg, "Only one usage of each socket address")
+}
@@ -1,68 +1,138 @@
 package server
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/ndzuma/probeTool/internal/auth"
+	"github.com/ndzuma/probeTool/internal/compare"
 	"github.com/ndzuma/probeTool/internal/config"
 	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/gitinfo"
+	"github.com/ndzuma/probeTool/internal/prober"
 	"github.com/ndzuma/probeTool/internal/version"
 )
 
 var database *sql.DB
 
-// StartServer starts the API server on localhost:3030.
-// The Next.js frontend runs separately on :3000 and proxies API calls here.
-func StartServer(dbConn *sql.DB) {
-	database = dbConn
-
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("/api/probes", cors(handleProbes))
-	mux.HandleFunc("/api/probes/", cors(handleProbeDetail))
-	mux.HandleFunc("/api/findings/", cors(handleFindings))
-	mux.HandleFunc("/api/config", cors(handleConfig))
-	mux.HandleFunc("/api/file-tree/", cors(handleFileTree))
-	mux.HandleFunc("/api/version", cors(handleVersion))
-
-	fmt.Println("🌐 API server starting on http://localhost:3030")
-	fmt.Println("📡 Frontend: cd web && npm run dev (http://localhost:3000)")
-	err := http.ListenAndServe(":3030", mux)
-	if err != nil {
-		fmt.Printf("❌ Server error: %v\n", err)
-	}
-}
+// verifyFinding runs the verification agent; tests replace it.
+var verifyFinding = prober.VerifyFinding
 
 func RegisterRoutes(mux *http.ServeMux, dbConn *sql.DB) {
 	database = dbConn
 
-	mux.HandleFunc("/api/probes", cors(handleProbes))
-	mux.HandleFunc("/api/probes/", cors(handleProbeDetail))
-	mux.HandleFunc("/api/findings/", cors(handleFindings))
-	mux.HandleFunc("/api/config", cors(handleConfig))
-	mux.HandleFunc("/api/file-tree/", cors(handleFileTree))
+	mux.HandleFunc("/api/probes", handleProbes)
+	mux.HandleFunc("/api/probes/", handleProbeDetail)
+	mux.HandleFunc("/api/findings", handleFindingList)
+	mux.HandleFunc("/api/findings/", handleFindings)
+	mux.HandleFunc("/api/config", handleConfig)
+	mux.HandleFunc("/api/file-tree/", handleFileTree)
+	mux.HandleFunc("/api/usage", handleUsage)
+	mux.HandleFunc("/api/search", handleSearch)
+	mux.HandleFunc("/api/projects", handleProjects)
+	mux.HandleFunc("/api/projects/", handleProjectDetail)
+	mux.HandleFunc("/api/version", handleVersion)
 }
 
 // ─── Middleware ──────────────────────────────────────────────────────────────
 
-func cors(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// Protect guards h, the dashboard and its API. Requests must be addressed
+// to the host of one of origins, so a page on another site cannot reach the
+// server by rebinding its own name to 127.0.0.1. Under /api/ they must
+// also carry the API token, and browsers may only make cross-origin calls
+// from origins.
+func Protect(h http.Handler, token string, origins ...string) http.Handler {
+	hosts := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		if u, err := url.Parse(origin); err == nil {
+			hosts[u.Host] = true
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hosts[r.Host] {
+			writeError(w, http.StatusForbidden, "Host not allowed")
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !slices.Contains(origins, origin) {
+				writeError(w, http.StatusForbidden, "Origin not allowed")
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Expose-Headers", "Link, X-Next-Cursor")
+			w.Header().Add("Vary", "Origin")
+		}
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
-		next(w, r)
+		if !auth.Valid(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="probe"`)
+			writeError(w, http.StatusUnauthorized, "Missing or invalid API token")
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// TokenMeta names the meta tag that carries the API token in dashboard
+// pages.
+const TokenMeta = "probe-api-token"
+
+// InjectToken returns a reverse proxy ModifyResponse hook that adds the API
+// token to the head of HTML pages as a meta tag, where the dashboard reads
+// it. Other sites cannot read the page, so the token stays with the
+// dashboard. Compressed responses are left alone; strip Accept-Encoding
+// from proxied requests so pages arrive uncompressed.
+func InjectToken(token string) func(*http.Response) error {
+	tag := []byte(`<meta name="` + TokenMeta + `" content="` + html.EscapeString(token) + `">`)
+
+	return func(resp *http.Response) error {
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || resp.Header.Get("Content-Encoding") != "" {
+			return nil
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if head := bytes.Index(bytes.ToLower(body), []byte("<head")); head >= 0 {
+			if end := bytes.IndexByte(body[head:], '>'); end >= 0 {
+				at := head + end + 1
+				body = bytes.Join([][]byte{body[:at], tag, body[at:]}, nil)
+			}
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		resp.Header.Set("Cache-Control", "no-store")
+		return nil
 	}
 }
 
@@ -78,7 +148,7 @@ func writeError(w http.ResponseWriter, status int, msg string) {
 	writeJSON(w, status, map[string]string{"error": msg})
 }
 
-// ─── GET /api/probes ────────────────────────────────────────────────────────
+// ─── GET /api/probes?target=&project=&status=&type=&since=&until=&sort=&order=&cursor= ─
 
 func handleProbes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -86,20 +156,75 @@ func handleProbes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	probes, err := db.GetAllProbes(database)
+	params := r.URL.Query()
+	filter := db.ProbeFilter{
+		Target:       params.Get("target"),
+		Project:      params.Get("project"),
+		Status:       params.Get("status"),
+		Type:         params.Get("type"),
+		Provider:     params.Get("provider"),
+		Model:        params.Get("model"),
+		ProbeVersion: params.Get("probe_version"),
+		AgentVersion: params.Get("agent_version"),
+		PromptHash:   params.Get("prompt_hash"),
+		SkillHash:    params.Get("skill_hash"),
+		Sort:         params.Get("sort"),
+		Cursor:       params.Get("cursor"),
+	}
+
+	switch params.Get("order") {
+	case "", "desc":
+	case "asc":
+		filter.Asc = true
+	default:
+		writeError(w, http.StatusBadRequest, "Invalid order (expected asc or desc)")
+		return
+	}
+
+	var err error
+	if filter.Since, err = db.ParseSince(params.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.Until, err = db.ParseSince(params.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			writeError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+	if _, ok := db.ProbeSortKeys[filter.Sort]; filter.Sort != "" && !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid sort %q", filter.Sort))
+		return
+	}
+
+	page, err := db.ListProbes(database, filter)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching probes: %v", err))
 		return
 	}
 
-	if probes == nil {
-		probes = []db.Probe{}
+	// The body stays a plain array; the next page is linked from headers.
+	if page.NextCursor != "" {
+		next := *r.URL
+		q := next.Query()
+		q.Set("cursor", page.NextCursor)
+		next.RawQuery = q.Encode()
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
 	}
 
-	writeJSON(w, http.StatusOK, probes)
+	writeJSON(w, http.StatusOK, page.Probes)
 }
 
-// ─── GET /api/probes/{id}  ·  GET /api/probes/{id}/content ──────────────────
+// ─── GET|DELETE /api/probes/{id}  ·  /content  ·  /compare/{other} ──────────
 
 func handleProbeDetail(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/probes/")
@@ -117,6 +242,17 @@ func handleProbeDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sub-route: /api/probes/{id}/compare/{other}
+	if len(parts) > 1 && strings.HasPrefix(parts[1], "compare/") {
+		handleProbeCompare(w, r, probeID, strings.TrimPrefix(parts[1], "compare/"))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		handleDeleteProbe(w, r, probeID)
+		return
+	}
+
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
@@ -129,24 +265,40 @@ func handleProbeDetail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := map[string]interface{}{
-		"id":         probe.ID,
-		"type":       probe.Type,
-		"target":     probe.Target,
-		"file_path":  probe.FilePath,
-		"status":     probe.Status,
-		"created_at": probe.CreatedAt,
+		"id":            probe.ID,
+		"type":          probe.Type,
+		"target":        probe.Target,
+		"file_path":     probe.FilePath,
+		"status":        probe.Status,
+		"created_at":    probe.CreatedAt,
+		"provider":      probe.Provider,
+		"model":         probe.Model,
+		"input_tokens":  probe.InputTokens,
+		"output_tokens": probe.OutputTokens,
+		"cost_usd":      probe.CostUSD,
+		"duration_ms":   probe.DurationMS,
+		"num_turns":     probe.NumTurns,
+		"probe_version": probe.ProbeVersion,
+		"agent_version": probe.AgentVersion,
+		"prompt_hash":   probe.PromptHash,
+		"skill_hash":    probe.SkillHash,
+		"project_id":    probe.ProjectID,
+		"git":           gitProvenance(probe),
 	}
 
-	if probe.FilePath != "" {
-		content, err := os.ReadFile(probe.FilePath)
-		if err == nil {
-			response["content"] = string(content)
-		}
+	// A report that cannot be found is reported rather than omitted.
+	if content, err := db.ReportContent(database, probe); err == nil {
+		response["content"] = content
+	} else if probe.FilePath != "" {
+		response["content_error"] = err.Error()
+	}
+	if report, err := db.GetReport(database, probeID); err == nil {
+		response["report_sha256"] = report.SHA256
 	}
 
 	findingsList, err := db.GetFindingsByProbe(database, probeID)
 	if err == nil {
-		response["findings"] = findingsList
+		response["findings"] = withSourceURLs(probe, findingsList)
 	} else {
 		response["findings"] = []db.Finding{}
 	}
@@ -154,6 +306,49 @@ func handleProbeDetail(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, response)
 }
 
+// gitProvenance is the repository state a probe ran against, or nil for a
+// target outside git.
+func gitProvenance(p *db.Probe) map[string]interface{} {
+	if p.GitCommit == "" && p.GitBranch == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"commit":        p.GitCommit,
+		"branch":        p.GitBranch,
+		"dirty":         p.GitDirty,
+		"remote":        p.GitRemote,
+		"changed_files": p.GitChangedFiles,
+	}
+}
+
+// findingWithSource is a finding with a link to its lines at the commit the
+// probe scanned.
+type findingWithSource struct {
+	db.Finding
+	SourceURL string `json:"source_url,omitempty"`
+}
+
+func withSourceURLs(p *db.Probe, list []db.Finding) []findingWithSource {
+	// Finding paths are relative to the target, links to the repository root.
+	prefix := ""
+	if p.GitRemote != "" && p.ProjectID != "" {
+		if project, err := db.GetProject(database, p.ProjectID); err == nil {
+			if rel, err := filepath.Rel(project.Root, p.Target); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+				prefix = rel
+			}
+		}
+	}
+
+	out := make([]findingWithSource, len(list))
+	for i, f := range list {
+		out[i].Finding = f
+		if f.File != "" {
+			out[i].SourceURL = gitinfo.BlobURL(p.GitRemote, p.GitCommit, filepath.Join(prefix, f.File), f.LineStart)
+		}
+	}
+	return out
+}
+
 func handleProbeContent(w http.ResponseWriter, r *http.Request, probeID string) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -166,42 +361,147 @@ func handleProbeContent(w http.ResponseWriter, r *http.Request, probeID string)
 		return
 	}
 
-	if probe.FilePath == "" {
-		writeError(w, http.StatusNotFound, "No file path for this probe")
+	content, err := db.ReportContent(database, probe)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
-	content, err := os.ReadFile(probe.FilePath)
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(content))
+}
+
+func handleProbeCompare(w http.ResponseWriter, r *http.Request, baseID, headID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if headID == "" || strings.Contains(headID, "/") {
+		writeError(w, http.StatusBadRequest, "Invalid probe ID to compare")
+		return
+	}
+
+	result, err := compare.Probes(database, baseID, headID)
 	if err != nil {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("Could not read probe file: %v", err))
+		var mismatch *compare.ErrTargetMismatch
+		if errors.As(err, &mismatch) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write(content)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleDeleteProbe deletes a probe with its findings and files. With
+// ?dry_run=true it only reports what would be removed.
+func handleDeleteProbe(w http.ResponseWriter, r *http.Request, probeID string) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	result, err := db.DeleteProbes(database, []string{probeID}, dryRun)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "Probe not found")
+		return
+	}
+	if err != nil && result == nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error deleting probe: %v", err))
+		return
+	}
+	if err != nil {
+		// The rows are gone; only some files could not be removed.
+		fmt.Printf("⚠️  Probe %s: %v\n", probeID, err)
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ─── GET /api/findings?tag=&assignee=&state=&severity=&probe= ───────────────
+
+func handleFindingList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := db.FindingFilter{
+		ProbeID:  q.Get("probe"),
+		Severity: q.Get("severity"),
+		State:    q.Get("state"),
+		Tag:      q.Get("tag"),
+		Assignee: q.Get("assignee"),
+	}
+
+	list, err := db.ListFindings(database, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching findings: %v", err))
+		return
+	}
+
+	if list == nil {
+		list = []db.Finding{}
+	}
+
+	writeJSON(w, http.StatusOK, list)
 }
 
-// ─── PATCH /api/findings/{id} ───────────────────────────────────────────────
+// ─── GET/PATCH/DELETE /api/findings/{id}  ·  /history  ·  /snippet  ·  /verify  ·  /patch  ·  /comments  ·  /tags
 
 func handleFindings(w http.ResponseWriter, r *http.Request) {
-	findingID := strings.TrimPrefix(r.URL.Path, "/api/findings/")
-	if findingID == "" || findingID == r.URL.Path {
+	path := strings.TrimPrefix(r.URL.Path, "/api/findings/")
+	if path == "" || path == r.URL.Path {
 		writeError(w, http.StatusBadRequest, "Invalid finding ID")
 		return
 	}
 
+	parts := strings.SplitN(path, "/", 2)
+	findingID := parts[0]
+
+	if len(parts) > 1 {
+		sub := strings.SplitN(parts[1], "/", 2)
+		switch sub[0] {
+		case "history":
+			handleFindingHistory(w, r, findingID)
+		case "snippet":
+			handleFindingSnippet(w, r, findingID)
+		case "verify":
+			handleVerifyFinding(w, r, findingID)
+		case "patch":
+			handleFindingPatch(w, r, findingID)
+		case "comments":
+			if len(sub) > 1 {
+				handleDeleteComment(w, r, findingID, sub[1])
+				return
+			}
+			handleFindingComments(w, r, findingID)
+		case "assignee":
+			handleFindingAssignee(w, r, findingID)
+		case "tags":
+			if len(sub) > 1 {
+				handleDeleteTag(w, r, findingID, sub[1])
+				return
+			}
+			handleFindingTags(w, r, findingID)
+		default:
+			writeError(w, http.StatusNotFound, "Not found")
+		}
+		return
+	}
+
 	switch r.Method {
-	case http.MethodPatch:
-		completed, err := db.ToggleFinding(database, findingID)
+	case http.MethodGet:
+		finding, err := db.GetFinding(database, findingID)
 		if err != nil {
 			writeError(w, http.StatusNotFound, fmt.Sprintf("Finding not found: %v", err))
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]interface{}{
-			"id":        findingID,
-			"completed": completed,
-		})
+		writeJSON(w, http.StatusOK, finding)
+	case http.MethodPatch:
+		handleSetFindingState(w, r, findingID)
 	case http.MethodDelete:
 		if err := db.DeleteFinding(database, findingID); err != nil {
 			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete finding: %v", err))
@@ -213,6 +513,344 @@ func handleFindings(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type stateRequest struct {
+	State  string `json:"state"`
+	Actor  string `json:"actor"`
+	Reason string `json:"reason"`
+}
+
+func handleSetFindingState(w http.ResponseWriter, r *http.Request, findingID string) {
+	var req stateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	defer r.Body.Close()
+
+	if !db.ValidFindingState(req.State) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid state %q (expected one of %s)", req.State, strings.Join(db.FindingStates, ", ")))
+		return
+	}
+
+	if req.Actor == "" {
+		req.Actor = "dashboard"
+	}
+
+	if _, err := db.SetFindingState(database, findingID, req.State, req.Actor, req.Reason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "Finding not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update finding: %v", err))
+		return
+	}
+
+	finding, err := db.GetFinding(database, findingID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Finding not found: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, finding)
+}
+
+func handleFindingHistory(w http.ResponseWriter, r *http.Request, findingID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if _, err := db.GetFinding(database, findingID); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Finding not found: %v", err))
+		return
+	}
+
+	history, err := db.GetFindingHistory(database, findingID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching history: %v", err))
+		return
+	}
+
+	if history == nil {
+		history = []db.StateChange{}
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// ─── GET /api/findings/{id}/snippet ──────────────────────────────────────────
+
+func handleFindingSnippet(w http.ResponseWriter, r *http.Request, findingID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	snippet, err := db.GetSnippet(database, findingID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "No snippet captured for this finding")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching snippet: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, snippet)
+}
+
+// ─── GET /api/findings/{id}/patch ────────────────────────────────────────────
+
+func handleFindingPatch(w http.ResponseWriter, r *http.Request, findingID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	patch, err := db.GetPatch(database, findingID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "No patch generated for this finding")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching patch: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, patch)
+}
+
+// ─── GET/POST /api/findings/{id}/verify ──────────────────────────────────────
+
+type verifyRequest struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Actor    string `json:"actor"`
+}
+
+type verifyResponse struct {
+	Verification *db.Verification `json:"verification"`
+	Change       *db.StateChange  `json:"change"`
+	Finding      *db.Finding      `json:"finding"`
+}
+
+func handleVerifyFinding(w http.ResponseWriter, r *http.Request, findingID string) {
+	if _, err := db.GetFinding(database, findingID); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Finding not found: %v", err))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := db.GetVerifications(database, findingID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching verifications: %v", err))
+			return
+		}
+		if list == nil {
+			list = []db.Verification{}
+		}
+		writeJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		var req verifyRequest
+		if r.Body != nil && r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+				return
+			}
+			defer r.Body.Close()
+		}
+		if req.Actor == "" {
+			req.Actor = "dashboard"
+		}
+
+		v, change, err := verifyFinding(r.Context(), database, findingID, prober.VerifyArgs{
+			Provider: req.Provider,
+			Model:    req.Model,
+			Actor:    req.Actor,
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Verification failed: %v", err))
+			return
+		}
+
+		finding, err := db.GetFinding(database, findingID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Finding not found: %v", err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, verifyResponse{Verification: v, Change: change, Finding: finding})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// ─── GET/POST /api/findings/{id}/comments  ·  DELETE …/comments/{cid} ────────
+
+type commentRequest struct {
+	Author   string `json:"author"`
+	Body     string `json:"body"`
+	ParentID int64  `json:"parent_id"`
+}
+
+func handleFindingComments(w http.ResponseWriter, r *http.Request, findingID string) {
+	if _, err := db.GetFinding(database, findingID); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Finding not found: %v", err))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		comments, err := db.GetComments(database, findingID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching comments: %v", err))
+			return
+		}
+		if comments == nil {
+			comments = []db.Comment{}
+		}
+		writeJSON(w, http.StatusOK, comments)
+	case http.MethodPost:
+		var req commentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+			return
+		}
+		defer r.Body.Close()
+
+		if req.Author == "" {
+			req.Author = "dashboard"
+		}
+
+		comment, err := db.AddComment(database, findingID, req.ParentID, req.Author, req.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, comment)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func handleDeleteComment(w http.ResponseWriter, r *http.Request, findingID, rawID string) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	commentID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	if err := db.DeleteComment(database, findingID, commentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "Comment not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete comment: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Comment deleted"})
+}
+
+// ─── PUT /api/findings/{id}/assignee ────────────────────────────────────────
+
+func handleFindingAssignee(w http.ResponseWriter, r *http.Request, findingID string) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := db.SetAssignee(database, findingID, req.Assignee); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "Finding not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to assign finding: %v", err))
+		return
+	}
+
+	finding, err := db.GetFinding(database, findingID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Finding not found: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, finding)
+}
+
+// ─── GET/POST /api/findings/{id}/tags  ·  DELETE …/tags/{tag} ───────────────
+
+func handleFindingTags(w http.ResponseWriter, r *http.Request, findingID string) {
+	if _, err := db.GetFinding(database, findingID); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Finding not found: %v", err))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+			return
+		}
+		defer r.Body.Close()
+
+		if err := db.AddTags(database, findingID, req.Tags...); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add tags: %v", err))
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	tags, err := db.GetTags(database, findingID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching tags: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+}
+
+func handleDeleteTag(w http.ResponseWriter, r *http.Request, findingID, tag string) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if err := db.RemoveTags(database, findingID, tag); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove tag: %v", err))
+		return
+	}
+
+	tags, err := db.GetTags(database, findingID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching tags: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+}
+
 // ─── GET/PUT /api/config ────────────────────────────────────────────────────
 
 func handleConfig(w http.ResponseWriter, r *http.Request) {
@@ -232,33 +870,52 @@ func handleGetConfig(w http.ResponseWriter) {
 		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error loading config: %v", err))
 		return
 	}
-	writeJSON(w, http.StatusOK, cfg)
+	writeJSON(w, http.StatusOK, cfg.Masked())
 }
 
+// configUpdate is the part of the config the dashboard may change. Other
+// settings, such as the server address and redaction, are only changed
+// through the CLI.
+type configUpdate struct {
+	Providers map[string]config.Provider `json:"providers"`
+	Default   string                     `json:"default"`
+}
+
+// handleUpdateConfig replaces the providers and default provider, keeping
+// every other setting. A body with any other field is rejected. API keys
+// are write-only: a key sent back empty or masked as GET returned it is
+// left unchanged.
 func handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "Could not read request body")
-		return
-	}
 	defer r.Body.Close()
 
-	var cfg config.Config
-	if err := json.Unmarshal(body, &cfg); err != nil {
+	var update configUpdate
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update); err != nil {
 		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
 		return
 	}
 
+	current, err := config.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error loading config: %v", err))
+		return
+	}
+
+	cfg := *current
+	cfg.Providers = update.Providers
 	if cfg.Providers == nil {
 		cfg.Providers = make(map[string]config.Provider)
 	}
+	cfg.Default = update.Default
+	cfg.KeepKeys(current)
 
 	if err := cfg.Save(); err != nil {
 		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error saving config: %v", err))
 		return
 	}
 
-	writeJSON(w, http.StatusOK, cfg)
+	writeJSON(w, http.StatusOK, cfg.Masked())
 }
 
 // ─── GET /api/file-tree/{id} ────────────────────────────────────────────────
@@ -338,6 +995,132 @@ func handleFileTree(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, tree)
 }
 
+// ─── GET /api/usage?by=provider|model|target&since=30d ──────────────────────
+
+func handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupBy := r.URL.Query().Get("by")
+	if groupBy == "" {
+		groupBy = "provider"
+	}
+
+	since, err := db.ParseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summaries, err := db.GetUsage(database, groupBy, since)
+	if err != nil {
+		if _, ok := db.UsageGroups[groupBy]; !ok {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching usage: %v", err))
+		return
+	}
+
+	if summaries == nil {
+		summaries = []db.UsageSummary{}
+	}
+
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// ─── GET /api/search?q=&severity=&target=&since=&until=&limit= ──────────────
+
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	params := r.URL.Query()
+	q := db.SearchQuery{
+		Query:  params.Get("q"),
+		Target: params.Get("target"),
+	}
+	if strings.TrimSpace(q.Query) == "" {
+		writeError(w, http.StatusBadRequest, "Query parameter q is required")
+		return
+	}
+	if severity := params.Get("severity"); severity != "" {
+		q.Severity = strings.Split(severity, ",")
+	}
+
+	var err error
+	if q.Since, err = db.ParseSince(params.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if q.Until, err = db.ParseSince(params.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 0 {
+			writeError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	hits, err := db.Search(database, q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error searching: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hits)
+}
+
+// ─── GET /api/projects ──────────────────────────────────────────────────────
+
+func handleProjects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	projects, err := db.ListProjects(database)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching projects: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, projects)
+}
+
+// ─── GET /api/projects/:id ──────────────────────────────────────────────────
+
+func handleProjectDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/projects/")
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	project, err := db.GetProject(database, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching project: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, project)
+}
+
 // ─── GET /api/version ─────────────────────────────────────────────────────────
 
 func handleVersion(w http.ResponseWriter, r *http.Request) {
@@ -2,17 +2,26 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/ndzuma/probeTool/internal/auth"
+	"github.com/ndzuma/probeTool/internal/config"
 	"github.com/ndzuma/probeTool/internal/db"
+	"github.com/ndzuma/probeTool/internal/gitinfo"
+	"github.com/ndzuma/probeTool/internal/prober"
 )
 
 func setupTestDB(t *testing.T) *sql.DB {
@@ -27,7 +36,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 
 func TestHealthEndpoint(t *testing.T) {
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	// Register routes
 	mux := http.NewServeMux()
@@ -45,9 +54,28 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestVersionEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/version", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"version"`) {
+		t.Errorf("Expected version info, got %s", rec.Body.String())
+	}
+}
+
 func TestGetProbesEndpoint(t *testing.T) {
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	// Insert some test probes
 	for i := 0; i < 3; i++ {
@@ -88,7 +116,7 @@ func TestGetProbesEndpoint(t *testing.T) {
 
 func TestGetProbeDetailEndpoint(t *testing.T) {
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	// Insert a test probe
 	testID := "detail-test-" + time.Now().Format("20060102150405")
@@ -122,7 +150,7 @@ func TestGetProbeDetailEndpoint(t *testing.T) {
 
 func TestGetProbeContentEndpoint(t *testing.T) {
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	// Create a test file
 	tmpDir := t.TempDir()
@@ -161,7 +189,7 @@ func TestGetProbeContentEndpoint(t *testing.T) {
 
 func TestFindingsEndpoint(t *testing.T) {
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	// Insert a test probe
 	probeID := "finding-probe-" + time.Now().Format("20060102150405")
@@ -180,8 +208,8 @@ func TestFindingsEndpoint(t *testing.T) {
 	mux := http.NewServeMux()
 	RegisterRoutes(mux, database)
 
-	// Test PATCH - toggle finding
-	req := httptest.NewRequest(http.MethodPatch, "/api/findings/"+findingID, nil)
+	// Test GET - fetch finding
+	req := httptest.NewRequest(http.MethodGet, "/api/findings/"+findingID, nil)
 	rec := httptest.NewRecorder()
 
 	mux.ServeHTTP(rec, req)
@@ -190,15 +218,64 @@ func TestFindingsEndpoint(t *testing.T) {
 		t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
 	}
 
-	var response map[string]interface{}
-	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+	var finding db.Finding
+	if err := json.Unmarshal(rec.Body.Bytes(), &finding); err != nil {
 		t.Errorf("Failed to parse response: %v", err)
 	}
+	if finding.ID != findingID || finding.Severity != "high" {
+		t.Errorf("Unexpected finding: %+v", finding)
+	}
+
+	// Test PATCH - set state by value
+	for i := 0; i < 2; i++ {
+		body := strings.NewReader(`{"state": "fixed", "actor": "tester", "reason": "patched"}`)
+		req = httptest.NewRequest(http.MethodPatch, "/api/findings/"+findingID, body)
+		rec = httptest.NewRecorder()
+
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var updated db.Finding
+		if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil {
+			t.Errorf("Failed to parse response: %v", err)
+		}
+
+		// Sending the same state twice must not toggle it back
+		if updated.State != "fixed" || !updated.Completed {
+			t.Errorf("Finding should be fixed after request %d, got %+v", i+1, updated)
+		}
+	}
 
-	if response["completed"] != true {
-		t.Error("Finding should be marked as completed")
+	// Test PATCH - invalid state
+	req = httptest.NewRequest(http.MethodPatch, "/api/findings/"+findingID, strings.NewReader(`{"state": "done"}`))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid state, got %d", rec.Code)
 	}
 
+	// Test GET - history
+	req = httptest.NewRequest(http.MethodGet, "/api/findings/"+findingID+"/history", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var history []db.StateChange
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil {
+		t.Errorf("Failed to parse response: %v", err)
+	}
+	if len(history) != 1 || history[0].Actor != "tester" || history[0].ToState != "fixed" {
+		t.Errorf("Unexpected history: %+v", history)
+	}
+
+	var response map[string]interface{}
+
 	// Test DELETE - delete finding
 	req = httptest.NewRequest(http.MethodDelete, "/api/findings/"+findingID, nil)
 	rec = httptest.NewRecorder()
@@ -219,8 +296,13 @@ func TestFindingsEndpoint(t *testing.T) {
 }
 
 func TestConfigEndpoint(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("APPDATA", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	mux := http.NewServeMux()
 	RegisterRoutes(mux, database)
@@ -250,28 +332,90 @@ func TestConfigEndpoint(t *testing.T) {
 			"test": map[string]interface{}{
 				"name":          "test",
 				"base_url":      "https://test.example.com",
-				"api_key":       "test-key",
+				"api_key":       "sk-test-0123456789",
 				"models":        []string{"model1"},
 				"default_model": "model1",
 			},
 		},
 		"default": "test",
 	}
-	body, _ := json.Marshal(updateData)
-	req = httptest.NewRequest(http.MethodPut, "/api/config", bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	rec = httptest.NewRecorder()
+	put := func() *config.Config {
+		t.Helper()
+		body, _ := json.Marshal(updateData)
+		req := httptest.NewRequest(http.MethodPut, "/api/config", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var cfg config.Config
+		json.NewDecoder(rec.Body).Decode(&cfg)
+		return &cfg
+	}
+	stored := func() string {
+		cfg, _ := config.Load()
+		return cfg.Providers["test"].APIKey
+	}
 
+	if got := put(); got.Providers["test"].APIKey != "****6789" {
+		t.Errorf("PUT returned key %q, want it masked", got.Providers["test"].APIKey)
+	}
+	if stored() != "sk-test-0123456789" {
+		t.Errorf("Stored key = %q after PUT", stored())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/config", nil)
+	rec = httptest.NewRecorder()
 	mux.ServeHTTP(rec, req)
+	if strings.Contains(rec.Body.String(), "0123456789") {
+		t.Errorf("GET returned the key in plain text: %s", rec.Body.String())
+	}
 
-	if rec.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	// Sending the masked or an empty key back keeps the stored one
+	provider := updateData["providers"].(map[string]interface{})["test"].(map[string]interface{})
+	for _, key := range []string{"****6789", ""} {
+		provider["api_key"] = key
+		put()
+		if stored() != "sk-test-0123456789" {
+			t.Errorf("Stored key = %q after sending %q", stored(), key)
+		}
+	}
+	provider["api_key"] = "sk-new-key-abcdef"
+	put()
+	if stored() != "sk-new-key-abcdef" {
+		t.Errorf("Stored key = %q, want the new key", stored())
+	}
+
+	// Settings the body leaves out are kept
+	cfg, _ := config.Load()
+	cfg.SetRetention(5, 30)
+	put()
+	if cfg, _ = config.Load(); cfg.Retention.KeepLast != 5 {
+		t.Errorf("PUT dropped the retention policy: %+v", cfg.Retention)
+	}
+
+	// Only providers and the default can be changed
+	for _, body := range []string{
+		`{"providers": {}, "default": "", "server": {"host": "0.0.0.0"}}`,
+		`{"providers": {}, "default": "", "redaction": {"no_defaults": true}}`,
+		`{"providers": {"test": {"name": "test", "extra": 1}}, "default": "test"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/config", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: expected status 400, got %d", body, rec.Code)
+		}
+	}
+	if cfg, _ = config.Load(); cfg.Server.Host != "" || cfg.Redaction.NoDefaults || len(cfg.Providers) != 1 {
+		t.Errorf("Rejected PUT changed the config: %+v", cfg)
 	}
 }
 
 func TestFileTreeEndpoint(t *testing.T) {
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	// Create a test directory structure
 	tmpDir := t.TempDir()
@@ -310,33 +454,99 @@ func TestFileTreeEndpoint(t *testing.T) {
 	}
 }
 
-func TestCORSHeaders(t *testing.T) {
+func TestProtect(t *testing.T) {
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	mux := http.NewServeMux()
 	RegisterRoutes(mux, database)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("dashboard"))
+	})
+	handler := Protect(mux, "secret", "http://localhost:37330", "http://127.0.0.1:37330")
+
+	serve := func(method, path, host, origin, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Host = host
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if token != "" {
+			auth.Authorize(req, token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/probes", nil)
-	rec := httptest.NewRecorder()
+	tests := []struct {
+		name, method, path, host, origin, token string
+		want                                    int
+	}{
+		{"API with token", http.MethodGet, "/api/probes", "localhost:37330", "", "secret", http.StatusOK},
+		{"API without token", http.MethodGet, "/api/probes", "localhost:37330", "", "", http.StatusUnauthorized},
+		{"API with a wrong token", http.MethodGet, "/api/config", "localhost:37330", "", "guess", http.StatusUnauthorized},
+		{"API from the dashboard", http.MethodGet, "/api/probes", "127.0.0.1:37330", "http://127.0.0.1:37330", "secret", http.StatusOK},
+		{"API from another site", http.MethodGet, "/api/probes", "localhost:37330", "http://evil.example", "secret", http.StatusForbidden},
+		{"Preflight from the dashboard", http.MethodOptions, "/api/config", "localhost:37330", "http://localhost:37330", "", http.StatusNoContent},
+		{"Preflight from another site", http.MethodOptions, "/api/config", "localhost:37330", "http://localhost:8080", "", http.StatusForbidden},
+		{"Dashboard page", http.MethodGet, "/probes/x", "localhost:37330", "", "", http.StatusOK},
+		{"Rebound host name", http.MethodGet, "/", "evil.example:37330", "", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rec := serve(tt.method, tt.path, tt.host, tt.origin, tt.token)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
 
-	mux.ServeHTTP(rec, req)
+	rec := serve(http.MethodOptions, "/api/config", "localhost:37330", "http://localhost:37330", "")
+	if origin := rec.Header().Get("Access-Control-Allow-Origin"); origin != "http://localhost:37330" {
+		t.Errorf("Expected CORS origin to be the dashboard, got %q", origin)
+	}
+	if methods := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, "PUT") {
+		t.Errorf("Expected CORS methods to include PUT, got %q", methods)
+	}
+	rec = serve(http.MethodGet, "/api/probes", "localhost:37330", "", "")
+	if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("A 401 should carry WWW-Authenticate")
+	}
+}
+
+func TestInjectToken(t *testing.T) {
+	inject := InjectToken("tok<en")
 
-	// Check CORS headers
-	origin := rec.Header().Get("Access-Control-Allow-Origin")
-	if origin != "*" {
-		t.Errorf("Expected CORS origin '*', got '%s'", origin)
+	page := `<!DOCTYPE html><html><head><title>probe</title></head><body></body></html>`
+	resp := &http.Response{
+		Header: http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+		Body:   io.NopCloser(strings.NewReader(page)),
+	}
+	if err := inject(resp); err != nil {
+		t.Fatalf("InjectToken() failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	want := `<head><meta name="probe-api-token" content="tok&lt;en"><title>`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("Page = %s, want the token meta tag first in <head>", body)
+	}
+	if resp.ContentLength != int64(len(body)) || resp.Header.Get("Cache-Control") != "no-store" {
+		t.Errorf("Unexpected headers %v, length %d", resp.Header, resp.ContentLength)
 	}
 
-	methods := rec.Header().Get("Access-Control-Allow-Methods")
-	if !strings.Contains(methods, "GET") {
-		t.Errorf("Expected CORS methods to include GET, got '%s'", methods)
+	script := "console.log('<head>')"
+	resp = &http.Response{
+		Header: http.Header{"Content-Type": []string{"application/javascript"}},
+		Body:   io.NopCloser(strings.NewReader(script)),
+	}
+	inject(resp)
+	if body, _ := io.ReadAll(resp.Body); string(body) != script {
+		t.Errorf("Non-HTML response changed to %s", body)
 	}
 }
 
 func TestAPIErrors(t *testing.T) {
 	database := setupTestDB(t)
-	defer database.Close()
+	defer db.Close(database)
 
 	mux := http.NewServeMux()
 	RegisterRoutes(mux, database)
@@ -382,3 +592,701 @@ func TestAPIErrors(t *testing.T) {
 		t.Errorf("Expected status 400, got %d", rec.Code)
 	}
 }
+
+func TestUsageEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	probeID := "usage-probe-" + time.Now().Format("20060102150405")
+	if err := db.InsertProbe(database, probeID, "full", "/tmp/test", "/tmp/test.md"); err != nil {
+		t.Fatalf("Failed to insert test probe: %v", err)
+	}
+	usage := db.Usage{Provider: "openrouter", Model: "test-model", InputTokens: 10, OutputTokens: 5, CostUSD: 0.02}
+	if err := db.UpdateProbeUsage(database, probeID, usage); err != nil {
+		t.Fatalf("Failed to update usage: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/usage?by=model&since=30d", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var summaries []db.UsageSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &summaries); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].Key != "test-model" {
+		t.Errorf("Unexpected usage summaries: %+v", summaries)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/usage?by=color", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid group, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/usage?since=soon", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid since, got %d", rec.Code)
+	}
+}
+
+func TestCompareEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	db.InsertProbe(database, "base", "full", "/repo", "")
+	db.InsertProbe(database, "head", "full", "/repo", "")
+	db.InsertProbe(database, "elsewhere", "full", "/other", "")
+	db.InsertFinding(database, "f1", "base", "Missing CSRF protection on payment endpoint", "high")
+	db.InsertFinding(database, "f2", "head", "Hardcoded API key in config", "critical")
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/probes/base/compare/head", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Summary map[string]int `json:"summary"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Summary["new"] != 1 || response.Summary["resolved"] != 1 {
+		t.Errorf("Unexpected summary: %v", response.Summary)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/probes/base/compare/elsewhere", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for different targets, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/probes/base/compare/missing", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown probe, got %d", rec.Code)
+	}
+}
+
+func TestFindingTriageEndpoints(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	db.InsertProbe(database, "triage-probe", "security", "/tmp/test", "/tmp/test.md")
+	db.InsertFinding(database, "triage-1", "triage-probe", "SQL injection in login", "high")
+	db.InsertFinding(database, "triage-2", "triage-probe", "Verbose error pages", "low")
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Comments
+	rec := do(http.MethodPost, "/api/findings/triage-1/comments", `{"author": "alice", "body": "Reproduced locally"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var comment db.Comment
+	json.Unmarshal(rec.Body.Bytes(), &comment)
+
+	rec = do(http.MethodPost, "/api/findings/triage-1/comments", `{"body": "Thanks", "parent_id": `+strconv.FormatInt(comment.ID, 10)+`}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 for reply, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(http.MethodPost, "/api/findings/triage-1/comments", `{"body": ""}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for empty comment, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/findings/missing/comments", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown finding, got %d", rec.Code)
+	}
+
+	rec = do(http.MethodGet, "/api/findings/triage-1/comments", "")
+	var comments []db.Comment
+	json.Unmarshal(rec.Body.Bytes(), &comments)
+	if len(comments) != 2 || comments[1].ParentID != comment.ID || comments[1].Author != "dashboard" {
+		t.Errorf("Unexpected comments: %+v", comments)
+	}
+
+	if rec := do(http.MethodDelete, "/api/findings/triage-1/comments/"+strconv.FormatInt(comment.ID, 10), ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 deleting comment, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodDelete, "/api/findings/triage-1/comments/"+strconv.FormatInt(comment.ID, 10), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting missing comment, got %d", rec.Code)
+	}
+
+	// Assignee
+	rec = do(http.MethodPut, "/api/findings/triage-1/assignee", `{"assignee": "alice"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var finding db.Finding
+	json.Unmarshal(rec.Body.Bytes(), &finding)
+	if finding.Assignee != "alice" {
+		t.Errorf("Expected assignee alice, got %q", finding.Assignee)
+	}
+	if rec := do(http.MethodPut, "/api/findings/missing/assignee", `{"assignee": "alice"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 assigning unknown finding, got %d", rec.Code)
+	}
+
+	// Tags
+	rec = do(http.MethodPost, "/api/findings/triage-1/tags", `{"tags": ["SQLi", "auth"]}`)
+	var tags []string
+	json.Unmarshal(rec.Body.Bytes(), &tags)
+	if rec.Code != http.StatusOK || strings.Join(tags, ",") != "auth,sqli" {
+		t.Errorf("Unexpected tags response %d: %v", rec.Code, tags)
+	}
+	do(http.MethodPost, "/api/findings/triage-2/tags", `{"tags": ["auth"]}`)
+
+	rec = do(http.MethodDelete, "/api/findings/triage-1/tags/sqli", "")
+	json.Unmarshal(rec.Body.Bytes(), &tags)
+	if rec.Code != http.StatusOK || strings.Join(tags, ",") != "auth" {
+		t.Errorf("Unexpected tags after delete %d: %v", rec.Code, tags)
+	}
+
+	// Filtered listing
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 2},
+		{"?tag=auth", 2},
+		{"?assignee=alice", 1},
+		{"?tag=auth&severity=low", 1},
+		{"?tag=sqli", 0},
+	}
+	for _, tt := range tests {
+		rec := do(http.MethodGet, "/api/findings"+tt.query, "")
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET /api/findings%s: expected 200, got %d", tt.query, rec.Code)
+			continue
+		}
+		var list []db.Finding
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Errorf("GET /api/findings%s: failed to parse response: %v", tt.query, err)
+		}
+		if len(list) != tt.want {
+			t.Errorf("GET /api/findings%s: got %d findings, want %d", tt.query, len(list), tt.want)
+		}
+	}
+}
+
+func TestFindingSnippetEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	db.InsertProbe(database, "snippet-probe", "security", "/tmp/test", "/tmp/test.md")
+	f := db.Finding{ID: "snippet-1", ProbeID: "snippet-probe", Text: "SQL injection in login", Severity: "critical", File: "src/login.js", LineStart: 12, LineEnd: 12}
+	db.CreateFinding(database, &f)
+	db.InsertFinding(database, "snippet-2", "snippet-probe", "Missing security headers", "low")
+	db.SaveSnippet(database, &db.Snippet{FindingID: "snippet-1", File: "src/login.js", StartLine: 7, EndLine: 17, Content: "db.query(sql + user)", Commit: "deadbeef"})
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/findings/snippet-1/snippet", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var snippet db.Snippet
+	if err := json.Unmarshal(rec.Body.Bytes(), &snippet); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if snippet.Content != "db.query(sql + user)" || snippet.Commit != "deadbeef" || snippet.LineStart != 12 {
+		t.Errorf("Unexpected snippet: %+v", snippet)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/findings/snippet-2/snippet", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for finding without snippet, got %d", rec.Code)
+	}
+}
+
+func TestVerifyFindingEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	db.InsertProbe(database, "verify-probe", "security", "/tmp/test", "/tmp/test.md")
+	db.InsertFinding(database, "verify-1", "verify-probe", "SQL injection in login", "critical")
+
+	origVerify := verifyFinding
+	defer func() { verifyFinding = origVerify }()
+	verifyFinding = func(ctx context.Context, database *sql.DB, findingID string, args prober.VerifyArgs) (*db.Verification, *db.StateChange, error) {
+		if args.Actor != "dashboard" || args.Model != "m" {
+			t.Errorf("Unexpected verify args %+v", args)
+		}
+		v := &db.Verification{FindingID: findingID, Verdict: "fixed", Reasoning: "Parameterised."}
+		change, err := db.RecordVerification(database, v, args.Actor)
+		return v, change, err
+	}
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/findings/verify-1/verify", strings.NewReader(`{"model":"m"}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Verification db.Verification `json:"verification"`
+		Change       db.StateChange  `json:"change"`
+		Finding      db.Finding      `json:"finding"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if resp.Verification.Verdict != "fixed" || resp.Finding.State != db.StateFixed || resp.Change.VerificationID != resp.Verification.ID {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/findings/verify-1/verify", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var list []db.Verification
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if rec.Code != http.StatusOK || len(list) != 1 {
+		t.Errorf("Expected one verification, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodPost, "/api/findings/missing/verify", http.StatusNotFound},
+		{http.MethodDelete, "/api/findings/verify-1/verify", http.StatusMethodNotAllowed},
+	} {
+		req = httptest.NewRequest(tc.method, tc.path, nil)
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestFindingPatchEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	db.InsertProbe(database, "patch-probe", "security", "/tmp/test", "/tmp/test.md")
+	db.InsertFinding(database, "patch-1", "patch-probe", "Debug mode enabled", "high")
+	db.InsertFinding(database, "patch-2", "patch-probe", "Missing security headers", "low")
+	db.SavePatch(database, &db.Patch{FindingID: "patch-1", Patch: "--- a/app.js\n+++ b/app.js\n", Valid: true})
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/findings/patch-1/patch", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var patch db.Patch
+	json.Unmarshal(rec.Body.Bytes(), &patch)
+	if rec.Code != http.StatusOK || !patch.Valid || patch.Patch == "" {
+		t.Errorf("Unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/findings/patch-2/patch", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for finding without patch, got %d", rec.Code)
+	}
+}
+
+func TestDeleteProbeEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	report := filepath.Join(t.TempDir(), "delete-probe.md")
+	os.WriteFile(report, []byte("# Report"), 0644)
+	db.InsertProbe(database, "delete-probe", "security", "/tmp/test", report)
+	db.InsertFinding(database, "delete-1", "delete-probe", "SQL injection in login", "critical")
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/probes/delete-probe?dry_run=true", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var plan db.DeleteResult
+	json.NewDecoder(rec.Body).Decode(&plan)
+	if !plan.DryRun || plan.Findings != 1 || len(plan.Files) != 1 {
+		t.Errorf("Unexpected dry run result %+v", plan)
+	}
+	if _, err := db.GetProbe(database, "delete-probe"); err != nil {
+		t.Fatal("A dry run should not delete the probe")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/probes/delete-probe", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := db.GetFinding(database, "delete-1"); err == nil {
+		t.Error("The probe's findings should be deleted")
+	}
+	if _, err := os.Stat(report); !os.IsNotExist(err) {
+		t.Error("The probe's report should be deleted")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/probes/delete-probe", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a deleted probe, got %d", rec.Code)
+	}
+}
+
+func TestSearchEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	db.InsertProbe(database, "search-probe", "security", "/tmp/test", "")
+	db.CreateFinding(database, &db.Finding{ID: "search-1", ProbeID: "search-probe", Text: "SSRF in image proxy", Severity: "high"})
+	db.CreateFinding(database, &db.Finding{ID: "search-2", ProbeID: "search-probe", Text: "SSRF in webhook", Severity: "low"})
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search?q=ssrf&severity=high,critical", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var hits []db.SearchHit
+	json.NewDecoder(rec.Body).Decode(&hits)
+	if len(hits) != 1 || hits[0].ID != "search-1" || !strings.Contains(hits[0].Snippet, "<mark>SSRF</mark>") {
+		t.Errorf("Unexpected hits %+v", hits)
+	}
+
+	for _, query := range []string{"", "?q=ssrf&since=soon", "?q=ssrf&limit=x"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/search"+query, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestListProbesPagination(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	for i := 0; i < 3; i++ {
+		id := "page-" + strconv.Itoa(i)
+		db.InsertProbe(database, id, "full", "/tmp/test", "")
+		database.Exec(`UPDATE probes SET created_at = ? WHERE id = ?`, "2025-01-0"+strconv.Itoa(i+1)+" 00:00:00", id)
+	}
+	db.InsertProbe(database, "other", "full", "/tmp/other", "")
+	db.InsertFinding(database, "page-f", "page-2", "SQL injection in login", "critical")
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	var seen []string
+	url := "/api/probes?target=/tmp/test&limit=2"
+	for pages := 0; url != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("Pagination did not terminate")
+		}
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var probes []db.ProbeListing
+		json.NewDecoder(rec.Body).Decode(&probes)
+		for _, p := range probes {
+			seen = append(seen, p.ID)
+			if p.ID == "page-2" && p.FindingCounts.Critical != 1 {
+				t.Errorf("Expected one critical finding on page-2, got %+v", p.FindingCounts)
+			}
+		}
+
+		url = ""
+		if link := rec.Header().Get("Link"); link != "" {
+			url = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+			if rec.Header().Get("X-Next-Cursor") == "" {
+				t.Error("A next link should come with X-Next-Cursor")
+			}
+		}
+	}
+
+	if strings.Join(seen, ",") != "page-2,page-1,page-0" {
+		t.Errorf("Paged probes = %v, want newest first", seen)
+	}
+
+	for _, query := range []string{"?sort=name", "?order=up", "?cursor=bad", "?since=soon", "?limit=x"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/probes"+query, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestProbeContentFromStoredReport(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	report := filepath.Join(t.TempDir(), "stored.md")
+	db.InsertProbe(database, "stored", "full", "/tmp/test", report)
+	db.SaveReport(database, "stored", "# Stored report")
+	db.InsertProbe(database, "lost", "full", "/tmp/test", filepath.Join(t.TempDir(), "lost.md"))
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	// The file was never written; the stored copy is served
+	req := httptest.NewRequest(http.MethodGet, "/api/probes/stored/content", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "# Stored report" {
+		t.Errorf("Expected the stored report, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/probes/stored", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var detail map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&detail)
+	if detail["content"] != "# Stored report" || detail["report_sha256"] != db.HashReport("# Stored report") {
+		t.Errorf("Unexpected probe detail content %v / %v", detail["content"], detail["report_sha256"])
+	}
+
+	// A report that is neither stored nor on disk is flagged, not omitted
+	req = httptest.NewRequest(http.MethodGet, "/api/probes/lost", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	detail = nil
+	json.NewDecoder(rec.Body).Decode(&detail)
+	if _, ok := detail["content_error"]; !ok {
+		t.Errorf("Expected content_error for a lost report, got %v", detail)
+	}
+}
+
+func TestProjectsEndpoint(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	project, err := db.EnsureProject(database, gitinfo.Repo{Root: "/src/app", RemoteURL: "github.com/org/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.InsertProbe(database, "project-probe", "full", "/src/app", "")
+	db.SetProbeProject(database, "project-probe", project.ID)
+	db.UpdateProbeStatus(database, "project-probe", "completed")
+	db.CreateFinding(database, &db.Finding{ID: "project-1", ProbeID: "project-probe", Text: "XSS", Severity: "high"})
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var projects []db.ProjectSummary
+	json.NewDecoder(rec.Body).Decode(&projects)
+	if len(projects) != 1 || projects[0].Posture.High != 1 || projects[0].OpenFindings.Total != 1 || len(projects[0].Trend) != 1 {
+		t.Errorf("Unexpected projects %+v", projects)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/projects/"+project.ID, nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"remote_url":"github.com/org/app"`) {
+		t.Errorf("Expected the project, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/projects/missing", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing project, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/probes?project="+project.ID, nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var probes []db.ProbeListing
+	json.NewDecoder(rec.Body).Decode(&probes)
+	if len(probes) != 1 || probes[0].ProjectID != project.ID {
+		t.Errorf("Unexpected probes for project filter %+v", probes)
+	}
+}
+
+// TestConcurrentAPIWrites triages findings through the API while a prober,
+// with its own connection to the same file, stores a probe's findings.
+func TestConcurrentAPIWrites(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "probes.db")
+	database, err := db.InitDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close(database)
+	prober, err := db.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prober.Close()
+
+	db.InsertProbe(database, "api-probe", "full", "/tmp/app", "")
+	db.CreateFinding(database, &db.Finding{ID: "api-finding", ProbeID: "api-probe", Text: "XSS", Severity: "high"})
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("cli-probe-%d", i)
+			if err := db.InsertProbe(prober, id, "full", "/tmp/app", ""); err != nil {
+				t.Errorf("InsertProbe(%s) failed: %v", id, err)
+				return
+			}
+			list := make([]db.Finding, 20)
+			for j := range list {
+				list[j] = db.Finding{ID: fmt.Sprintf("%s-%d", id, j), ProbeID: id, Text: fmt.Sprintf("Issue %d", j), Severity: "low"}
+			}
+			if err := db.CreateFindings(prober, list); err != nil {
+				t.Errorf("CreateFindings(%s) failed: %v", id, err)
+			}
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"author": "api", "body": "comment %d"}`, i)
+			req := httptest.NewRequest(http.MethodPost, "/api/findings/api-finding/comments", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+				t.Errorf("POST comment %d: %d %s", i, rec.Code, rec.Body.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if comments, _ := db.GetComments(database, "api-finding"); len(comments) != 20 {
+		t.Errorf("Stored %d comments, want 20", len(comments))
+	}
+	var count int
+	database.QueryRow(`SELECT COUNT(*) FROM findings WHERE probe_id LIKE 'cli-probe-%'`).Scan(&count)
+	if count != 80 {
+		t.Errorf("Stored %d prober findings, want 80", count)
+	}
+}
+
+func TestProbeDetailGitProvenance(t *testing.T) {
+	database := setupTestDB(t)
+	defer db.Close(database)
+
+	project, _ := db.EnsureProject(database, gitinfo.Repo{Root: "/src/app", RemoteURL: "github.com/org/app"})
+	db.InsertProbe(database, "git-probe", "full", "/src/app/api", "")
+	db.SetProbeProject(database, "git-probe", project.ID)
+	db.UpdateProbeGit(database, "git-probe", db.GitProvenance{
+		GitCommit: "abc123", GitBranch: "main", GitDirty: true, GitRemote: "github.com/org/app", GitChangedFiles: 2,
+	})
+	db.UpdateProbeVersions(database, "git-probe", db.Versions{ProbeVersion: "v1.2.0", AgentVersion: "1.0.0", PromptHash: "abcdef", SkillHash: "123456"})
+	db.InsertProbe(database, "other-probe", "full", "/src/app/api", "")
+	db.CreateFinding(database, &db.Finding{ID: "git-finding", ProbeID: "git-probe", Text: "SQLi", Severity: "high", File: "db.go", LineStart: 7})
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, database)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/probes/git-probe", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Git struct {
+			Commit       string `json:"commit"`
+			Branch       string `json:"branch"`
+			Dirty        bool   `json:"dirty"`
+			Remote       string `json:"remote"`
+			ChangedFiles int    `json:"changed_files"`
+		} `json:"git"`
+		ProbeVersion string `json:"probe_version"`
+		PromptHash   string `json:"prompt_hash"`
+		Findings     []struct {
+			ID        string `json:"id"`
+			SourceURL string `json:"source_url"`
+		} `json:"findings"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.ProbeVersion != "v1.2.0" || resp.PromptHash != "abcdef" {
+		t.Errorf("Unexpected versions %q, %q", resp.ProbeVersion, resp.PromptHash)
+	}
+	if resp.Git.Commit != "abc123" || resp.Git.Branch != "main" || !resp.Git.Dirty || resp.Git.ChangedFiles != 2 {
+		t.Errorf("Unexpected git provenance %+v", resp.Git)
+	}
+	if len(resp.Findings) != 1 || resp.Findings[0].SourceURL != "https://github.com/org/app/blob/abc123/api/db.go#L7" {
+		t.Errorf("Unexpected findings %+v", resp.Findings)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/probes?probe_version=v1.2.0&prompt_hash=abc", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var probes []db.ProbeListing
+	json.NewDecoder(rec.Body).Decode(&probes)
+	if len(probes) != 1 || probes[0].ID != "git-probe" {
+		t.Errorf("Unexpected probes for version filters %+v", probes)
+	}
+}
@@ -0,0 +1,130 @@
+package snippet
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ContextLines is how many lines are kept above and below the
+	// referenced range.
+	ContextLines = 5
+	// MaxLines caps a snippet so a finding spanning a whole file does not
+	// copy the file into the database.
+	MaxLines = 60
+	// maxLineLength truncates minified or generated lines.
+	maxLineLength = 500
+)
+
+// Snippet is a slice of a source file around the lines a finding points at.
+type Snippet struct {
+	File      string
+	StartLine int // first line in Content, 1-based
+	EndLine   int // last line in Content
+	Content   string
+}
+
+// Capture reads the lines lineStart..lineEnd of file, plus ContextLines on
+// either side. file may be relative to root or absolute; either way it must
+// resolve inside root.
+func Capture(root, file string, lineStart, lineEnd int) (*Snippet, error) {
+	if file == "" || lineStart <= 0 {
+		return nil, fmt.Errorf("finding has no file location")
+	}
+	if lineEnd < lineStart {
+		lineEnd = lineStart
+	}
+
+	path, rel, err := resolve(root, file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data, 0) != -1 {
+		return nil, fmt.Errorf("%s is a binary file", rel)
+	}
+
+	from := lineStart - ContextLines
+	if from < 1 {
+		from = 1
+	}
+	to := lineEnd + ContextLines
+	if to-from+1 > MaxLines {
+		to = from + MaxLines - 1
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for n := 1; scanner.Scan() && n <= to; n++ {
+		if n < from {
+			continue
+		}
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > maxLineLength {
+			line = line[:maxLineLength] + "…"
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s has no line %d", rel, lineStart)
+	}
+
+	return &Snippet{
+		File:      rel,
+		StartLine: from,
+		EndLine:   from + len(lines) - 1,
+		Content:   strings.Join(lines, "\n"),
+	}, nil
+}
+
+// resolve returns the absolute path of file and its path relative to root,
+// rejecting anything that escapes root.
+func resolve(root, file string) (string, string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", "", err
+	}
+	if r, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = r
+	}
+
+	path := filepath.FromSlash(file)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(absRoot, path)
+	}
+	if r, err := filepath.EvalSymlinks(path); err == nil {
+		path = r
+	}
+
+	rel, err := filepath.Rel(absRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("%s is outside the target directory", file)
+	}
+
+	return path, filepath.ToSlash(rel), nil
+}
+
+// Commit returns the commit checked out in dir, or "" when dir is not a git
+// work tree or git is unavailable.
+func Commit(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
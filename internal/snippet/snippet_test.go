@@ -0,0 +1,109 @@
+package snippet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSource(t *testing.T, dir, name string, lines int) {
+	t.Helper()
+	var b strings.Builder
+	for i := 1; i <= lines; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "src/app.js", 100)
+
+	tests := []struct {
+		name      string
+		lineStart int
+		lineEnd   int
+		wantStart int
+		wantEnd   int
+	}{
+		{"middle", 42, 48, 37, 53},
+		{"single line", 10, 0, 5, 15},
+		{"near start", 2, 3, 1, 8},
+		{"near end", 99, 100, 94, 100},
+		{"capped", 10, 90, 5, 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Capture(root, "src/app.js", tt.lineStart, tt.lineEnd)
+			if err != nil {
+				t.Fatalf("Capture() failed: %v", err)
+			}
+			if s.File != "src/app.js" || s.StartLine != tt.wantStart || s.EndLine != tt.wantEnd {
+				t.Errorf("Capture() = %s:%d-%d, want src/app.js:%d-%d", s.File, s.StartLine, s.EndLine, tt.wantStart, tt.wantEnd)
+			}
+			lines := strings.Split(s.Content, "\n")
+			if lines[0] != fmt.Sprintf("line %d", tt.wantStart) || len(lines) != tt.wantEnd-tt.wantStart+1 {
+				t.Errorf("Unexpected content starting %q with %d lines", lines[0], len(lines))
+			}
+		})
+	}
+}
+
+func TestCaptureAbsolutePath(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "main.go", 20)
+
+	s, err := Capture(root, filepath.Join(root, "main.go"), 3, 3)
+	if err != nil {
+		t.Fatalf("Capture() failed: %v", err)
+	}
+	if s.File != "main.go" {
+		t.Errorf("File = %q, want path relative to the target", s.File)
+	}
+}
+
+func TestCaptureErrors(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "app.js", 10)
+	os.WriteFile(filepath.Join(root, "image.png"), []byte{0x89, 'P', 'N', 'G', 0, 0}, 0644)
+
+	outside := t.TempDir()
+	writeSource(t, outside, "secret.txt", 10)
+
+	tests := []struct {
+		name string
+		file string
+		line int
+	}{
+		{"no file", "", 1},
+		{"no line", "app.js", 0},
+		{"missing file", "nope.js", 1},
+		{"past end", "app.js", 50},
+		{"binary", "image.png", 1},
+		{"traversal", "../" + filepath.Base(outside) + "/secret.txt", 1},
+		{"absolute outside", filepath.Join(outside, "secret.txt"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Capture(root, tt.file, tt.line, tt.line); err == nil {
+				t.Errorf("Capture(%q, %d) should fail", tt.file, tt.line)
+			}
+		})
+	}
+}
+
+func TestCommitOutsideRepo(t *testing.T) {
+	if got := Commit(t.TempDir()); got != "" {
+		t.Errorf("Commit() outside a git repo = %q, want empty", got)
+	}
+}
@@ -5,19 +5,34 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/ndzuma/probeTool/internal/auth"
 	"github.com/ndzuma/probeTool/internal/process"
 )
 
 func (m *Manager) waitForServer(timeoutSeconds int) error {
 	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
-	checkURL := fmt.Sprintf("http://localhost:%s/api/health", process.ServerPort)
+
+	// The health check is an API route and needs the token
+	token, err := auth.LoadToken()
+	if err != nil {
+		return err
+	}
 
 	for time.Now().Before(deadline) {
-		resp, err := http.Get(checkURL)
-		if err == nil {
-			resp.Body.Close()
-			if resp.StatusCode == 200 {
-				return nil
+		// The server records its address once it is listening
+		if state, err := process.RunningServer(); err == nil {
+			req, err := http.NewRequest(http.MethodGet, state.URL+"/api/health", nil)
+			if err != nil {
+				return err
+			}
+			auth.Authorize(req, token)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == 200 {
+					return nil
+				}
 			}
 		}
 		time.Sleep(500 * time.Millisecond)
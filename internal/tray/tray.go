@@ -17,11 +17,10 @@ import (
 const updateCheckInterval = 4 * time.Hour
 
 type Manager struct {
-	serveCmd     *exec.Cmd
-	menuItems    *MenuItems
-	dashboardURL string
-	updateInfo   *updater.UpdateInfo
-	stopPolling  chan struct{}
+	serveCmd    *exec.Cmd
+	menuItems   *MenuItems
+	updateInfo  *updater.UpdateInfo
+	stopPolling chan struct{}
 }
 
 type MenuItems struct {
@@ -34,8 +33,7 @@ type MenuItems struct {
 
 func New() *Manager {
 	return &Manager{
-		dashboardURL: fmt.Sprintf("http://localhost:%s", process.ServerPort),
-		stopPolling:  make(chan struct{}),
+		stopPolling: make(chan struct{}),
 	}
 }
 
@@ -84,7 +82,9 @@ func (m *Manager) handleMenuActions() {
 	for {
 		select {
 		case <-m.menuItems.openDashboard.ClickedCh:
-			m.openBrowser(m.dashboardURL)
+			// The server records its address when it starts, and may
+			// have moved to another port since the tray started
+			m.openBrowser(process.DashboardURL())
 
 		case <-m.menuItems.update.ClickedCh:
 			m.handleUpdateClick()
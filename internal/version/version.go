@@ -8,6 +8,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/ndzuma/probeTool/internal/paths"
+	"github.com/ndzuma/probeTool/internal/process"
 )
 
 // These will be set via -ldflags during build
@@ -42,7 +43,7 @@ func GetInfo() Info {
 		BuildDate:    BuildDate,
 		GoVersion:    GoVersion,
 		Platform:     fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
-		DashboardURL: "http://localhost:37330",
+		DashboardURL: process.DashboardURL(),
 		ConfigPath:   paths.GetAppDir(),
 	}
 }
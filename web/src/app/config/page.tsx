@@ -404,7 +404,7 @@ export default function ConfigPage() {
                           type="password"
                           value={provider.api_key}
                           onChange={(e) => updateProviderField(name, "api_key", e.target.value)}
-                          placeholder="sk-..."
+                          placeholder="Leave unchanged to keep the stored key"
                         />
                       </div>
                     </div>
@@ -19,7 +19,14 @@ import {
   Trash,
 } from "@phosphor-icons/react";
 
-import { getProbe, deleteFinding as deleteFindingAPI, type Probe } from "@/lib/api";
+import {
+  apiFetch,
+  getProbe,
+  getFindingSnippet,
+  deleteFinding as deleteFindingAPI,
+  type Probe,
+  type Snippet,
+} from "@/lib/api";
 import { Button } from "@/components/ui/button";
 import { Badge } from "@/components/ui/badge";
 import { Card, CardContent } from "@/components/ui/card";
@@ -31,7 +38,79 @@ interface Finding {
   id: string;
   text: string;
   severity: string;
+  state: string;
   completed: boolean;
+  file?: string;
+  line_start?: number;
+  line_end?: number;
+  aliases?: string[];
+}
+
+function FindingSnippet({ finding }: { finding: Finding }) {
+  const [open, setOpen] = useState(false);
+  const [snippet, setSnippet] = useState<Snippet | null>(null);
+  const [missing, setMissing] = useState(false);
+
+  if (!finding.file || !finding.line_start) return null;
+
+  const toggle = async () => {
+    setOpen((prev) => !prev);
+    if (snippet || missing) return;
+    try {
+      setSnippet(await getFindingSnippet(finding.id));
+    } catch {
+      setMissing(true);
+    }
+  };
+
+  const location =
+    finding.line_end && finding.line_end !== finding.line_start
+      ? `${finding.file}:${finding.line_start}-${finding.line_end}`
+      : `${finding.file}:${finding.line_start}`;
+
+  return (
+    <div className="mt-1">
+      <button
+        onClick={toggle}
+        className="font-mono text-xs text-muted-foreground hover:text-primary cursor-pointer"
+      >
+        {location}
+      </button>
+      {open && missing && (
+        <p className="text-xs text-muted-foreground/70 mt-1">
+          No code was captured for this finding
+        </p>
+      )}
+      {open && snippet && (
+        <div className="mt-2 rounded-md border border-border bg-muted/30 overflow-x-auto">
+          <pre className="text-xs leading-5 py-2">
+            {snippet.content.split("\n").map((line, i) => {
+              const number = snippet.start_line + i;
+              const hit =
+                number >= snippet.line_start &&
+                number <= Math.max(snippet.line_end, snippet.line_start);
+              return (
+                <div
+                  key={number}
+                  className={cn("flex px-3", hit && "bg-destructive/10")}
+                >
+                  <span className="w-10 shrink-0 select-none text-right pr-3 text-muted-foreground/60">
+                    {number}
+                  </span>
+                  <code>{line}</code>
+                </div>
+              );
+            })}
+          </pre>
+          {snippet.commit && (
+            <p className="px-3 pb-2 text-[0.65rem] text-muted-foreground/70">
+              Captured at {snippet.commit.slice(0, 10)}
+            </p>
+          )}
+        </div>
+      )}
+    </div>
+  );
 }
 
 function severityIcon(severity: string) {
@@ -117,14 +196,17 @@ export default function ProbeDetailPage() {
 
         const probeData = data as Probe & {
           content?: string;
+          content_error?: string;
           findings?: Finding[];
         };
 
         if (probeData.content) {
           setContent(probeData.content);
+        } else if (probeData.content_error) {
+          setContent(`> Report unavailable: ${probeData.content_error}`);
         } else if (probeData.file_path) {
           try {
-            const res = await fetch(`/api/probes/${id}/content`);
+            const res = await apiFetch(`/probes/${id}/content`);
             if (res.ok) {
               const text = await res.text();
               setContent(text);
@@ -148,20 +230,31 @@ export default function ProbeDetailPage() {
   }, [id]);
 
   const toggleFinding = async (findingId: string) => {
+    const previous = findings;
+    const target = findings.find((f) => f.id === findingId);
+    if (!target) return;
+
+    // Send the desired state rather than a toggle so concurrent tabs converge.
+    const state = target.completed ? "open" : "fixed";
     setFindings((prev) =>
       prev.map((f) =>
-        f.id === findingId ? { ...f, completed: !f.completed } : f,
+        f.id === findingId ? { ...f, state, completed: !f.completed } : f,
       ),
     );
 
     try {
-      await fetch(`/api/findings/${findingId}`, { method: "PATCH" });
-    } catch {
+      const res = await apiFetch(`/findings/${findingId}`, {
+        method: "PATCH",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ state }),
+      });
+      if (!res.ok) throw new Error(res.statusText);
+      const updated: Finding = await res.json();
       setFindings((prev) =>
-        prev.map((f) =>
-          f.id === findingId ? { ...f, completed: !f.completed } : f,
-        ),
+        prev.map((f) => (f.id === findingId ? { ...f, ...updated } : f)),
       );
+    } catch {
+      setFindings(previous);
     }
   };
 
@@ -254,6 +347,31 @@ export default function ProbeDetailPage() {
               <span className="text-xs font-mono text-muted-foreground/70 truncate max-w-[200px]">
                 {probe.target}
               </span>
+              {probe.git?.commit && (
+                <span
+                  className="text-xs font-mono text-muted-foreground/70"
+                  title={probe.git.remote || undefined}
+                >
+                  {probe.git.branch || "detached"}@{probe.git.commit.slice(0, 8)}
+                  {probe.git.dirty &&
+                    ` (+${probe.git.changed_files} uncommitted)`}
+                </span>
+              )}
+              {probe.model && (
+                <span
+                  className="text-xs font-mono text-muted-foreground/70"
+                  title={[
+                    probe.probe_version && `probe ${probe.probe_version}`,
+                    probe.agent_version && `agent ${probe.agent_version}`,
+                    probe.prompt_hash && `prompt ${probe.prompt_hash.slice(0, 12)}`,
+                    probe.skill_hash && `skill ${probe.skill_hash.slice(0, 12)}`,
+                  ]
+                    .filter(Boolean)
+                    .join(", ") || undefined}
+                >
+                  {probe.model}
+                </span>
+              )}
             </div>
           </div>
 
@@ -414,6 +532,15 @@ export default function ProbeDetailPage() {
                           >
                             {finding.text}
                           </p>
+                          {finding.aliases && finding.aliases.length > 0 && (
+                            <p
+                              className="mt-0.5 text-xs text-muted-foreground"
+                              title={finding.aliases.join("\n")}
+                            >
+                              Also reported as: {finding.aliases.join(" · ")}
+                            </p>
+                          )}
+                          <FindingSnippet finding={finding} />
                         </div>
                         <div className="shrink-0 flex items-center gap-2">
                           {severityIcon(finding.severity)}
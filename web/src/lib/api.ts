@@ -7,11 +7,130 @@ export interface Probe {
   file_path: string;
   status: string;
   created_at: string;
+  provider: string;
+  model: string;
+  input_tokens: number;
+  output_tokens: number;
+  cost_usd: number;
+  duration_ms: number;
+  num_turns: number;
+  project_id: string;
+  git_commit: string;
+  git_branch: string;
+  git_dirty: boolean;
+  git_remote: string;
+  git_changed_files: number;
+  probe_version: string;
+  agent_version: string;
+  prompt_hash: string;
+  skill_hash: string;
+  // Present in listings, not in probe detail.
+  finding_counts?: SeverityCounts;
+  // Present in probe detail when the target is a git repository.
+  git?: GitProvenance | null;
+}
+
+// The repository state a probe ran against, as returned by getProbe.
+export interface GitProvenance {
+  commit: string;
+  branch: string;
+  dirty: boolean;
+  remote: string;
+  changed_files: number;
+}
+
+export interface SeverityCounts {
+  critical: number;
+  high: number;
+  medium: number;
+  low: number;
+  info: number;
+  total: number;
+}
+
+export interface ProbeFilter {
+  target?: string;
+  project?: string;
+  status?: string;
+  type?: string;
+  provider?: string;
+  model?: string;
+  probe_version?: string;
+  agent_version?: string;
+  // Prompt and skill hashes match by prefix.
+  prompt_hash?: string;
+  skill_hash?: string;
+  since?: string;
+  until?: string;
+  sort?: "created_at" | "cost" | "duration" | "findings";
+  order?: "asc" | "desc";
+  limit?: number;
+  cursor?: string;
+}
+
+export interface TrendPoint {
+  probe_id: string;
+  created_at: string;
+  counts: SeverityCounts;
+}
+
+export interface Project {
+  id: string;
+  name: string;
+  remote_url: string;
+  root: string;
+  created_at: string;
+  probe_count: number;
+  last_probe_at?: string;
+  latest_probe_id?: string;
+  posture: SeverityCounts;
+  open_findings: SeverityCounts;
+  trend: TrendPoint[];
+}
+
+export interface DeleteResult {
+  probes: Probe[];
+  findings: number;
+  files: string[];
+  dry_run: boolean;
+}
+
+export interface SearchHit {
+  kind: "finding" | "report";
+  id: string;
+  probe_id: string;
+  title: string;
+  // Matched terms are wrapped in <mark>; escape the rest before rendering.
+  snippet: string;
+  severity?: string;
+  target: string;
+  created_at: string;
+  rank: number;
+}
+
+export interface SearchFilter {
+  severity?: string;
+  target?: string;
+  since?: string;
+  until?: string;
+  limit?: number;
+}
+
+export interface UsageSummary {
+  key: string;
+  probes: number;
+  input_tokens: number;
+  output_tokens: number;
+  cost_usd: number;
+  duration_ms: number;
+  num_turns: number;
 }
 
 export interface Provider {
   name: string;
   base_url: string;
+  // Masked by the server. Send it back unchanged, or empty, to keep the
+  // stored key; any other value replaces it.
   api_key: string;
   models: string[];
   default_model: string;
@@ -22,22 +141,135 @@ export interface Config {
   default: string;
 }
 
+export type FindingState =
+  | "open"
+  | "in_progress"
+  | "fixed"
+  | "accepted_risk"
+  | "false_positive";
+
+export interface StateChange {
+  id: number;
+  finding_id: string;
+  from_state: FindingState;
+  to_state: FindingState;
+  actor: string;
+  reason: string;
+  verification_id?: number;
+  created_at: string;
+}
+
+export type Verdict = "fixed" | "still_present" | "inconclusive";
+
+export interface Verification {
+  id: number;
+  finding_id: string;
+  verdict: Verdict;
+  reasoning: string;
+  provider: string;
+  model: string;
+  cost_usd: number;
+  created_at: string;
+}
+
+export interface VerifyResult {
+  verification: Verification;
+  change: StateChange;
+  finding: Finding;
+}
+
+export interface Patch {
+  finding_id: string;
+  patch: string;
+  summary: string;
+  valid: boolean;
+  check_output: string;
+  base_commit: string;
+  provider: string;
+  model: string;
+  cost_usd: number;
+  branch: string;
+  applied_at: string;
+  created_at: string;
+}
+
+export interface Snippet {
+  finding_id: string;
+  file: string;
+  start_line: number;
+  end_line: number;
+  line_start: number;
+  line_end: number;
+  content: string;
+  commit: string;
+  captured_at: string;
+}
+
+export interface Comment {
+  id: number;
+  finding_id: string;
+  parent_id?: number;
+  author: string;
+  body: string;
+  created_at: string;
+}
+
+export interface FindingFilter {
+  probe?: string;
+  severity?: string;
+  state?: FindingState;
+  assignee?: string;
+  tag?: string;
+}
+
 export interface Finding {
   id: string;
   probe_id: string;
+  // Link to the finding's lines at the scanned commit, in probe detail.
+  source_url?: string;
+  fingerprint: string;
   text: string;
   severity: string;
+  state: FindingState;
+  assignee: string;
+  tags: string[];
   completed: boolean;
+  file: string;
+  line_start: number;
+  line_end: number;
+  cwe: string;
+  owasp: string;
+  description: string;
+  remediation: string;
+  aliases: string[];
+}
+
+// The server adds the API token to every dashboard page it serves; the
+// NEXT_PUBLIC_PROBE_API_TOKEN variable stands in during development.
+export function apiToken(): string {
+  if (typeof document !== "undefined") {
+    const meta = document.querySelector<HTMLMetaElement>(
+      'meta[name="probe-api-token"]'
+    );
+    if (meta?.content) return meta.content;
+  }
+  return process.env.NEXT_PUBLIC_PROBE_API_TOKEN ?? "";
+}
+
+// apiFetch calls an API path with the token. Every /api route requires it.
+export function apiFetch(path: string, options: RequestInit = {}) {
+  const headers = new Headers(options.headers);
+  headers.set("Authorization", `Bearer ${apiToken()}`);
+  return fetch(`${API_BASE}${path}`, { ...options, headers });
 }
 
 async function request<T>(
   path: string,
-  options?: RequestInit
+  options: RequestInit = {}
 ): Promise<T> {
-  const res = await fetch(`${API_BASE}${path}`, {
-    headers: { "Content-Type": "application/json" },
-    ...options,
-  });
+  const headers = new Headers(options.headers);
+  headers.set("Content-Type", "application/json");
+  const res = await apiFetch(path, { ...options, headers });
   if (!res.ok) {
     throw new Error(`API error: ${res.status} ${res.statusText}`);
   }
@@ -45,24 +277,138 @@ async function request<T>(
 }
 
 // Probes
-export const getProbes = () => request<Probe[]>("/probes");
+export const listProbes = async (filter: ProbeFilter = {}) => {
+  const params = new URLSearchParams();
+  Object.entries(filter).forEach(([key, value]) => {
+    if (value) params.set(key, String(value));
+  });
+  const query = params.toString();
+  const res = await apiFetch(`/probes${query ? `?${query}` : ""}`);
+  if (!res.ok) {
+    throw new Error(`API error: ${res.status} ${res.statusText}`);
+  }
+  const probes: Probe[] = await res.json();
+  return { probes, nextCursor: res.headers.get("X-Next-Cursor") ?? "" };
+};
+
+// getProbes follows the cursor through every page of the listing.
+export const getProbes = async (filter: ProbeFilter = {}) => {
+  const all: Probe[] = [];
+  let cursor = "";
+  do {
+    const page = await listProbes({ ...filter, limit: 1000, cursor });
+    all.push(...page.probes);
+    cursor = page.nextCursor;
+  } while (cursor);
+  return all;
+};
 export const getProbe = (id: string) => request<Probe>(`/probes/${id}`);
+export const deleteProbe = (id: string, dryRun = false) =>
+  request<DeleteResult>(`/probes/${id}${dryRun ? "?dry_run=true" : ""}`, {
+    method: "DELETE",
+  });
+
+// Projects
+export const getProjects = () => request<Project[]>("/projects");
+export const getProject = (id: string) => request<Project>(`/projects/${id}`);
+
+// Search
+export const search = (q: string, filter: SearchFilter = {}) => {
+  const params = new URLSearchParams({ q });
+  Object.entries(filter).forEach(([key, value]) => {
+    if (value) params.set(key, String(value));
+  });
+  return request<SearchHit[]>(`/search?${params.toString()}`);
+};
+
+// Usage
+export const getUsage = (by = "provider", since = "") =>
+  request<UsageSummary[]>(
+    `/usage?by=${by}${since ? `&since=${encodeURIComponent(since)}` : ""}`
+  );
 
 // Config
 export const getConfig = () => request<Config>("/config");
-export const updateConfig = (config: Config) =>
+// Only providers and the default provider can be changed from the dashboard
+export const updateConfig = ({ providers, default: defaultProvider }: Config) =>
   request<Config>("/config", {
     method: "PUT",
-    body: JSON.stringify(config),
+    body: JSON.stringify({ providers, default: defaultProvider }),
   });
 
 // Findings
-export const toggleFinding = (id: string) =>
-  request<Finding>(`/findings/${id}`, { method: "PATCH" });
+export const getFinding = (id: string) => request<Finding>(`/findings/${id}`);
+
+export const setFindingState = (
+  id: string,
+  state: FindingState,
+  reason = ""
+) =>
+  request<Finding>(`/findings/${id}`, {
+    method: "PATCH",
+    body: JSON.stringify({ state, reason }),
+  });
+
+export const getFindingHistory = (id: string) =>
+  request<StateChange[]>(`/findings/${id}/history`);
+
+export const getFindingSnippet = (id: string) =>
+  request<Snippet>(`/findings/${id}/snippet`);
+
+export const getFindingPatch = (id: string) =>
+  request<Patch>(`/findings/${id}/patch`);
+
+export const getVerifications = (id: string) =>
+  request<Verification[]>(`/findings/${id}/verify`);
+
+export const verifyFinding = (id: string, opts: { provider?: string; model?: string } = {}) =>
+  request<VerifyResult>(`/findings/${id}/verify`, {
+    method: "POST",
+    body: JSON.stringify(opts),
+  });
 
 export const deleteFinding = (id: string) =>
   request<void>(`/findings/${id}`, { method: "DELETE" });
 
+export const listFindings = (filter: FindingFilter = {}) => {
+  const params = new URLSearchParams();
+  Object.entries(filter).forEach(([key, value]) => {
+    if (value) params.set(key, value);
+  });
+  const query = params.toString();
+  return request<Finding[]>(`/findings${query ? `?${query}` : ""}`);
+};
+
+// Triage
+export const getComments = (id: string) =>
+  request<Comment[]>(`/findings/${id}/comments`);
+
+export const addComment = (id: string, body: string, parentId?: number) =>
+  request<Comment>(`/findings/${id}/comments`, {
+    method: "POST",
+    body: JSON.stringify({ body, parent_id: parentId }),
+  });
+
+export const deleteComment = (id: string, commentId: number) =>
+  request<void>(`/findings/${id}/comments/${commentId}`, { method: "DELETE" });
+
+export const setAssignee = (id: string, assignee: string) =>
+  request<Finding>(`/findings/${id}/assignee`, {
+    method: "PUT",
+    body: JSON.stringify({ assignee }),
+  });
+
+export const addTags = (id: string, tags: string[]) =>
+  request<string[]>(`/findings/${id}/tags`, {
+    method: "POST",
+    body: JSON.stringify({ tags }),
+  });
+
+export const removeTag = (id: string, tag: string) =>
+  request<string[]>(`/findings/${id}/tags/${encodeURIComponent(tag)}`, {
+    method: "DELETE",
+  });
+
 // File tree (placeholder)
 export const getFileTree = (id: string) =>
   request<string[]>(`/file-tree/${id}`);